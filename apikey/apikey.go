@@ -0,0 +1,239 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vhvplatform/go-shared/auth"
+)
+
+var (
+	// ErrKeyNotFound is returned when a key does not exist
+	ErrKeyNotFound = errors.New("api key not found")
+	// ErrInvalidKey is returned when a presented key is malformed or its secret does not match
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrKeyExpired is returned when a key has expired
+	ErrKeyExpired = errors.New("api key expired")
+	// ErrKeyRevoked is returned when a key has been revoked
+	ErrKeyRevoked = errors.New("api key revoked")
+)
+
+// Config configures the API key service
+type Config struct {
+	// Prefix identifies keys issued by this system, e.g. "vhv" yields "vhv_<id>.<secret>"
+	Prefix string // default: "sk"
+	// LastUsedInterval throttles last-used writes to at most one per key per interval
+	LastUsedInterval time.Duration // default: 1 minute
+}
+
+// IssueRequest describes a key to issue
+type IssueRequest struct {
+	TenantID  string
+	OwnerID   string
+	Name      string
+	Scopes    []string
+	Roles     []string
+	ExpiresAt *time.Time
+}
+
+// IssuedKey is returned once on issuance or rotation; the plaintext key cannot be recovered later
+type IssuedKey struct {
+	Key       *Key   `json:"key"`
+	Plaintext string `json:"api_key"`
+}
+
+// Service issues, rotates, revokes and authenticates API keys
+type Service struct {
+	store            Store
+	prefix           string
+	lastUsedInterval time.Duration
+	now              func() time.Time
+}
+
+// NewService creates an API key service
+func NewService(store Store, config Config) *Service {
+	if config.Prefix == "" {
+		config.Prefix = "sk"
+	}
+	if config.LastUsedInterval <= 0 {
+		config.LastUsedInterval = time.Minute
+	}
+	return &Service{
+		store:            store,
+		prefix:           config.Prefix,
+		lastUsedInterval: config.LastUsedInterval,
+		now:              time.Now,
+	}
+}
+
+// Issue creates a new key and returns its plaintext value
+func (s *Service) Issue(ctx context.Context, req IssueRequest) (*IssuedKey, error) {
+	id, err := generateKeyID()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{
+		ID:         id,
+		TenantID:   req.TenantID,
+		OwnerID:    req.OwnerID,
+		Name:       req.Name,
+		Scopes:     req.Scopes,
+		Roles:      req.Roles,
+		SecretHash: hashSecret(secret),
+		ExpiresAt:  req.ExpiresAt,
+		CreatedAt:  s.now(),
+	}
+	if err := s.store.Create(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to store api key: %w", err)
+	}
+
+	return &IssuedKey{Key: key, Plaintext: s.format(id, secret)}, nil
+}
+
+// Rotate replaces the secret of a key. The previous secret keeps working for
+// the grace period so clients can roll over without downtime; a zero grace
+// period invalidates it immediately.
+func (s *Service) Rotate(ctx context.Context, id string, grace time.Duration) (*IssuedKey, error) {
+	key, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsRevoked() {
+		return nil, ErrKeyRevoked
+	}
+
+	secret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	key.PreviousSecretHash = ""
+	key.PreviousExpiresAt = nil
+	if grace > 0 {
+		graceEnd := now.Add(grace)
+		key.PreviousSecretHash = key.SecretHash
+		key.PreviousExpiresAt = &graceEnd
+	}
+	key.SecretHash = hashSecret(secret)
+	key.RotatedAt = &now
+
+	if err := s.store.Update(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to rotate api key: %w", err)
+	}
+
+	return &IssuedKey{Key: key, Plaintext: s.format(id, secret)}, nil
+}
+
+// Revoke permanently disables a key
+func (s *Service) Revoke(ctx context.Context, id string) error {
+	key, err := s.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key.IsRevoked() {
+		return nil
+	}
+
+	now := s.now()
+	key.RevokedAt = &now
+	return s.store.Update(ctx, key)
+}
+
+// List returns the keys of a tenant
+func (s *Service) List(ctx context.Context, tenantID string) ([]*Key, error) {
+	return s.store.List(ctx, tenantID)
+}
+
+// Authenticate validates a plaintext key and returns the stored key
+func (s *Service) Authenticate(ctx context.Context, plaintext string) (*Key, error) {
+	id, secret, ok := s.parse(plaintext)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := s.store.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+
+	now := s.now()
+	if !s.secretMatches(key, secret, now) {
+		return nil, ErrInvalidKey
+	}
+	if key.IsRevoked() {
+		return nil, ErrKeyRevoked
+	}
+	if key.IsExpired(now) {
+		return nil, ErrKeyExpired
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= s.lastUsedInterval {
+		// Best effort: a failed bookkeeping write must not reject a valid key
+		if err := s.store.TouchLastUsed(ctx, key.ID, now); err == nil {
+			key.LastUsedAt = &now
+		}
+	}
+
+	return key, nil
+}
+
+func (s *Service) secretMatches(key *Key, secret string, now time.Time) bool {
+	hash := hashSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(key.SecretHash)) == 1 {
+		return true
+	}
+	if key.PreviousSecretHash != "" && key.PreviousExpiresAt != nil && now.Before(*key.PreviousExpiresAt) {
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(key.PreviousSecretHash)) == 1
+	}
+	return false
+}
+
+// format builds "<prefix>_<id>.<secret>"
+func (s *Service) format(id, secret string) string {
+	return s.prefix + "_" + id + "." + secret
+}
+
+// parse splits a plaintext key into its ID and secret
+func (s *Service) parse(plaintext string) (id, secret string, ok bool) {
+	rest, found := strings.CutPrefix(plaintext, s.prefix+"_")
+	if !found {
+		return "", "", false
+	}
+	id, secret, found = strings.Cut(rest, ".")
+	if !found || id == "" || secret == "" {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+// generateKeyID returns a random public identifier used to look keys up
+func generateKeyID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecret hashes a high-entropy secret; a fast hash is sufficient because
+// secrets are 256-bit random values rather than user-chosen passwords
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
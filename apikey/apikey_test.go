@@ -0,0 +1,98 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestService() (*Service, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	service := NewService(NewMemoryStore(), Config{Prefix: "vhv"})
+	service.now = func() time.Time { return now }
+	return service, &now
+}
+
+func TestService_IssueAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService()
+
+	issued, err := service.Issue(ctx, IssueRequest{TenantID: "tenant-1", OwnerID: "svc-1", Scopes: []string{"orders:read"}})
+	if err != nil {
+		t.Fatalf("failed to issue key: %v", err)
+	}
+	if !strings.HasPrefix(issued.Plaintext, "vhv_"+issued.Key.ID+".") {
+		t.Errorf("unexpected key format: %s", issued.Plaintext)
+	}
+	if strings.Contains(issued.Key.SecretHash, strings.SplitN(issued.Plaintext, ".", 2)[1]) {
+		t.Error("secret must not be stored in plaintext")
+	}
+
+	key, err := service.Authenticate(ctx, issued.Plaintext)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if key.TenantID != "tenant-1" || !key.HasScope("orders:read") || key.HasScope("orders:write") {
+		t.Errorf("unexpected key: %+v", key)
+	}
+	if key.LastUsedAt == nil || !key.LastUsedAt.Equal(*now) {
+		t.Error("expected last used time to be recorded")
+	}
+
+	for _, bad := range []string{"", "vhv_", "other_" + issued.Key.ID + ".x", issued.Plaintext + "x"} {
+		if _, err := service.Authenticate(ctx, bad); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestService_RotateWithGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService()
+
+	issued, _ := service.Issue(ctx, IssueRequest{TenantID: "tenant-1"})
+	rotated, err := service.Rotate(ctx, issued.Key.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+
+	if _, err := service.Authenticate(ctx, rotated.Plaintext); err != nil {
+		t.Errorf("expected new key to work: %v", err)
+	}
+	if _, err := service.Authenticate(ctx, issued.Plaintext); err != nil {
+		t.Errorf("expected old key to work during grace period: %v", err)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if _, err := service.Authenticate(ctx, issued.Plaintext); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected old key to be rejected after grace period, got %v", err)
+	}
+}
+
+func TestService_ExpiryAndRevocation(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService()
+
+	expiresAt := now.Add(time.Hour)
+	issued, _ := service.Issue(ctx, IssueRequest{TenantID: "tenant-1", ExpiresAt: &expiresAt})
+
+	*now = now.Add(2 * time.Hour)
+	if _, err := service.Authenticate(ctx, issued.Plaintext); !errors.Is(err, ErrKeyExpired) {
+		t.Errorf("expected ErrKeyExpired, got %v", err)
+	}
+
+	other, _ := service.Issue(ctx, IssueRequest{TenantID: "tenant-1"})
+	if err := service.Revoke(ctx, other.Key.ID); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if _, err := service.Authenticate(ctx, other.Plaintext); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("expected ErrKeyRevoked, got %v", err)
+	}
+
+	keys, _ := service.List(ctx, "tenant-1")
+	if len(keys) != 2 {
+		t.Errorf("expected 2 keys, got %d", len(keys))
+	}
+}
@@ -0,0 +1,228 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Key is a stored API key. Only hashes of the secret are persisted.
+type Key struct {
+	ID                 string     `bson:"_id" json:"id"`
+	TenantID           string     `bson:"tenant_id" json:"tenant_id"`
+	OwnerID            string     `bson:"owner_id" json:"owner_id"`
+	Name               string     `bson:"name" json:"name"`
+	Scopes             []string   `bson:"scopes" json:"scopes"`
+	Roles              []string   `bson:"roles,omitempty" json:"roles,omitempty"`
+	SecretHash         string     `bson:"secret_hash" json:"-"`
+	PreviousSecretHash string     `bson:"previous_secret_hash,omitempty" json:"-"`
+	PreviousExpiresAt  *time.Time `bson:"previous_expires_at,omitempty" json:"previous_expires_at,omitempty"`
+	ExpiresAt          *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt         *time.Time `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt          *time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	RotatedAt          *time.Time `bson:"rotated_at,omitempty" json:"rotated_at,omitempty"`
+	CreatedAt          time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// IsExpired reports whether the key has passed its expiry
+func (k *Key) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// IsRevoked reports whether the key has been revoked
+func (k *Key) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// HasScope reports whether the key grants the scope ("*" grants everything)
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Store persists API keys
+type Store interface {
+	// Create stores a new key
+	Create(ctx context.Context, key *Key) error
+	// Get returns a key by ID or ErrKeyNotFound
+	Get(ctx context.Context, id string) (*Key, error)
+	// List returns the keys of a tenant, newest first
+	List(ctx context.Context, tenantID string) ([]*Key, error)
+	// Update replaces a key
+	Update(ctx context.Context, key *Key) error
+	// TouchLastUsed records the last use time
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
+
+// MemoryStore is an in-memory Store, intended for tests and single-instance deployments
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys map[string]*Key
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys: make(map[string]*Key),
+	}
+}
+
+// Create stores a new key
+func (s *MemoryStore) Create(ctx context.Context, key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keys[key.ID]; exists {
+		return fmt.Errorf("api key %s already exists", key.ID)
+	}
+	copied := *key
+	s.keys[key.ID] = &copied
+	return nil
+}
+
+// Get returns a key by ID
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	copied := *key
+	return &copied, nil
+}
+
+// List returns the keys of a tenant, newest first
+func (s *MemoryStore) List(ctx context.Context, tenantID string) ([]*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []*Key
+	for _, key := range s.keys {
+		if key.TenantID == tenantID {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// Update replaces a key
+func (s *MemoryStore) Update(ctx context.Context, key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[key.ID]; !ok {
+		return ErrKeyNotFound
+	}
+	copied := *key
+	s.keys[key.ID] = &copied
+	return nil
+}
+
+// TouchLastUsed records the last use time
+func (s *MemoryStore) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	key.LastUsedAt = &at
+	return nil
+}
+
+// MongoStore is a MongoDB-backed Store
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a new MongoDB store
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+// EnsureIndexes creates the tenant listing index
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tenant_id index: %w", err)
+	}
+	return nil
+}
+
+// Create stores a new key
+func (s *MongoStore) Create(ctx context.Context, key *Key) error {
+	_, err := s.collection.InsertOne(ctx, key)
+	return err
+}
+
+// Get returns a key by ID
+func (s *MongoStore) Get(ctx context.Context, id string) (*Key, error) {
+	var key Key
+	err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// List returns the keys of a tenant, newest first
+func (s *MongoStore) List(ctx context.Context, tenantID string) ([]*Key, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"tenant_id": tenantID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []*Key
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Update replaces a key
+func (s *MongoStore) Update(ctx context.Context, key *Key) error {
+	result, err := s.collection.ReplaceOne(ctx, bson.M{"_id": key.ID}, key)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed records the last use time
+func (s *MongoStore) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_used_at": at}},
+	)
+	return err
+}
@@ -0,0 +1,363 @@
+// Package audit records the change history of documents: who changed what,
+// when and in which tenant, as field-level before/after diffs that can be
+// queried and replayed to reconstruct a document at a point in time.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrDocumentNotFound is returned when a document did not exist at the requested time
+var ErrDocumentNotFound = errors.New("document not found in audit history")
+
+// Operation is the kind of change recorded in an audit entry
+type Operation string
+
+const (
+	OperationCreate     Operation = "create"
+	OperationUpdate     Operation = "update"
+	OperationDelete     Operation = "delete" // hard delete
+	OperationSoftDelete Operation = "soft_delete"
+	OperationRestore    Operation = "restore"
+)
+
+// Redacted replaces the values of redacted fields in audit entries
+const Redacted = "[REDACTED]"
+
+// FieldChange is the change of a single field, addressed by its dotted path
+type FieldChange struct {
+	Path   string      `bson:"path" json:"path"`
+	Before interface{} `bson:"before" json:"before,omitempty"`
+	After  interface{} `bson:"after" json:"after,omitempty"`
+	Unset  bool        `bson:"unset,omitempty" json:"unset,omitempty"` // the field was removed
+}
+
+// Entry is a recorded change of one document
+type Entry struct {
+	ID             string        `bson:"_id" json:"id"`
+	TenantID       string        `bson:"tenant_id" json:"tenant_id,omitempty"`
+	Collection     string        `bson:"collection" json:"collection"`
+	DocumentID     string        `bson:"document_id" json:"document_id"`
+	Operation      Operation     `bson:"operation" json:"operation"`
+	ActorID        string        `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ImpersonatorID string        `bson:"impersonator_id,omitempty" json:"impersonator_id,omitempty"`
+	CorrelationID  string        `bson:"correlation_id,omitempty" json:"correlation_id,omitempty"`
+	Changes        []FieldChange `bson:"changes" json:"changes"`
+	Timestamp      time.Time     `bson:"timestamp" json:"timestamp"`
+}
+
+// Change is a document change to record
+type Change struct {
+	Operation Operation
+	Before    interface{} // the document before the change, nil on create
+	After     interface{} // the document after the change, nil on hard delete
+}
+
+// Config configures an Auditor
+type Config struct {
+	// Redact lists field paths whose values are replaced by Redacted; a path
+	// also covers its sub-fields. Changes of redacted fields are still recorded.
+	Redact []string
+	// Ignore lists field paths excluded from diffs (e.g. "updated_at")
+	Ignore []string
+	// OnError receives write failures instead of them being returned to the caller
+	OnError func(ctx context.Context, entries []*Entry, err error)
+}
+
+// Auditor turns document changes into audit entries and writes them to a Store
+type Auditor struct {
+	store   Store
+	redact  []string
+	ignore  []string
+	onError func(ctx context.Context, entries []*Entry, err error)
+	now     func() time.Time
+}
+
+// NewAuditor creates an auditor writing to the store
+func NewAuditor(store Store, config Config) *Auditor {
+	return &Auditor{
+		store:   store,
+		redact:  config.Redact,
+		ignore:  config.Ignore,
+		onError: config.OnError,
+		now:     time.Now,
+	}
+}
+
+// Record writes audit entries for changes of documents in the collection.
+// The actor, impersonator, correlation ID and (unless the document has a
+// tenant_id field) tenant are taken from the context. Updates that change
+// no field are not recorded.
+func (a *Auditor) Record(ctx context.Context, collection string, changes ...Change) error {
+	entries := make([]*Entry, 0, len(changes))
+	for _, change := range changes {
+		entry, err := a.entry(ctx, collection, change)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := a.store.Write(ctx, entries); err != nil {
+		err = fmt.Errorf("failed to write audit entries: %w", err)
+		if a.onError != nil {
+			a.onError(ctx, entries, err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// History returns the entries matching the query, oldest first. The tenant
+// defaults to the tenant of the context.
+func (a *Auditor) History(ctx context.Context, query Query) ([]*Entry, error) {
+	if query.TenantID == "" {
+		query.TenantID, _ = pkgctx.GetTenantID(ctx)
+	}
+	return a.store.History(ctx, query)
+}
+
+// At reconstructs a document of the context tenant as it was at the given
+// time by replaying its history. It returns ErrDocumentNotFound when the
+// document did not exist at that time.
+func (a *Auditor) At(ctx context.Context, collection, documentID string, at time.Time) (bson.M, error) {
+	entries, err := a.History(ctx, Query{Collection: collection, DocumentID: documentID, To: at})
+	if err != nil {
+		return nil, err
+	}
+	doc := Reconstruct(entries)
+	if doc == nil {
+		return nil, ErrDocumentNotFound
+	}
+	return doc, nil
+}
+
+// Reconstruct replays the entries of one document, oldest first, and returns
+// the resulting document, or nil when it does not exist after the last entry.
+// Redacted fields hold Redacted.
+func Reconstruct(entries []*Entry) bson.M {
+	var doc bson.M
+	for _, entry := range entries {
+		switch entry.Operation {
+		case OperationDelete:
+			doc = nil
+			continue
+		case OperationCreate:
+			doc = bson.M{}
+		default:
+			if doc == nil {
+				doc = bson.M{}
+			}
+		}
+
+		for _, change := range entry.Changes {
+			if change.Unset {
+				unsetPath(doc, change.Path)
+			}
+		}
+		for _, change := range entry.Changes {
+			if !change.Unset {
+				setPath(doc, change.Path, change.After)
+			}
+		}
+	}
+	return doc
+}
+
+// DocumentID returns the string form of a document _id used in audit entries
+func DocumentID(id interface{}) string {
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (a *Auditor) entry(ctx context.Context, collection string, change Change) (*Entry, error) {
+	before, err := toDocument(change.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := toDocument(change.After)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := a.diff(before, after)
+	if len(changes) == 0 && change.Operation == OperationUpdate {
+		return nil, nil
+	}
+
+	current := after
+	if current == nil {
+		current = before
+	}
+	tenantID, _ := current["tenant_id"].(string)
+	if tenantID == "" {
+		tenantID, _ = pkgctx.GetTenantID(ctx)
+	}
+	actorID, _ := pkgctx.GetUserID(ctx)
+
+	entry := &Entry{
+		ID:            primitive.NewObjectID().Hex(),
+		TenantID:      tenantID,
+		Collection:    collection,
+		DocumentID:    DocumentID(current["_id"]),
+		Operation:     change.Operation,
+		ActorID:       actorID,
+		CorrelationID: pkgctx.GetCorrelationID(ctx),
+		Changes:       changes,
+		Timestamp:     a.now().UTC().Truncate(time.Millisecond),
+	}
+	if impersonator := pkgctx.GetActor(ctx); impersonator != nil {
+		entry.ImpersonatorID = impersonator.UserID
+	}
+	return entry, nil
+}
+
+// diff compares the flattened documents and returns the changed fields sorted by path
+func (a *Auditor) diff(before, after bson.M) []FieldChange {
+	old := make(map[string]interface{})
+	flatten("", before, old)
+	updated := make(map[string]interface{})
+	flatten("", after, updated)
+
+	paths := make([]string, 0, len(old)+len(updated))
+	for path := range old {
+		paths = append(paths, path)
+	}
+	for path := range updated {
+		if _, ok := old[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []FieldChange
+	for _, path := range paths {
+		if matchesPath(a.ignore, path) {
+			continue
+		}
+		oldValue, hadOld := old[path]
+		newValue, hasNew := updated[path]
+		if hadOld && hasNew && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		change := FieldChange{Path: path, Before: oldValue, After: newValue, Unset: !hasNew}
+		if matchesPath(a.redact, path) {
+			if hadOld {
+				change.Before = Redacted
+			}
+			if hasNew {
+				change.After = Redacted
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// toDocument converts a document (struct, map or bson document) to bson.M
+func toDocument(document interface{}) (bson.M, error) {
+	if document == nil || (reflect.ValueOf(document).Kind() == reflect.Ptr && reflect.ValueOf(document).IsNil()) {
+		return nil, nil
+	}
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audited document: %w", err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode audited document: %w", err)
+	}
+	return doc, nil
+}
+
+// flatten collects the leaf values of non-empty nested documents by dotted path
+func flatten(prefix string, doc bson.M, out map[string]interface{}) {
+	for key, value := range doc {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested := subDocument(value); len(nested) > 0 {
+			flatten(path, nested, out)
+			continue
+		}
+		out[path] = value
+	}
+}
+
+func subDocument(value interface{}) bson.M {
+	switch v := value.(type) {
+	case bson.M:
+		return v
+	case map[string]interface{}:
+		return v
+	case bson.D:
+		doc := make(bson.M, len(v))
+		for _, e := range v {
+			doc[e.Key] = e.Value
+		}
+		return doc
+	}
+	return nil
+}
+
+// matchesPath reports whether path equals one of the paths or is nested below one
+func matchesPath(paths []string, path string) bool {
+	for _, candidate := range paths {
+		if path == candidate || strings.HasPrefix(path, candidate+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func setPath(doc bson.M, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := doc[key].(bson.M)
+		if !ok {
+			nested = subDocument(doc[key])
+			if nested == nil {
+				nested = bson.M{}
+			}
+			doc[key] = nested
+		}
+		doc = nested
+	}
+	doc[keys[len(keys)-1]] = value
+}
+
+func unsetPath(doc bson.M, path string) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := doc[key].(bson.M)
+		if !ok {
+			return
+		}
+		doc = nested
+	}
+	delete(doc, keys[len(keys)-1])
+}
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAuditor_Diff(t *testing.T) {
+	auditor := NewAuditor(NewMemoryStore(), Config{Redact: []string{"password", "card"}, Ignore: []string{"updated_at"}})
+
+	tests := []struct {
+		name   string
+		before bson.M
+		after  bson.M
+		want   []FieldChange
+	}{
+		{
+			name:   "create",
+			before: nil,
+			after:  bson.M{"_id": "u1", "name": "Alice", "profile": bson.M{"age": int32(30)}},
+			want: []FieldChange{
+				{Path: "_id", After: "u1"},
+				{Path: "name", After: "Alice"},
+				{Path: "profile.age", After: int32(30)},
+			},
+		},
+		{
+			name:   "nested update and removal",
+			before: bson.M{"_id": "u1", "profile": bson.M{"age": int32(30), "city": "Hanoi"}, "updated_at": int32(1)},
+			after:  bson.M{"_id": "u1", "profile": bson.M{"age": int32(31)}, "updated_at": int32(2)},
+			want: []FieldChange{
+				{Path: "profile.age", Before: int32(30), After: int32(31)},
+				{Path: "profile.city", Before: "Hanoi", Unset: true},
+			},
+		},
+		{
+			name:   "redacted fields",
+			before: bson.M{"_id": "u1", "password": "old", "card": bson.M{"number": "4111"}},
+			after:  bson.M{"_id": "u1", "password": "new", "card": bson.M{"number": "4242"}},
+			want: []FieldChange{
+				{Path: "card.number", Before: Redacted, After: Redacted},
+				{Path: "password", Before: Redacted, After: Redacted},
+			},
+		},
+		{
+			name:   "unchanged",
+			before: bson.M{"_id": "u1", "name": "Alice"},
+			after:  bson.M{"_id": "u1", "name": "Alice", "updated_at": int32(2)},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditor.diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuditor_RecordAndReconstruct(t *testing.T) {
+	store := NewMemoryStore()
+	auditor := NewAuditor(store, Config{})
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	auditor.now = func() time.Time { return clock }
+
+	ctx := pkgctx.WithRequestContext(context.Background(), &pkgctx.RequestContext{
+		UserID:        "user-1",
+		TenantID:      "acme",
+		CorrelationID: "corr-1",
+		Actor:         &pkgctx.Actor{UserID: "support-1"},
+	})
+
+	v1 := bson.M{"_id": "o1", "status": "new", "total": int32(10)}
+	v2 := bson.M{"_id": "o1", "status": "paid", "total": int32(10)}
+	steps := []Change{
+		{Operation: OperationCreate, After: v1},
+		{Operation: OperationUpdate, Before: v1, After: v1}, // no-op, not recorded
+		{Operation: OperationUpdate, Before: v1, After: v2},
+		{Operation: OperationDelete, Before: v2},
+	}
+	for _, step := range steps {
+		if err := auditor.Record(ctx, "orders", step); err != nil {
+			t.Fatalf("failed to record change: %v", err)
+		}
+		clock = clock.Add(time.Hour)
+	}
+
+	history, err := auditor.History(ctx, Query{Collection: "orders", DocumentID: "o1"})
+	if err != nil || len(history) != 3 {
+		t.Fatalf("expected 3 entries, got %d (%v)", len(history), err)
+	}
+	entry := history[1]
+	if entry.TenantID != "acme" || entry.ActorID != "user-1" || entry.ImpersonatorID != "support-1" ||
+		entry.CorrelationID != "corr-1" || entry.Operation != OperationUpdate {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bson.M
+		err  error
+	}{
+		{"before creation", time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), nil, ErrDocumentNotFound},
+		{"after creation", time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), v1, nil},
+		{"after update", time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), v2, nil},
+		{"after deletion", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), nil, ErrDocumentNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := auditor.At(ctx, "orders", "o1", tt.at)
+			if !errors.Is(err, tt.err) || !reflect.DeepEqual(doc, tt.want) {
+				t.Errorf("expected %v (%v), got %v (%v)", tt.want, tt.err, doc, err)
+			}
+		})
+	}
+
+	// Other tenants do not see the history
+	other := pkgctx.WithTenantID(context.Background(), "globex")
+	if history, _ := auditor.History(other, Query{Collection: "orders"}); len(history) != 0 {
+		t.Errorf("expected no history for another tenant, got %d entries", len(history))
+	}
+}
+
+func TestAuditor_OnError(t *testing.T) {
+	failing := errors.New("store unavailable")
+	var reported error
+	auditor := NewAuditor(storeFunc(func(ctx context.Context, entries []*Entry) error { return failing }), Config{
+		OnError: func(ctx context.Context, entries []*Entry, err error) { reported = err },
+	})
+
+	if err := auditor.Record(context.Background(), "orders", Change{Operation: OperationCreate, After: bson.M{"_id": "o1"}}); err != nil {
+		t.Fatalf("expected error to be reported, got %v", err)
+	}
+	if !errors.Is(reported, failing) {
+		t.Errorf("expected reported store error, got %v", reported)
+	}
+}
+
+// storeFunc is a Store whose writes call the function
+type storeFunc func(ctx context.Context, entries []*Entry) error
+
+func (f storeFunc) Write(ctx context.Context, entries []*Entry) error {
+	return f(ctx, entries)
+}
+
+func (f storeFunc) History(ctx context.Context, query Query) ([]*Entry, error) {
+	return nil, nil
+}
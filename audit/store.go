@@ -0,0 +1,273 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vhvplatform/go-shared/clickhouse"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Query selects the history entries of documents
+type Query struct {
+	TenantID   string
+	Collection string
+	DocumentID string    // optional: all documents of the collection when empty
+	ActorID    string    // optional
+	From       time.Time // optional: inclusive lower bound
+	To         time.Time // optional: inclusive upper bound
+	Limit      int64     // optional: no limit when zero
+}
+
+// Store persists audit entries
+type Store interface {
+	// Write appends entries to the history
+	Write(ctx context.Context, entries []*Entry) error
+	// History returns the entries matching the query, oldest first
+	History(ctx context.Context, query Query) ([]*Entry, error)
+}
+
+// MongoStore stores audit entries in a MongoDB history collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a MongoDB-backed audit store
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+// EnsureIndexes creates the index used by history queries
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "tenant_id", Value: 1},
+			{Key: "collection", Value: 1},
+			{Key: "document_id", Value: 1},
+			{Key: "timestamp", Value: 1},
+		},
+	})
+	return err
+}
+
+// Write inserts the entries
+func (s *MongoStore) Write(ctx context.Context, entries []*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	documents := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		documents[i] = entry
+	}
+	_, err := s.collection.InsertMany(ctx, documents)
+	return err
+}
+
+// History returns the entries matching the query, oldest first
+func (s *MongoStore) History(ctx context.Context, query Query) ([]*Entry, error) {
+	filter := bson.M{
+		"tenant_id":  query.TenantID,
+		"collection": query.Collection,
+	}
+	if query.DocumentID != "" {
+		filter["document_id"] = query.DocumentID
+	}
+	if query.ActorID != "" {
+		filter["actor_id"] = query.ActorID
+	}
+	if timestamp := timeRange(query); len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+	if query.Limit > 0 {
+		findOptions.SetLimit(query.Limit)
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*Entry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func timeRange(query Query) bson.M {
+	timestamp := bson.M{}
+	if !query.From.IsZero() {
+		timestamp["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		timestamp["$lte"] = query.To
+	}
+	return timestamp
+}
+
+// ClickHouseStore writes audit entries to a ClickHouse table with the columns
+// (id String, tenant_id String, collection String, document_id String,
+// operation String, actor_id String, impersonator_id String,
+// correlation_id String, changes String, timestamp DateTime64(3)).
+// Changes are stored as JSON, so values read back have JSON types.
+type ClickHouseStore struct {
+	client *clickhouse.Client
+	table  string
+}
+
+// NewClickHouseStore creates a ClickHouse audit store for the table
+func NewClickHouseStore(client *clickhouse.Client, table string) *ClickHouseStore {
+	return &ClickHouseStore{client: client, table: table}
+}
+
+// clickHouseEntry is the row layout of the audit table
+type clickHouseEntry struct {
+	ID             string    `ch:"id"`
+	TenantID       string    `ch:"tenant_id"`
+	Collection     string    `ch:"collection"`
+	DocumentID     string    `ch:"document_id"`
+	Operation      string    `ch:"operation"`
+	ActorID        string    `ch:"actor_id"`
+	ImpersonatorID string    `ch:"impersonator_id"`
+	CorrelationID  string    `ch:"correlation_id"`
+	Changes        string    `ch:"changes"`
+	Timestamp      time.Time `ch:"timestamp"`
+}
+
+// Write inserts the entries in a single batch
+func (s *ClickHouseStore) Write(ctx context.Context, entries []*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	batch, err := s.client.NewBatchInserter(ctx, fmt.Sprintf(
+		"INSERT INTO %s (id, tenant_id, collection, document_id, operation, actor_id, impersonator_id, correlation_id, changes, timestamp)", s.table))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		changes, err := json.Marshal(entry.Changes)
+		if err != nil {
+			batch.Abort()
+			return fmt.Errorf("failed to encode audit changes: %w", err)
+		}
+		if err := batch.Append(entry.ID, entry.TenantID, entry.Collection, entry.DocumentID, string(entry.Operation),
+			entry.ActorID, entry.ImpersonatorID, entry.CorrelationID, string(changes), entry.Timestamp); err != nil {
+			batch.Abort()
+			return fmt.Errorf("failed to append audit entry: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to send audit entries: %w", err)
+	}
+	return nil
+}
+
+// History returns the entries matching the query, oldest first
+func (s *ClickHouseStore) History(ctx context.Context, query Query) ([]*Entry, error) {
+	conditions := []string{"tenant_id = ?", "collection = ?"}
+	args := []interface{}{query.TenantID, query.Collection}
+	if query.DocumentID != "" {
+		conditions = append(conditions, "document_id = ?")
+		args = append(args, query.DocumentID)
+	}
+	if query.ActorID != "" {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, query.ActorID)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, query.From)
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, query.To)
+	}
+
+	sql := fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY timestamp, id", s.table, strings.Join(conditions, " AND "))
+	if query.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", query.Limit)
+	}
+
+	var rows []clickHouseEntry
+	if err := s.client.Query(ctx, &rows, sql, args...); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, len(rows))
+	for i, row := range rows {
+		entry := &Entry{
+			ID:             row.ID,
+			TenantID:       row.TenantID,
+			Collection:     row.Collection,
+			DocumentID:     row.DocumentID,
+			Operation:      Operation(row.Operation),
+			ActorID:        row.ActorID,
+			ImpersonatorID: row.ImpersonatorID,
+			CorrelationID:  row.CorrelationID,
+			Timestamp:      row.Timestamp,
+		}
+		if err := json.Unmarshal([]byte(row.Changes), &entry.Changes); err != nil {
+			return nil, fmt.Errorf("failed to decode audit changes: %w", err)
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// MemoryStore is an in-memory Store, intended for tests
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries []*Entry
+}
+
+// NewMemoryStore creates an in-memory audit store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Write appends entries to the history
+func (s *MemoryStore) Write(ctx context.Context, entries []*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+// History returns the entries matching the query, oldest first
+func (s *MemoryStore) History(ctx context.Context, query Query) ([]*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []*Entry
+	for _, entry := range s.entries {
+		if entry.TenantID != query.TenantID || entry.Collection != query.Collection ||
+			(query.DocumentID != "" && entry.DocumentID != query.DocumentID) ||
+			(query.ActorID != "" && entry.ActorID != query.ActorID) ||
+			(!query.From.IsZero() && entry.Timestamp.Before(query.From)) ||
+			(!query.To.IsZero() && entry.Timestamp.After(query.To)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	if query.Limit > 0 && int64(len(entries)) > query.Limit {
+		entries = entries[:query.Limit]
+	}
+	return entries, nil
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1" //nolint:gosec // SHA-1 is mandated by the k-anonymity range format, not used for security
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// breachPrefixLength is the number of hex characters of the SHA-1 hash sent to a range source
+const breachPrefixLength = 5
+
+// BreachChecker reports whether a password appears in a breached-password corpus
+type BreachChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// BreachRangeSource returns the hash suffixes (and occurrence counts) for a SHA-1 prefix.
+// Only the 5-character prefix leaves the caller, so the source never learns the password.
+type BreachRangeSource interface {
+	Range(ctx context.Context, prefix string) (map[string]int, error)
+}
+
+// KAnonymityBreachChecker checks passwords against a range source using k-anonymity
+type KAnonymityBreachChecker struct {
+	source   BreachRangeSource
+	minCount int
+}
+
+// NewKAnonymityBreachChecker creates a breach checker for the given range source.
+// A password is considered breached when it was seen at least minCount times (default: 1).
+func NewKAnonymityBreachChecker(source BreachRangeSource, minCount int) *KAnonymityBreachChecker {
+	if minCount < 1 {
+		minCount = 1
+	}
+	return &KAnonymityBreachChecker{
+		source:   source,
+		minCount: minCount,
+	}
+}
+
+// IsBreached checks if the password appears in the range source
+func (c *KAnonymityBreachChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	prefix, suffix := splitPasswordHash(password)
+
+	suffixes, err := c.source.Range(ctx, prefix)
+	if err != nil {
+		return false, fmt.Errorf("failed to query breach range: %w", err)
+	}
+
+	return suffixes[suffix] >= c.minCount, nil
+}
+
+// LocalBreachCorpus is an in-memory breached-password corpus bucketed by SHA-1 prefix
+type LocalBreachCorpus struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string]int
+}
+
+// NewLocalBreachCorpus creates an empty local breach corpus
+func NewLocalBreachCorpus() *LocalBreachCorpus {
+	return &LocalBreachCorpus{
+		buckets: make(map[string]map[string]int),
+	}
+}
+
+// AddPassword adds a plaintext password to the corpus
+func (c *LocalBreachCorpus) AddPassword(password string) {
+	prefix, suffix := splitPasswordHash(password)
+	c.add(prefix, suffix, 1)
+}
+
+// AddHash adds a full SHA-1 hash (40 hex characters) with its occurrence count.
+// Entries with a zero count (range padding) are ignored.
+func (c *LocalBreachCorpus) AddHash(hash string, count int) error {
+	hash = strings.ToUpper(strings.TrimSpace(hash))
+	if len(hash) != sha1.Size*2 {
+		return fmt.Errorf("invalid SHA-1 hash length: %d", len(hash))
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return fmt.Errorf("invalid SHA-1 hash: %w", err)
+	}
+	if count < 1 {
+		return nil
+	}
+	c.add(hash[:breachPrefixLength], hash[breachPrefixLength:], count)
+	return nil
+}
+
+// LoadHashes loads full SHA-1 hashes from a reader, one "HASH[:COUNT]" entry per line
+func (c *LocalBreachCorpus) LoadHashes(r io.Reader) error {
+	return scanBreachLines(r, func(hash string, count int) error {
+		return c.AddHash(hash, count)
+	})
+}
+
+// LoadRange loads a range file for a single prefix, one "SUFFIX:COUNT" entry per line
+func (c *LocalBreachCorpus) LoadRange(prefix string, r io.Reader) error {
+	prefix = strings.ToUpper(prefix)
+	if len(prefix) != breachPrefixLength {
+		return fmt.Errorf("invalid range prefix: %s", prefix)
+	}
+	return scanBreachLines(r, func(suffix string, count int) error {
+		return c.AddHash(prefix+suffix, count)
+	})
+}
+
+// Range returns the hash suffixes stored for a prefix
+func (c *LocalBreachCorpus) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	prefix = strings.ToUpper(prefix)
+	if len(prefix) != breachPrefixLength {
+		return nil, fmt.Errorf("invalid range prefix: %s", prefix)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	bucket := c.buckets[prefix]
+	result := make(map[string]int, len(bucket))
+	for suffix, count := range bucket {
+		result[suffix] = count
+	}
+	return result, nil
+}
+
+// IsBreached checks if the password appears in the corpus
+func (c *LocalBreachCorpus) IsBreached(ctx context.Context, password string) (bool, error) {
+	prefix, suffix := splitPasswordHash(password)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.buckets[prefix][suffix] > 0, nil
+}
+
+// Size returns the number of hashes in the corpus
+func (c *LocalBreachCorpus) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	size := 0
+	for _, bucket := range c.buckets {
+		size += len(bucket)
+	}
+	return size
+}
+
+func (c *LocalBreachCorpus) add(prefix, suffix string, count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bucket, exists := c.buckets[prefix]
+	if !exists {
+		bucket = make(map[string]int)
+		c.buckets[prefix] = bucket
+	}
+	bucket[suffix] += count
+}
+
+// splitPasswordHash returns the uppercase SHA-1 prefix and suffix of a password
+func splitPasswordHash(password string) (string, string) {
+	sum := sha1.Sum([]byte(password)) //nolint:gosec // see import comment
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:breachPrefixLength], hash[breachPrefixLength:]
+}
+
+// scanBreachLines parses "VALUE[:COUNT]" lines, skipping blanks and comments
+func scanBreachLines(r io.Reader, fn func(value string, count int) error) error {
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		value, countStr, hasCount := strings.Cut(line, ":")
+		count := 1
+		if hasCount {
+			parsed, err := strconv.Atoi(strings.TrimSpace(countStr))
+			if err != nil {
+				return fmt.Errorf("line %d: invalid count: %w", lineNo, err)
+			}
+			count = parsed
+		}
+
+		if err := fn(value, count); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"github.com/vhvplatform/go-shared/jwt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Impersonation audit event types
+const (
+	ImpersonationStarted = "impersonation.started"
+	ImpersonationRequest = "impersonation.request"
+	ImpersonationEnded   = "impersonation.ended"
+)
+
+var (
+	// ErrImpersonationNotAllowed is returned when the target may not be impersonated
+	ErrImpersonationNotAllowed = errors.New("impersonation not allowed")
+	// ErrImpersonationReasonRequired is returned when no reason is given for impersonation
+	ErrImpersonationReasonRequired = errors.New("impersonation reason required")
+)
+
+// DefaultImpersonationBlockedPermissions are denied while impersonating unless configured otherwise
+var DefaultImpersonationBlockedPermissions = []string{
+	"users.impersonate",
+	"users.password.change",
+	"users.mfa.manage",
+	"users.delete",
+	"api_keys.*",
+	"billing.*",
+}
+
+// ImpersonationConfig configures impersonation
+type ImpersonationConfig struct {
+	TTL                time.Duration // default: 30 minutes
+	BlockedPermissions []string      // default: DefaultImpersonationBlockedPermissions
+}
+
+// ImpersonationTarget is the user to impersonate
+type ImpersonationTarget struct {
+	UserID      string
+	TenantID    string
+	Email       string
+	Roles       []string
+	Permissions []string
+}
+
+// ImpersonationAuditEvent is an audit record of impersonation activity
+type ImpersonationAuditEvent struct {
+	Type          string    `bson:"type" json:"type"`
+	ActorID       string    `bson:"actor_id" json:"actor_id"`
+	ActorTenantID string    `bson:"actor_tenant_id,omitempty" json:"actor_tenant_id,omitempty"`
+	ActorEmail    string    `bson:"actor_email,omitempty" json:"actor_email,omitempty"`
+	UserID        string    `bson:"user_id" json:"user_id"`
+	TenantID      string    `bson:"tenant_id" json:"tenant_id"`
+	Reason        string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	Method        string    `bson:"method,omitempty" json:"method,omitempty"`
+	Path          string    `bson:"path,omitempty" json:"path,omitempty"`
+	StatusCode    int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	IPAddress     string    `bson:"ip_address,omitempty" json:"ip_address,omitempty"`
+	CorrelationID string    `bson:"correlation_id,omitempty" json:"correlation_id,omitempty"`
+	Timestamp     time.Time `bson:"timestamp" json:"timestamp"`
+}
+
+// ImpersonationAuditLog records impersonation activity
+type ImpersonationAuditLog interface {
+	Record(ctx context.Context, event *ImpersonationAuditEvent) error
+}
+
+// MongoImpersonationAuditLog stores impersonation audit events in MongoDB
+type MongoImpersonationAuditLog struct {
+	collection *mongo.Collection
+}
+
+// NewMongoImpersonationAuditLog creates a MongoDB-backed audit log
+func NewMongoImpersonationAuditLog(collection *mongo.Collection) *MongoImpersonationAuditLog {
+	return &MongoImpersonationAuditLog{collection: collection}
+}
+
+// EnsureIndexes creates indexes for querying by actor and by impersonated user
+func (l *MongoImpersonationAuditLog) EnsureIndexes(ctx context.Context) error {
+	_, err := l.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create audit indexes: %w", err)
+	}
+	return nil
+}
+
+// Record stores an audit event
+func (l *MongoImpersonationAuditLog) Record(ctx context.Context, event *ImpersonationAuditEvent) error {
+	_, err := l.collection.InsertOne(ctx, event)
+	return err
+}
+
+// Impersonator issues impersonation tokens for super admins
+type Impersonator struct {
+	jwtManager *jwt.Manager
+	auditLog   ImpersonationAuditLog
+	ttl        time.Duration
+	blocked    []string
+}
+
+// NewImpersonator creates an impersonator
+func NewImpersonator(jwtManager *jwt.Manager, auditLog ImpersonationAuditLog, config ImpersonationConfig) *Impersonator {
+	if config.TTL <= 0 {
+		config.TTL = 30 * time.Minute
+	}
+	if config.BlockedPermissions == nil {
+		config.BlockedPermissions = DefaultImpersonationBlockedPermissions
+	}
+	return &Impersonator{
+		jwtManager: jwtManager,
+		auditLog:   auditLog,
+		ttl:        config.TTL,
+		blocked:    config.BlockedPermissions,
+	}
+}
+
+// Start issues a token that acts as target on behalf of the super admin in ctx.
+// The token carries the admin in its "act" claim and the blocked permissions.
+func (i *Impersonator) Start(ctx context.Context, target ImpersonationTarget, reason string) (string, error) {
+	if !IsSuperAdmin(ctx) {
+		return "", ErrPermissionDenied
+	}
+	if reason == "" {
+		return "", ErrImpersonationReasonRequired
+	}
+
+	rc := pkgctx.GetRequestContext(ctx)
+	if rc.IsImpersonated() || target.UserID == "" || target.UserID == rc.UserID {
+		return "", ErrImpersonationNotAllowed
+	}
+	for _, role := range target.Roles {
+		if role == "super_admin" {
+			return "", ErrImpersonationNotAllowed
+		}
+	}
+
+	now := time.Now()
+	claims := jwt.Claims{
+		UserID:      target.UserID,
+		TenantID:    target.TenantID,
+		Email:       target.Email,
+		Roles:       target.Roles,
+		Permissions: target.Permissions,
+		Actor: &jwt.Actor{
+			UserID:   rc.UserID,
+			TenantID: rc.TenantID,
+			Email:    rc.Email,
+		},
+		BlockedPermissions: i.blocked,
+	}
+	claims.ExpiresAt = gojwt.NewNumericDate(now.Add(i.ttl))
+
+	token, err := i.jwtManager.GenerateTokenWithClaims(claims)
+	if err != nil {
+		return "", err
+	}
+
+	event := &ImpersonationAuditEvent{
+		Type:          ImpersonationStarted,
+		ActorID:       rc.UserID,
+		ActorTenantID: rc.TenantID,
+		ActorEmail:    rc.Email,
+		UserID:        target.UserID,
+		TenantID:      target.TenantID,
+		Reason:        reason,
+		CorrelationID: rc.CorrelationID,
+		Timestamp:     now,
+	}
+	// Impersonation must never happen without an audit record
+	if err := i.auditLog.Record(ctx, event); err != nil {
+		return "", fmt.Errorf("failed to record impersonation: %w", err)
+	}
+
+	return token, nil
+}
+
+// End records the end of an impersonation session for the impersonated request in ctx.
+// Tokens are short-lived; clients end impersonation by discarding the token.
+func (i *Impersonator) End(ctx context.Context) error {
+	rc := pkgctx.GetRequestContext(ctx)
+	if !rc.IsImpersonated() {
+		return nil
+	}
+
+	return i.auditLog.Record(ctx, &ImpersonationAuditEvent{
+		Type:          ImpersonationEnded,
+		ActorID:       rc.Actor.UserID,
+		ActorTenantID: rc.Actor.TenantID,
+		ActorEmail:    rc.Actor.Email,
+		UserID:        rc.UserID,
+		TenantID:      rc.TenantID,
+		CorrelationID: rc.CorrelationID,
+		Timestamp:     time.Now(),
+	})
+}
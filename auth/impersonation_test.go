@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"github.com/vhvplatform/go-shared/jwt"
+)
+
+type recordingAuditLog struct {
+	events []*ImpersonationAuditEvent
+}
+
+func (l *recordingAuditLog) Record(ctx context.Context, event *ImpersonationAuditEvent) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+func TestImpersonator_Start(t *testing.T) {
+	manager := jwt.NewManager("secret", 3600, 86400)
+	auditLog := &recordingAuditLog{}
+	impersonator := NewImpersonator(manager, auditLog, ImpersonationConfig{})
+
+	admin := pkgctx.WithRequestContext(context.Background(), &pkgctx.RequestContext{
+		UserID: "admin-1", TenantID: "platform", Roles: []string{"super_admin"},
+	})
+	target := ImpersonationTarget{UserID: "user-1", TenantID: "tenant-1", Permissions: []string{"*"}}
+
+	token, err := impersonator.Start(admin, target, "ticket #42")
+	if err != nil {
+		t.Fatalf("failed to start impersonation: %v", err)
+	}
+
+	claims, err := manager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("invalid impersonation token: %v", err)
+	}
+	if !claims.IsImpersonation() || claims.Actor.UserID != "admin-1" || claims.UserID != "user-1" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if len(auditLog.events) != 1 || auditLog.events[0].Type != ImpersonationStarted || auditLog.events[0].Reason != "ticket #42" {
+		t.Errorf("expected start event to be recorded, got %+v", auditLog.events)
+	}
+
+	regular := pkgctx.WithRequestContext(context.Background(), &pkgctx.RequestContext{UserID: "user-2", Roles: []string{"tenant_admin"}})
+	if _, err := impersonator.Start(regular, target, "reason"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied for non super admin, got %v", err)
+	}
+	if _, err := impersonator.Start(admin, target, ""); !errors.Is(err, ErrImpersonationReasonRequired) {
+		t.Errorf("expected ErrImpersonationReasonRequired, got %v", err)
+	}
+	otherAdmin := ImpersonationTarget{UserID: "admin-2", Roles: []string{"super_admin"}}
+	if _, err := impersonator.Start(admin, otherAdmin, "reason"); !errors.Is(err, ErrImpersonationNotAllowed) {
+		t.Errorf("expected ErrImpersonationNotAllowed for super admin target, got %v", err)
+	}
+}
+
+func TestHasPermission_BlockedDuringImpersonation(t *testing.T) {
+	ctx := pkgctx.WithRequestContext(context.Background(), &pkgctx.RequestContext{
+		UserID:             "user-1",
+		Permissions:        []string{"*"},
+		Actor:              &pkgctx.Actor{UserID: "admin-1"},
+		BlockedPermissions: []string{"billing.*", "users.delete"},
+	})
+
+	if !HasPermission(ctx, "orders.read") {
+		t.Error("expected unblocked permission to be granted")
+	}
+	for _, permission := range []string{"billing.refund", "users.delete"} {
+		if HasPermission(ctx, permission) {
+			t.Errorf("expected %s to be blocked during impersonation", permission)
+		}
+	}
+}
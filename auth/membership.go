@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vhvplatform/go-shared/jwt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrNotMember is returned when the user has no relation with the tenant
+	ErrNotMember = errors.New("user is not a member of the tenant")
+	// ErrMembershipInactive is returned when the user's relation with the tenant is inactive
+	ErrMembershipInactive = errors.New("tenant membership is inactive")
+)
+
+// MembershipStore persists user-tenant relations
+type MembershipStore interface {
+	// Get returns the relation or ErrNotMember
+	Get(ctx context.Context, userID, tenantID string) (*UserTenantRelation, error)
+	// ListByUser returns all relations of a user
+	ListByUser(ctx context.Context, userID string) ([]*UserTenantRelation, error)
+	// Save creates or replaces a relation
+	Save(ctx context.Context, relation *UserTenantRelation) error
+	// Delete removes a relation
+	Delete(ctx context.Context, userID, tenantID string) error
+}
+
+// MongoMembershipStore stores user-tenant relations in MongoDB
+type MongoMembershipStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoMembershipStore creates a MongoDB-backed membership store
+func NewMongoMembershipStore(collection *mongo.Collection) *MongoMembershipStore {
+	return &MongoMembershipStore{collection: collection}
+}
+
+// EnsureIndexes creates the unique (user_id, tenant_id) index and the tenant listing index
+func (s *MongoMembershipStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "tenant_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create membership indexes: %w", err)
+	}
+	return nil
+}
+
+// Get returns the relation between a user and a tenant
+func (s *MongoMembershipStore) Get(ctx context.Context, userID, tenantID string) (*UserTenantRelation, error) {
+	var relation UserTenantRelation
+	err := s.collection.FindOne(ctx, bson.M{"user_id": userID, "tenant_id": tenantID}).Decode(&relation)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+	return &relation, nil
+}
+
+// ListByUser returns all relations of a user
+func (s *MongoMembershipStore) ListByUser(ctx context.Context, userID string) ([]*UserTenantRelation, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "tenant_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var relations []*UserTenantRelation
+	if err := cursor.All(ctx, &relations); err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
+
+// Save creates or replaces a relation
+func (s *MongoMembershipStore) Save(ctx context.Context, relation *UserTenantRelation) error {
+	now := time.Now()
+	if relation.CreatedAt.IsZero() {
+		relation.CreatedAt = now
+	}
+	relation.UpdatedAt = now
+
+	_, err := s.collection.ReplaceOne(ctx,
+		bson.M{"user_id": relation.UserID, "tenant_id": relation.TenantID},
+		relation,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// Delete removes a relation
+func (s *MongoMembershipStore) Delete(ctx context.Context, userID, tenantID string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"user_id": userID, "tenant_id": tenantID})
+	return err
+}
+
+// MemoryMembershipStore is an in-memory MembershipStore, intended for tests
+type MemoryMembershipStore struct {
+	mu        sync.Mutex
+	relations map[string]*UserTenantRelation
+}
+
+// NewMemoryMembershipStore creates an in-memory membership store
+func NewMemoryMembershipStore() *MemoryMembershipStore {
+	return &MemoryMembershipStore{
+		relations: make(map[string]*UserTenantRelation),
+	}
+}
+
+// Get returns the relation between a user and a tenant
+func (s *MemoryMembershipStore) Get(ctx context.Context, userID, tenantID string) (*UserTenantRelation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	relation, ok := s.relations[userID+"|"+tenantID]
+	if !ok {
+		return nil, ErrNotMember
+	}
+	copied := *relation
+	return &copied, nil
+}
+
+// ListByUser returns all relations of a user
+func (s *MemoryMembershipStore) ListByUser(ctx context.Context, userID string) ([]*UserTenantRelation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var relations []*UserTenantRelation
+	for _, relation := range s.relations {
+		if relation.UserID == userID {
+			copied := *relation
+			relations = append(relations, &copied)
+		}
+	}
+	sort.Slice(relations, func(i, j int) bool {
+		return relations[i].TenantID < relations[j].TenantID
+	})
+	return relations, nil
+}
+
+// Save creates or replaces a relation
+func (s *MemoryMembershipStore) Save(ctx context.Context, relation *UserTenantRelation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *relation
+	s.relations[relation.UserID+"|"+relation.TenantID] = &copied
+	return nil
+}
+
+// Delete removes a relation
+func (s *MemoryMembershipStore) Delete(ctx context.Context, userID, tenantID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.relations, userID+"|"+tenantID)
+	return nil
+}
+
+// RolePermissionResolver maps a relation's roles to permissions for tenant-scoped tokens
+type RolePermissionResolver func(ctx context.Context, relation *UserTenantRelation) ([]string, error)
+
+// MembershipConfig configures the membership service
+type MembershipConfig struct {
+	// ResolvePermissions computes permissions for a relation; nil issues tokens without permissions
+	ResolvePermissions RolePermissionResolver
+}
+
+// TenantSwitchResult is the outcome of switching tenants
+type TenantSwitchResult struct {
+	Token       string              `json:"token"`
+	Relation    *UserTenantRelation `json:"relation"`
+	Permissions []string            `json:"permissions,omitempty"`
+}
+
+// MembershipService resolves tenant memberships and issues tenant-scoped tokens
+type MembershipService struct {
+	store              MembershipStore
+	jwtManager         *jwt.Manager
+	resolvePermissions RolePermissionResolver
+}
+
+// NewMembershipService creates a membership service
+func NewMembershipService(store MembershipStore, jwtManager *jwt.Manager, config MembershipConfig) *MembershipService {
+	return &MembershipService{
+		store:              store,
+		jwtManager:         jwtManager,
+		resolvePermissions: config.ResolvePermissions,
+	}
+}
+
+// ListTenants returns the tenants a user can switch to (active relations only)
+func (s *MembershipService) ListTenants(ctx context.Context, userID string) ([]*UserTenantRelation, error) {
+	relations, err := s.store.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*UserTenantRelation, 0, len(relations))
+	for _, relation := range relations {
+		if relation.IsActive {
+			active = append(active, relation)
+		}
+	}
+	return active, nil
+}
+
+// Verify returns the active relation between a user and a tenant
+func (s *MembershipService) Verify(ctx context.Context, userID, tenantID string) (*UserTenantRelation, error) {
+	relation, err := s.store.Get(ctx, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !relation.IsActive {
+		return nil, ErrMembershipInactive
+	}
+	return relation, nil
+}
+
+// Permissions returns the permissions granted by a relation
+func (s *MembershipService) Permissions(ctx context.Context, relation *UserTenantRelation) ([]string, error) {
+	if s.resolvePermissions == nil {
+		return nil, nil
+	}
+	return s.resolvePermissions(ctx, relation)
+}
+
+// SwitchTenant issues a token scoped to tenantID with the roles of the user's
+// relation. The token is derived from the current claims: the impersonation
+// actor, blocked permissions and MFA status are kept, and it expires no later
+// than the current token.
+func (s *MembershipService) SwitchTenant(ctx context.Context, current *jwt.Claims, tenantID string) (*TenantSwitchResult, error) {
+	relation, err := s.Verify(ctx, current.UserID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.Permissions(ctx, relation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve permissions: %w", err)
+	}
+
+	claims := jwt.Claims{
+		UserID:             current.UserID,
+		TenantID:           tenantID,
+		Email:              current.Email,
+		Roles:              relation.Roles,
+		Permissions:        permissions,
+		MFAVerified:        current.MFAVerified,
+		Actor:              current.Actor,
+		BlockedPermissions: current.BlockedPermissions,
+	}
+	claims.ExpiresAt = current.ExpiresAt
+
+	token, err := s.jwtManager.GenerateTokenWithClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TenantSwitchResult{
+		Token:       token,
+		Relation:    relation,
+		Permissions: permissions,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/vhvplatform/go-shared/jwt"
+)
+
+func TestMembershipService_SwitchTenant(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryMembershipStore()
+	store.Save(ctx, &UserTenantRelation{UserID: "user-1", TenantID: "acme", Roles: []string{"tenant_admin"}, IsActive: true})
+	store.Save(ctx, &UserTenantRelation{UserID: "user-1", TenantID: "globex", Roles: []string{"viewer"}, IsActive: false})
+	store.Save(ctx, &UserTenantRelation{UserID: "user-2", TenantID: "initech", IsActive: true})
+
+	manager := jwt.NewManager("secret", 3600, 86400)
+	service := NewMembershipService(store, manager, MembershipConfig{
+		ResolvePermissions: func(ctx context.Context, relation *UserTenantRelation) ([]string, error) {
+			return []string{"orders.*"}, nil
+		},
+	})
+
+	tenants, err := service.ListTenants(ctx, "user-1")
+	if err != nil || len(tenants) != 1 || tenants[0].TenantID != "acme" {
+		t.Fatalf("expected only the active acme membership, got %+v (%v)", tenants, err)
+	}
+
+	current := &jwt.Claims{UserID: "user-1", Email: "alice@example.com", MFAVerified: true}
+	result, err := service.SwitchTenant(ctx, current, "acme")
+	if err != nil {
+		t.Fatalf("failed to switch tenant: %v", err)
+	}
+	claims, err := manager.ValidateToken(result.Token)
+	if err != nil {
+		t.Fatalf("invalid tenant token: %v", err)
+	}
+	if claims.TenantID != "acme" || len(claims.Roles) != 1 || claims.Roles[0] != "tenant_admin" || claims.Permissions[0] != "orders.*" || !claims.MFAVerified {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	if _, err := service.SwitchTenant(ctx, current, "globex"); !errors.Is(err, ErrMembershipInactive) {
+		t.Errorf("expected ErrMembershipInactive, got %v", err)
+	}
+	if _, err := service.SwitchTenant(ctx, current, "initech"); !errors.Is(err, ErrNotMember) {
+		t.Errorf("expected ErrNotMember, got %v", err)
+	}
+}
+
+func TestMembershipService_SwitchTenantKeepsImpersonation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryMembershipStore()
+	store.Save(ctx, &UserTenantRelation{UserID: "user-1", TenantID: "acme", IsActive: true})
+	store.Save(ctx, &UserTenantRelation{UserID: "user-1", TenantID: "globex", Roles: []string{"tenant_admin"}, IsActive: true})
+
+	manager := jwt.NewManager("secret", 3600, 86400)
+	service := NewMembershipService(store, manager, MembershipConfig{})
+
+	impersonation, err := manager.GenerateTokenWithClaims(jwt.Claims{
+		UserID:             "user-1",
+		TenantID:           "acme",
+		Actor:              &jwt.Actor{UserID: "admin-1"},
+		BlockedPermissions: []string{"billing.*"},
+		RegisteredClaims:   gojwt.RegisteredClaims{ExpiresAt: gojwt.NewNumericDate(time.Now().Add(10 * time.Minute))},
+	})
+	if err != nil {
+		t.Fatalf("failed to issue impersonation token: %v", err)
+	}
+	current, err := manager.ValidateToken(impersonation)
+	if err != nil {
+		t.Fatalf("invalid impersonation token: %v", err)
+	}
+
+	result, err := service.SwitchTenant(ctx, current, "globex")
+	if err != nil {
+		t.Fatalf("failed to switch tenant: %v", err)
+	}
+	claims, err := manager.ValidateToken(result.Token)
+	if err != nil {
+		t.Fatalf("invalid tenant token: %v", err)
+	}
+	if claims.TenantID != "globex" || !claims.IsImpersonation() || claims.Actor.UserID != "admin-1" {
+		t.Errorf("expected the impersonation actor to be kept, got %+v", claims)
+	}
+	if len(claims.BlockedPermissions) != 1 || claims.BlockedPermissions[0] != "billing.*" {
+		t.Errorf("expected blocked permissions to be kept, got %v", claims.BlockedPermissions)
+	}
+	if claims.ExpiresAt.After(current.ExpiresAt.Time) {
+		t.Errorf("expected expiry capped at %v, got %v", current.ExpiresAt, claims.ExpiresAt)
+	}
+}
@@ -1,5 +1,7 @@
 package auth
 
+import "time"
+
 // MultiTenantContext holds authentication context for multi-tenant systems
 type MultiTenantContext struct {
 	UserID      string            `json:"user_id"`
@@ -95,6 +97,7 @@ type TenantLoginConfig struct {
 	PasswordRequireDigit bool              `json:"password_require_digit"`
 	PasswordRequireSpec  bool              `json:"password_require_spec"`
 	SessionTimeout       int               `json:"session_timeout"`
+	SessionAbsTimeout    int               `json:"session_absolute_timeout"`
 	MaxLoginAttempts     int               `json:"max_login_attempts"`
 	LockoutDuration      int               `json:"lockout_duration"`
 }
@@ -117,6 +120,15 @@ func (c *TenantLoginConfig) GetSessionTimeoutDuration() int {
 	return 1440 // Default 24 hours
 }
 
+// GetSessionAbsoluteTimeoutMinutes returns the maximum session lifetime in minutes,
+// regardless of activity
+func (c *TenantLoginConfig) GetSessionAbsoluteTimeoutMinutes() int {
+	if c.SessionAbsTimeout > 0 {
+		return c.SessionAbsTimeout
+	}
+	return 10080 // Default 7 days
+}
+
 // GetLockoutDurationMinutes returns lockout duration in minutes
 func (c *TenantLoginConfig) GetLockoutDurationMinutes() int {
 	if c.LockoutDuration > 0 {
@@ -127,8 +139,10 @@ func (c *TenantLoginConfig) GetLockoutDurationMinutes() int {
 
 // UserTenantRelation represents a user's relationship with a tenant
 type UserTenantRelation struct {
-	UserID   string   `json:"user_id"`
-	TenantID string   `json:"tenant_id"`
-	Roles    []string `json:"roles"`
-	IsActive bool     `json:"is_active"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	TenantID  string    `json:"tenant_id" bson:"tenant_id"`
+	Roles     []string  `json:"roles" bson:"roles"`
+	IsActive  bool      `json:"is_active" bson:"is_active"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
 }
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vhvplatform/go-shared/utils"
+	"github.com/vhvplatform/go-shared/validation"
+)
+
+const (
+	// DefaultPasswordMinLength is used when the tenant config does not set a minimum length
+	DefaultPasswordMinLength = 8
+
+	// minSimilarityTokenLength is the shortest username/email token checked for similarity
+	minSimilarityTokenLength = 3
+)
+
+// PasswordPolicyConfig holds optional settings for a password policy
+type PasswordPolicyConfig struct {
+	// HistorySize limits how many previous hashes are checked (0 = check all provided)
+	HistorySize int
+	// MinStrength is the minimum PasswordStrength score required (0 = disabled)
+	MinStrength int
+	// BreachChecker checks passwords against a breached-password corpus (nil = disabled)
+	BreachChecker BreachChecker
+	// CompareHash compares a password with a stored hash (default: utils.CheckPassword)
+	CompareHash func(password, hash string) bool
+}
+
+// PasswordCandidate holds a new password and the user data it is validated against
+type PasswordCandidate struct {
+	Password       string
+	Username       string
+	Email          string
+	PreviousHashes []string // Most recent first
+}
+
+// PasswordPolicy validates passwords against a tenant's login configuration
+type PasswordPolicy struct {
+	loginConfig *TenantLoginConfig
+	config      PasswordPolicyConfig
+}
+
+// NewPasswordPolicy creates a password policy for the given tenant login config
+func NewPasswordPolicy(loginConfig *TenantLoginConfig, config PasswordPolicyConfig) *PasswordPolicy {
+	if loginConfig == nil {
+		loginConfig = &TenantLoginConfig{}
+	}
+	if config.CompareHash == nil {
+		config.CompareHash = utils.CheckPassword
+	}
+
+	return &PasswordPolicy{
+		loginConfig: loginConfig,
+		config:      config,
+	}
+}
+
+// MinLength returns the effective minimum password length
+func (p *PasswordPolicy) MinLength() int {
+	if p.loginConfig.PasswordMinLength > 0 {
+		return p.loginConfig.PasswordMinLength
+	}
+	return DefaultPasswordMinLength
+}
+
+// Validate checks a password candidate against the policy.
+// It returns validation.ValidationErrors when the password violates the policy,
+// or a regular error when a dependency (e.g. the breach checker) fails.
+func (p *PasswordPolicy) Validate(ctx context.Context, candidate PasswordCandidate) error {
+	var errs []validation.ValidationError
+	password := candidate.Password
+
+	errs = append(errs, p.checkComposition(password)...)
+
+	if p.IsSimilarToIdentity(password, candidate.Username, candidate.Email) {
+		errs = append(errs, passwordError("password_similar", "password must not be similar to your username or email"))
+	}
+
+	if p.IsReused(password, candidate.PreviousHashes) {
+		errs = append(errs, passwordError("password_history", "password must not match a recently used password"))
+	}
+
+	if p.config.BreachChecker != nil {
+		breached, err := p.config.BreachChecker.IsBreached(ctx, password)
+		if err != nil {
+			return fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+		if breached {
+			errs = append(errs, passwordError("password_breached", "password has appeared in a data breach and cannot be used"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return validation.ValidationErrors{Errors: errs}
+}
+
+// checkComposition checks length, character classes and strength
+func (p *PasswordPolicy) checkComposition(password string) []validation.ValidationError {
+	var errs []validation.ValidationError
+
+	if minLength := p.MinLength(); len([]rune(password)) < minLength {
+		errs = append(errs, passwordError("min", fmt.Sprintf("password must be at least %d characters long", minLength)))
+	}
+	if p.loginConfig.PasswordRequireUpper && !ContainsUppercase(password) {
+		errs = append(errs, passwordError("password_upper", "password must contain at least one uppercase letter"))
+	}
+	if p.loginConfig.PasswordRequireLower && !ContainsLowercase(password) {
+		errs = append(errs, passwordError("password_lower", "password must contain at least one lowercase letter"))
+	}
+	if p.loginConfig.PasswordRequireDigit && !ContainsDigit(password) {
+		errs = append(errs, passwordError("password_digit", "password must contain at least one digit"))
+	}
+	if p.loginConfig.PasswordRequireSpec && !ContainsSpecialChar(password) {
+		errs = append(errs, passwordError("password_special", "password must contain at least one special character"))
+	}
+	if p.config.MinStrength > 0 && PasswordStrength(password) < p.config.MinStrength {
+		errs = append(errs, passwordError("password_strength", "password is too weak"))
+	}
+
+	return errs
+}
+
+// IsSimilarToIdentity checks if the password contains, or is contained in,
+// the username, the email address or the email local part (case-insensitive)
+func (p *PasswordPolicy) IsSimilarToIdentity(password, username, email string) bool {
+	normalized := normalizeForSimilarity(password)
+	if normalized == "" {
+		return false
+	}
+
+	tokens := []string{username, email}
+	if local, _, found := strings.Cut(email, "@"); found {
+		tokens = append(tokens, local)
+	}
+
+	for _, token := range tokens {
+		token = normalizeForSimilarity(token)
+		if len(token) < minSimilarityTokenLength {
+			continue
+		}
+		if strings.Contains(normalized, token) || strings.Contains(token, normalized) {
+			return true
+		}
+		if strings.Contains(normalized, reverseString(token)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsReused checks if the password matches any of the previous hashes
+func (p *PasswordPolicy) IsReused(password string, previousHashes []string) bool {
+	if p.config.HistorySize > 0 && len(previousHashes) > p.config.HistorySize {
+		previousHashes = previousHashes[:p.config.HistorySize]
+	}
+
+	for _, hash := range previousHashes {
+		if hash != "" && p.config.CompareHash(password, hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddToHistory prepends a new hash to the history and trims it to the configured size
+func (p *PasswordPolicy) AddToHistory(history []string, newHash string) []string {
+	result := make([]string, 0, len(history)+1)
+	result = append(result, newHash)
+	result = append(result, history...)
+	if p.config.HistorySize > 0 && len(result) > p.config.HistorySize {
+		result = result[:p.config.HistorySize]
+	}
+	return result
+}
+
+// passwordError builds a validation error for the password field
+func passwordError(tag, message string) validation.ValidationError {
+	return validation.ValidationError{
+		Field:   "password",
+		Tag:     tag,
+		Message: message,
+	}
+}
+
+// normalizeForSimilarity lowercases a value and strips separators
+func normalizeForSimilarity(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '_', '-', ' ':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func reverseString(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vhvplatform/go-shared/utils"
+	"github.com/vhvplatform/go-shared/validation"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	corpus := NewLocalBreachCorpus()
+	corpus.AddPassword("Password123!")
+
+	oldHash, err := utils.HashPassword("Old-Secret#2020")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	policy := NewPasswordPolicy(&TenantLoginConfig{
+		PasswordMinLength:    10,
+		PasswordRequireUpper: true,
+		PasswordRequireLower: true,
+		PasswordRequireDigit: true,
+		PasswordRequireSpec:  true,
+	}, PasswordPolicyConfig{
+		HistorySize:   5,
+		BreachChecker: corpus,
+	})
+
+	tests := []struct {
+		name         string
+		candidate    PasswordCandidate
+		expectedTags []string
+	}{
+		{
+			name:      "valid password",
+			candidate: PasswordCandidate{Password: "Tr0ub4dor&3x", Username: "alice", Email: "alice@example.com"},
+		},
+		{
+			name:         "too short and missing classes",
+			candidate:    PasswordCandidate{Password: "short"},
+			expectedTags: []string{"min", "password_upper", "password_digit", "password_special"},
+		},
+		{
+			name:         "contains username",
+			candidate:    PasswordCandidate{Password: "Alice-Wonder#42", Username: "alice"},
+			expectedTags: []string{"password_similar"},
+		},
+		{
+			name:         "contains email local part",
+			candidate:    PasswordCandidate{Password: "J.Smith2024!!", Email: "j.smith@example.com"},
+			expectedTags: []string{"password_similar"},
+		},
+		{
+			name:         "reused password",
+			candidate:    PasswordCandidate{Password: "Old-Secret#2020", PreviousHashes: []string{oldHash}},
+			expectedTags: []string{"password_history"},
+		},
+		{
+			name:         "breached password",
+			candidate:    PasswordCandidate{Password: "Password123!"},
+			expectedTags: []string{"password_breached"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(context.Background(), tt.candidate)
+			if len(tt.expectedTags) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var verrs validation.ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("expected ValidationErrors, got %v", err)
+			}
+
+			tags := make([]string, 0, len(verrs.Errors))
+			for _, e := range verrs.Errors {
+				tags = append(tags, e.Tag)
+			}
+			if strings.Join(tags, ",") != strings.Join(tt.expectedTags, ",") {
+				t.Errorf("expected tags %v, got %v", tt.expectedTags, tags)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicy_DefaultMinLength(t *testing.T) {
+	policy := NewPasswordPolicy(nil, PasswordPolicyConfig{})
+	if policy.MinLength() != DefaultPasswordMinLength {
+		t.Errorf("expected default min length %d, got %d", DefaultPasswordMinLength, policy.MinLength())
+	}
+}
+
+func TestPasswordPolicy_AddToHistory(t *testing.T) {
+	policy := NewPasswordPolicy(nil, PasswordPolicyConfig{HistorySize: 2})
+	history := policy.AddToHistory([]string{"b", "c"}, "a")
+	if strings.Join(history, ",") != "a,b" {
+		t.Errorf("expected [a b], got %v", history)
+	}
+}
+
+func TestLocalBreachCorpus_LoadRange(t *testing.T) {
+	// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	corpus := NewLocalBreachCorpus()
+	data := "1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n0018A45C4D1DEF81644B54AB7F969B88D65:0\n"
+	if err := corpus.LoadRange("5baa6", strings.NewReader(data)); err != nil {
+		t.Fatalf("failed to load range: %v", err)
+	}
+
+	if corpus.Size() != 1 {
+		t.Errorf("expected padding entry to be ignored, got size %d", corpus.Size())
+	}
+
+	checker := NewKAnonymityBreachChecker(corpus, 100)
+	breached, err := checker.IsBreached(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !breached {
+		t.Error("expected password to be breached")
+	}
+
+	breached, _ = checker.IsBreached(context.Background(), "correct horse battery staple")
+	if breached {
+		t.Error("expected password not to be breached")
+	}
+}
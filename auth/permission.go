@@ -29,27 +29,30 @@ func (pc *PermissionChecker) HasPermission(ctx context.Context, permission strin
 		return false
 	}
 
-	// Fast path for wildcard admin permission
-	for _, p := range permissions {
-		if p == "*" {
-			return true
-		}
-		if p == permission {
-			return true
+	// Blocked permissions (e.g. during impersonation) override any grant
+	for _, blocked := range pkgctx.GetBlockedPermissions(ctx) {
+		if permissionMatches(blocked, permission) {
+			return false
 		}
 	}
 
-	// Check wildcard permissions (e.g., "users.*" matches "users.read")
-	// Performance: Only process wildcards if exact match not found
+	// Grants and blocks share one matcher, so "*", exact and "prefix.*" forms behave alike
 	for _, p := range permissions {
-		if strings.HasSuffix(p, ".*") {
-			prefix := p[:len(p)-2] // Remove ".*"
-			if strings.HasPrefix(permission, prefix+".") {
-				return true
-			}
+		if permissionMatches(p, permission) {
+			return true
 		}
 	}
+	return false
+}
 
+// permissionMatches reports whether a granted (possibly wildcard) permission covers the permission
+func permissionMatches(granted, permission string) bool {
+	if granted == "*" || granted == permission {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(granted, ".*"); ok {
+		return strings.HasPrefix(permission, prefix+".")
+	}
 	return false
 }
 
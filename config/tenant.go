@@ -0,0 +1,289 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/vhvplatform/go-shared/redis"
+)
+
+var (
+	// ErrTenantConfigNotFound is returned by a TenantStore when a tenant has no overrides
+	ErrTenantConfigNotFound = errors.New("tenant configuration not found")
+	// ErrInvalidTenantConfig is returned when overrides do not decode into the configuration type
+	ErrInvalidTenantConfig = errors.New("invalid tenant configuration")
+)
+
+// invalidateAll is the invalidation message that evicts every tenant
+const invalidateAll = "*"
+
+// TenantServiceConfig configures a TenantService
+type TenantServiceConfig struct {
+	CacheTTL time.Duration // default: 5 minutes
+	// Redis, when set, broadcasts cache invalidations to every instance (see Listen)
+	Redis   *redis.Client
+	Channel string // default: "config:tenant:invalidate"
+}
+
+// TenantService resolves typed per-tenant configuration by layering, from
+// lowest to highest precedence, the defaults, the global configuration and the
+// tenant's override document. Global fields left at their zero value fall back
+// to the defaults; tenant overrides apply whenever a key is present, so a
+// tenant can switch a toggle off or set a limit to zero.
+type TenantService[T any] struct {
+	store   TenantStore
+	base    map[string]interface{}
+	ttl     time.Duration
+	redis   *redis.Client
+	channel string
+	now     func() time.Time
+
+	mu    sync.RWMutex
+	cache map[string]tenantCacheEntry[T]
+}
+
+type tenantCacheEntry[T any] struct {
+	value     *T
+	expiresAt time.Time
+}
+
+// NewTenantService creates a tenant configuration service for the layered defaults and global configuration
+func NewTenantService[T any](store TenantStore, defaults, global T, config TenantServiceConfig) (*TenantService[T], error) {
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 5 * time.Minute
+	}
+	if config.Channel == "" {
+		config.Channel = "config:tenant:invalidate"
+	}
+
+	base, err := toLayer(defaults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode default configuration: %w", err)
+	}
+	globalLayer, err := toLayer(global)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode global configuration: %w", err)
+	}
+	mergeLayer(base, globalLayer, true)
+
+	return &TenantService[T]{
+		store:   store,
+		base:    base,
+		ttl:     config.CacheTTL,
+		redis:   config.Redis,
+		channel: config.Channel,
+		now:     time.Now,
+		cache:   make(map[string]tenantCacheEntry[T]),
+	}, nil
+}
+
+// Get returns the effective configuration of a tenant. The returned value is
+// shared with the cache and must not be modified.
+func (s *TenantService[T]) Get(ctx context.Context, tenantID string) (*T, error) {
+	now := s.now()
+	s.mu.RLock()
+	entry, ok := s.cache[tenantID]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	overrides, err := s.store.Get(ctx, tenantID)
+	if err != nil && !errors.Is(err, ErrTenantConfigNotFound) {
+		return nil, fmt.Errorf("failed to load tenant configuration: %w", err)
+	}
+
+	value, err := s.build(overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = tenantCacheEntry[T]{value: value, expiresAt: now.Add(s.ttl)}
+	s.mu.Unlock()
+	return value, nil
+}
+
+// Save validates and stores the overrides of a tenant, then invalidates its cached configuration
+func (s *TenantService[T]) Save(ctx context.Context, tenantID string, overrides map[string]interface{}) error {
+	if _, err := s.build(overrides); err != nil {
+		return err
+	}
+	if err := s.store.Save(ctx, tenantID, overrides); err != nil {
+		return fmt.Errorf("failed to save tenant configuration: %w", err)
+	}
+	return s.Invalidate(ctx, tenantID)
+}
+
+// Delete removes the overrides of a tenant so it falls back to the global configuration
+func (s *TenantService[T]) Delete(ctx context.Context, tenantID string) error {
+	if err := s.store.Delete(ctx, tenantID); err != nil {
+		return fmt.Errorf("failed to delete tenant configuration: %w", err)
+	}
+	return s.Invalidate(ctx, tenantID)
+}
+
+// Invalidate evicts cached configuration on this instance and, when Redis is
+// configured, publishes the invalidation to the other instances. With no
+// tenant IDs every tenant is evicted.
+func (s *TenantService[T]) Invalidate(ctx context.Context, tenantIDs ...string) error {
+	if len(tenantIDs) == 0 {
+		tenantIDs = []string{invalidateAll}
+	}
+	for _, tenantID := range tenantIDs {
+		s.evict(tenantID)
+	}
+
+	if s.redis == nil {
+		return nil
+	}
+	for _, tenantID := range tenantIDs {
+		if err := s.redis.Publish(ctx, s.channel, tenantID).Err(); err != nil {
+			return fmt.Errorf("failed to publish tenant configuration invalidation: %w", err)
+		}
+	}
+	return nil
+}
+
+// Listen subscribes to invalidations published by other instances and evicts
+// the affected tenants until ctx is cancelled. It is typically run in its own goroutine.
+func (s *TenantService[T]) Listen(ctx context.Context) error {
+	if s.redis == nil {
+		return errors.New("tenant configuration invalidation requires a Redis client")
+	}
+
+	pubsub := s.redis.Subscribe(ctx, s.channel)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to tenant configuration invalidations: %w", err)
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			s.evict(msg.Payload)
+		}
+	}
+}
+
+func (s *TenantService[T]) evict(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tenantID == invalidateAll {
+		s.cache = make(map[string]tenantCacheEntry[T])
+		return
+	}
+	delete(s.cache, tenantID)
+}
+
+// build merges the overrides over the base layer and decodes the result
+func (s *TenantService[T]) build(overrides map[string]interface{}) (*T, error) {
+	merged := cloneLayer(s.base)
+	if len(overrides) > 0 {
+		// Round-trip through JSON so store-specific map types (e.g. bson.M) merge like plain maps
+		layer, err := toLayer(overrides)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTenantConfig, err)
+		}
+		mergeLayer(merged, layer, false)
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTenantConfig, err)
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTenantConfig, err)
+	}
+	return &value, nil
+}
+
+// toLayer encodes a value as a JSON object, keeping numbers exact
+func toLayer(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var layer map[string]interface{}
+	if err := decoder.Decode(&layer); err != nil {
+		return nil, err
+	}
+	if layer == nil {
+		layer = make(map[string]interface{})
+	}
+	return layer, nil
+}
+
+// mergeLayer merges src into dst, recursing into nested objects. With
+// skipZero, zero values in src do not replace values in dst.
+func mergeLayer(dst, src map[string]interface{}, skipZero bool) {
+	for key, value := range src {
+		if nested, ok := value.(map[string]interface{}); ok {
+			if existing, ok := dst[key].(map[string]interface{}); ok {
+				mergeLayer(existing, nested, skipZero)
+				continue
+			}
+		}
+		if skipZero && isZeroValue(value) {
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+func cloneLayer(layer map[string]interface{}) map[string]interface{} {
+	cloned := make(map[string]interface{}, len(layer))
+	for key, value := range layer {
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = cloneLayer(nested)
+		}
+		cloned[key] = value
+	}
+	return cloned
+}
+
+func isZeroValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	default:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice {
+			return rv.Len() == 0
+		}
+		return rv.IsZero()
+	}
+}
+
+type tenantConfigKey[T any] struct{}
+
+// WithTenantConfig stores a tenant configuration in the context
+func WithTenantConfig[T any](ctx context.Context, config *T) context.Context {
+	return context.WithValue(ctx, tenantConfigKey[T]{}, config)
+}
+
+// TenantConfigFromContext returns the tenant configuration of type T stored in the context
+func TenantConfigFromContext[T any](ctx context.Context) (*T, bool) {
+	config, ok := ctx.Value(tenantConfigKey[T]{}).(*T)
+	return config, ok && config != nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TenantStore persists tenant-specific configuration overrides. Override
+// documents use the same field names as the JSON encoding of the typed
+// configuration, e.g. {"RateLimit": {"Burst": 50}} for Config.
+type TenantStore interface {
+	// Get returns the overrides of a tenant or ErrTenantConfigNotFound
+	Get(ctx context.Context, tenantID string) (map[string]interface{}, error)
+	// Save creates or replaces the overrides of a tenant
+	Save(ctx context.Context, tenantID string, overrides map[string]interface{}) error
+	// Delete removes the overrides of a tenant
+	Delete(ctx context.Context, tenantID string) error
+}
+
+type tenantConfigDocument struct {
+	TenantID  string                 `bson:"_id"`
+	Overrides map[string]interface{} `bson:"overrides"`
+	UpdatedAt time.Time              `bson:"updated_at"`
+}
+
+// MongoTenantStore stores tenant overrides in MongoDB, one document per tenant
+type MongoTenantStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoTenantStore creates a MongoDB-backed tenant override store
+func NewMongoTenantStore(collection *mongo.Collection) *MongoTenantStore {
+	return &MongoTenantStore{collection: collection}
+}
+
+// Get returns the overrides of a tenant
+func (s *MongoTenantStore) Get(ctx context.Context, tenantID string) (map[string]interface{}, error) {
+	var doc tenantConfigDocument
+	if err := s.collection.FindOne(ctx, bson.M{"_id": tenantID}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrTenantConfigNotFound
+		}
+		return nil, err
+	}
+	return doc.Overrides, nil
+}
+
+// Save creates or replaces the overrides of a tenant
+func (s *MongoTenantStore) Save(ctx context.Context, tenantID string, overrides map[string]interface{}) error {
+	_, err := s.collection.ReplaceOne(ctx,
+		bson.M{"_id": tenantID},
+		tenantConfigDocument{TenantID: tenantID, Overrides: overrides, UpdatedAt: time.Now()},
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// Delete removes the overrides of a tenant
+func (s *MongoTenantStore) Delete(ctx context.Context, tenantID string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": tenantID})
+	return err
+}
+
+// MemoryTenantStore is an in-memory TenantStore, intended for tests
+type MemoryTenantStore struct {
+	mu        sync.RWMutex
+	overrides map[string]map[string]interface{}
+}
+
+// NewMemoryTenantStore creates an in-memory tenant override store
+func NewMemoryTenantStore() *MemoryTenantStore {
+	return &MemoryTenantStore{
+		overrides: make(map[string]map[string]interface{}),
+	}
+}
+
+// Get returns the overrides of a tenant
+func (s *MemoryTenantStore) Get(ctx context.Context, tenantID string) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	overrides, ok := s.overrides[tenantID]
+	if !ok {
+		return nil, ErrTenantConfigNotFound
+	}
+	return overrides, nil
+}
+
+// Save creates or replaces the overrides of a tenant
+func (s *MemoryTenantStore) Save(ctx context.Context, tenantID string, overrides map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.overrides[tenantID] = overrides
+	return nil
+}
+
+// Delete removes the overrides of a tenant
+func (s *MemoryTenantStore) Delete(ctx context.Context, tenantID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.overrides, tenantID)
+	return nil
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type tenantSettings struct {
+	RateLimit RateLimitConfig
+	SMTP      SMTPConfig
+	Features  map[string]bool
+}
+
+func TestTenantService_Layering(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTenantStore()
+	store.Save(ctx, "acme", map[string]interface{}{
+		"RateLimit": bson.M{"Burst": 0},
+		"SMTP":      bson.M{"FromEmail": "noreply@acme.io"},
+		"Features":  bson.M{"beta": false},
+	})
+
+	defaults := tenantSettings{
+		RateLimit: RateLimitConfig{RequestsPerSecond: 10, Burst: 20},
+		SMTP:      SMTPConfig{Port: 587, FromName: "Platform"},
+		Features:  map[string]bool{"beta": true, "sso": true},
+	}
+	global := tenantSettings{
+		RateLimit: RateLimitConfig{RequestsPerSecond: 100},
+		SMTP:      SMTPConfig{FromEmail: "noreply@example.com"},
+	}
+
+	service, err := NewTenantService(store, defaults, global, TenantServiceConfig{})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	tests := []struct {
+		tenantID string
+		expected tenantSettings
+	}{
+		{
+			tenantID: "acme",
+			expected: tenantSettings{
+				RateLimit: RateLimitConfig{RequestsPerSecond: 100, Burst: 0},
+				SMTP:      SMTPConfig{Port: 587, FromName: "Platform", FromEmail: "noreply@acme.io"},
+				Features:  map[string]bool{"beta": false, "sso": true},
+			},
+		},
+		{
+			tenantID: "globex",
+			expected: tenantSettings{
+				RateLimit: RateLimitConfig{RequestsPerSecond: 100, Burst: 20},
+				SMTP:      SMTPConfig{Port: 587, FromName: "Platform", FromEmail: "noreply@example.com"},
+				Features:  map[string]bool{"beta": true, "sso": true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tenantID, func(t *testing.T) {
+			settings, err := service.Get(ctx, tt.tenantID)
+			if err != nil {
+				t.Fatalf("failed to get configuration: %v", err)
+			}
+			if settings.RateLimit != tt.expected.RateLimit || settings.SMTP != tt.expected.SMTP ||
+				settings.Features["beta"] != tt.expected.Features["beta"] || settings.Features["sso"] != tt.expected.Features["sso"] {
+				t.Errorf("expected %+v, got %+v", tt.expected, *settings)
+			}
+		})
+	}
+}
+
+func TestTenantService_CacheAndInvalidation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTenantStore()
+	service, err := NewTenantService(store, RateLimitConfig{Burst: 20}, RateLimitConfig{}, TenantServiceConfig{CacheTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	if limits, _ := service.Get(ctx, "acme"); limits.Burst != 20 {
+		t.Fatalf("expected default burst, got %d", limits.Burst)
+	}
+
+	// Direct store writes are hidden by the cache until invalidated
+	store.Save(ctx, "acme", map[string]interface{}{"Burst": 5})
+	if limits, _ := service.Get(ctx, "acme"); limits.Burst != 20 {
+		t.Errorf("expected cached burst, got %d", limits.Burst)
+	}
+	service.Invalidate(ctx)
+	if limits, _ := service.Get(ctx, "acme"); limits.Burst != 5 {
+		t.Errorf("expected overridden burst, got %d", limits.Burst)
+	}
+
+	if err := service.Save(ctx, "acme", map[string]interface{}{"Burst": "many"}); !errors.Is(err, ErrInvalidTenantConfig) {
+		t.Errorf("expected ErrInvalidTenantConfig, got %v", err)
+	}
+	if err := service.Save(ctx, "acme", map[string]interface{}{"Burst": 50}); err != nil {
+		t.Fatalf("failed to save overrides: %v", err)
+	}
+	limits, _ := service.Get(ctx, "acme")
+	if limits.Burst != 50 {
+		t.Errorf("expected saved burst, got %d", limits.Burst)
+	}
+
+	ctx = WithTenantConfig(ctx, limits)
+	if fromCtx, ok := TenantConfigFromContext[RateLimitConfig](ctx); !ok || fromCtx.Burst != 50 {
+		t.Errorf("expected configuration in context, got %+v", fromCtx)
+	}
+	if _, ok := TenantConfigFromContext[SMTPConfig](ctx); ok {
+		t.Error("expected no configuration of another type in context")
+	}
+}
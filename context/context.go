@@ -16,6 +16,8 @@ const (
 	EmailKey         contextKey = "email"
 	CorrelationIDKey contextKey = "correlation_id"
 	TenantDomainKey  contextKey = "tenant_domain"
+	ActorKey         contextKey = "actor"
+	BlockedPermsKey  contextKey = "blocked_permissions"
 	// RequestCtxKey caches the full request context to avoid repeated field lookups during retrieval
 	RequestCtxKey contextKey = "request_context"
 )
@@ -36,6 +38,22 @@ type RequestContext struct {
 	Permissions   []string
 	CorrelationID string
 	TenantDomain  string
+	// Actor is the real user when the request is made while impersonating UserID
+	Actor *Actor
+	// BlockedPermissions are denied regardless of Permissions (e.g. during impersonation)
+	BlockedPermissions []string
+}
+
+// Actor identifies the user acting on behalf of another user
+type Actor struct {
+	UserID   string `json:"sub"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+// IsImpersonated reports whether the request is made by an actor on behalf of UserID
+func (rc *RequestContext) IsImpersonated() bool {
+	return rc.Actor != nil
 }
 
 // WithUserID adds user ID to context
@@ -156,6 +174,28 @@ func GetTenantDomain(ctx context.Context) string {
 	return domain
 }
 
+// WithActor adds the impersonating actor to context
+func WithActor(ctx context.Context, actor *Actor) context.Context {
+	return context.WithValue(ctx, ActorKey, actor)
+}
+
+// GetActor retrieves the impersonating actor, or nil when not impersonating
+func GetActor(ctx context.Context) *Actor {
+	actor, _ := ctx.Value(ActorKey).(*Actor)
+	return actor
+}
+
+// WithBlockedPermissions adds permissions that must be denied to context
+func WithBlockedPermissions(ctx context.Context, permissions []string) context.Context {
+	return context.WithValue(ctx, BlockedPermsKey, permissions)
+}
+
+// GetBlockedPermissions retrieves permissions that must be denied
+func GetBlockedPermissions(ctx context.Context) []string {
+	permissions, _ := ctx.Value(BlockedPermsKey).([]string)
+	return permissions
+}
+
 // WithRequestContext adds full request context
 // Performance: Caches the full RequestContext to avoid repeated field lookups
 func WithRequestContext(ctx context.Context, rc *RequestContext) context.Context {
@@ -167,6 +207,8 @@ func WithRequestContext(ctx context.Context, rc *RequestContext) context.Context
 	ctx = WithPermissions(ctx, rc.Permissions)
 	ctx = WithCorrelationID(ctx, rc.CorrelationID)
 	ctx = WithTenantDomain(ctx, rc.TenantDomain)
+	ctx = WithActor(ctx, rc.Actor)
+	ctx = WithBlockedPermissions(ctx, rc.BlockedPermissions)
 	// Store complete context for faster retrieval
 	ctx = context.WithValue(ctx, RequestCtxKey, rc)
 	return ctx
@@ -186,13 +228,15 @@ func GetRequestContext(ctx context.Context) *RequestContext {
 	permissions, _ := GetPermissions(ctx)
 
 	return &RequestContext{
-		UserID:        userID,
-		TenantID:      tenantID,
-		AppID:         GetAppID(ctx),
-		Email:         GetEmail(ctx),
-		Roles:         GetRoles(ctx),
-		Permissions:   permissions,
-		CorrelationID: GetCorrelationID(ctx),
-		TenantDomain:  GetTenantDomain(ctx),
+		UserID:             userID,
+		TenantID:           tenantID,
+		AppID:              GetAppID(ctx),
+		Email:              GetEmail(ctx),
+		Roles:              GetRoles(ctx),
+		Permissions:        permissions,
+		CorrelationID:      GetCorrelationID(ctx),
+		TenantDomain:       GetTenantDomain(ctx),
+		Actor:              GetActor(ctx),
+		BlockedPermissions: GetBlockedPermissions(ctx),
 	}
 }
@@ -20,6 +20,8 @@ func ToGinContext(c *gin.Context, rc *RequestContext) {
 	c.Set("permissions", rc.Permissions)
 	c.Set("correlation_id", rc.CorrelationID)
 	c.Set("tenant_domain", rc.TenantDomain)
+	c.Set("actor", rc.Actor)
+	c.Set("blocked_permissions", rc.BlockedPermissions)
 	// Cache the full RequestContext to avoid rebuilding it
 	c.Set(GinContextKey, rc)
 }
@@ -36,14 +38,16 @@ func FromGinContext(c *gin.Context) *RequestContext {
 
 	// Fallback to building from individual values
 	return &RequestContext{
-		UserID:        c.GetString("user_id"),
-		TenantID:      c.GetString("tenant_id"),
-		AppID:         c.GetString("app_id"),
-		Email:         c.GetString("email"),
-		Roles:         getStringSlice(c, "roles"),
-		Permissions:   getStringSlice(c, "permissions"),
-		CorrelationID: c.GetString("correlation_id"),
-		TenantDomain:  c.GetString("tenant_domain"),
+		UserID:             c.GetString("user_id"),
+		TenantID:           c.GetString("tenant_id"),
+		AppID:              c.GetString("app_id"),
+		Email:              c.GetString("email"),
+		Roles:              getStringSlice(c, "roles"),
+		Permissions:        getStringSlice(c, "permissions"),
+		CorrelationID:      c.GetString("correlation_id"),
+		TenantDomain:       c.GetString("tenant_domain"),
+		Actor:              getActor(c),
+		BlockedPermissions: getStringSlice(c, "blocked_permissions"),
 	}
 }
 
@@ -65,6 +69,20 @@ func getStringSlice(c *gin.Context, key string) []string {
 	return slice
 }
 
+func getActor(c *gin.Context) *Actor {
+	value, exists := c.Get("actor")
+	if !exists {
+		return nil
+	}
+	actor, _ := value.(*Actor)
+	return actor
+}
+
+// GetActorFromGin retrieves the impersonating actor from gin context, or nil when not impersonating
+func GetActorFromGin(c *gin.Context) *Actor {
+	return getActor(c)
+}
+
 // GetUserIDFromGin retrieves user ID from gin context
 func GetUserIDFromGin(c *gin.Context) string {
 	return c.GetString("user_id")
@@ -0,0 +1,258 @@
+// Package encryption provides application-level field encryption: AES-GCM
+// envelope encryption of struct fields tagged `secure:"encrypt"` with data
+// keys wrapped by a pluggable KeyProvider, key rotation, and HMAC blind
+// indexes (`secure:"blind_index=Field"`) for equality queries on encrypted
+// fields.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownKey is returned when a key-encryption key does not exist
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrDecryptionFailed is returned for malformed or tampered ciphertexts
+	ErrDecryptionFailed = errors.New("decryption failed")
+	// ErrUnsupportedField is returned for tagged fields that are not strings
+	ErrUnsupportedField = errors.New("unsupported field for encryption")
+)
+
+// prefix marks encrypted values, so plaintext values written before
+// encryption was enabled are read as is
+const prefix = "enc:v1:"
+
+// Config configures an Encryptor
+type Config struct {
+	// IndexKey is the HMAC key for blind indexes (required, at least 32 bytes).
+	// Changing it requires recomputing every blind index.
+	IndexKey []byte
+	// DataKeyTTL is how long a data key encrypts new values before a new one
+	// is generated (default: 24 hours)
+	DataKeyTTL time.Duration
+}
+
+// Encryptor encrypts and decrypts values and tagged struct fields
+type Encryptor struct {
+	provider   KeyProvider
+	indexKey   []byte
+	dataKeyTTL time.Duration
+	now        func() time.Time
+
+	mu       sync.Mutex
+	active   *dataKey
+	dataKeys map[string]cipher.AEAD // unwrapped data keys by key ID and wrapped key
+}
+
+type dataKey struct {
+	keyID     string
+	wrapped   []byte
+	aead      cipher.AEAD
+	expiresAt time.Time
+}
+
+// NewEncryptor creates an encryptor
+func NewEncryptor(provider KeyProvider, config Config) (*Encryptor, error) {
+	if provider == nil {
+		return nil, errors.New("encryption key provider is required")
+	}
+	if len(config.IndexKey) < 32 {
+		return nil, errors.New("blind index key must be at least 32 bytes")
+	}
+	if config.DataKeyTTL <= 0 {
+		config.DataKeyTTL = 24 * time.Hour
+	}
+
+	return &Encryptor{
+		provider:   provider,
+		indexKey:   config.IndexKey,
+		dataKeyTTL: config.DataKeyTTL,
+		now:        time.Now,
+		dataKeys:   make(map[string]cipher.AEAD),
+	}, nil
+}
+
+// IsEncrypted reports whether a value was produced by EncryptString
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// EncryptString encrypts a value. The field name is bound to the ciphertext,
+// so it only decrypts for the same field.
+func (e *Encryptor) EncryptString(ctx context.Context, field, plaintext string) (string, error) {
+	key, err := e.activeKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// keyID length | keyID | wrapped key length | wrapped key | nonce | ciphertext
+	payload := make([]byte, 0, 3+len(key.keyID)+len(key.wrapped)+len(nonce)+len(plaintext)+key.aead.Overhead())
+	payload = append(payload, byte(len(key.keyID)))
+	payload = append(payload, key.keyID...)
+	payload = binary.BigEndian.AppendUint16(payload, uint16(len(key.wrapped)))
+	payload = append(payload, key.wrapped...)
+	payload = append(payload, nonce...)
+	payload = key.aead.Seal(payload, nonce, []byte(plaintext), []byte(field))
+
+	return prefix + base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// DecryptString decrypts a value encrypted for the field. Values that are
+// not encrypted are returned unchanged.
+func (e *Encryptor) DecryptString(ctx context.Context, field, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	envelope, err := parseEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := e.unwrap(ctx, envelope.keyID, envelope.wrapped)
+	if err != nil {
+		return "", err
+	}
+	nonceSize := aead.NonceSize()
+	if len(envelope.sealed) < nonceSize {
+		return "", ErrDecryptionFailed
+	}
+	plaintext, err := aead.Open(nil, envelope.sealed[:nonceSize], envelope.sealed[nonceSize:], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("%w for field %s", ErrDecryptionFailed, field)
+	}
+	return string(plaintext), nil
+}
+
+// KeyID returns the ID of the key-encryption key of an encrypted value
+func KeyID(value string) (string, error) {
+	envelope, err := parseEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+	return envelope.keyID, nil
+}
+
+// BlindIndex returns a keyed hash of the value for equality queries on an
+// encrypted field; field is the name of the field holding the index
+func (e *Encryptor) BlindIndex(field, value string) string {
+	mac := hmac.New(sha256.New, e.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type envelope struct {
+	keyID   string
+	wrapped []byte
+	sealed  []byte // nonce and ciphertext
+}
+
+func parseEnvelope(value string) (*envelope, error) {
+	if !IsEncrypted(value) {
+		return nil, ErrDecryptionFailed
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value[len(prefix):])
+	if err != nil || len(payload) < 1 {
+		return nil, ErrDecryptionFailed
+	}
+
+	idLen := int(payload[0])
+	if len(payload) < 1+idLen+2 {
+		return nil, ErrDecryptionFailed
+	}
+	keyID := string(payload[1 : 1+idLen])
+	rest := payload[1+idLen:]
+	wrappedLen := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+	if len(rest) < wrappedLen {
+		return nil, ErrDecryptionFailed
+	}
+	return &envelope{keyID: keyID, wrapped: rest[:wrappedLen], sealed: rest[wrappedLen:]}, nil
+}
+
+// activeKey returns the data key for new values, generating a new one when
+// the current KEK changed or the data key expired
+func (e *Encryptor) activeKey(ctx context.Context) (*dataKey, error) {
+	keyID, err := e.provider.CurrentKeyID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	if e.active != nil && e.active.keyID == keyID && now.Before(e.active.expiresAt) {
+		return e.active, nil
+	}
+	if len(keyID) > 255 {
+		return nil, fmt.Errorf("encryption key ID %q is too long", keyID)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := e.provider.WrapKey(ctx, keyID, raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	e.active = &dataKey{keyID: keyID, wrapped: wrapped, aead: aead, expiresAt: now.Add(e.dataKeyTTL)}
+	e.dataKeys[keyID+"\x00"+string(wrapped)] = aead
+	return e.active, nil
+}
+
+// unwrap returns the data key of a value, unwrapping it with the KEK on first use
+func (e *Encryptor) unwrap(ctx context.Context, keyID string, wrapped []byte) (cipher.AEAD, error) {
+	cacheKey := keyID + "\x00" + string(wrapped)
+	e.mu.Lock()
+	aead, ok := e.dataKeys[cacheKey]
+	e.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	raw, err := e.provider.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if aead, err = newAEAD(raw); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.dataKeys[cacheKey] = aead
+	e.mu.Unlock()
+	return aead, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,278 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type address struct {
+	Street string `bson:"street" secure:"encrypt"`
+	City   string `bson:"city"`
+}
+
+type customer struct {
+	Name        string     `bson:"name"`
+	Phone       string     `bson:"phone" secure:"encrypt"`
+	PhoneIndex  string     `bson:"phone_bidx" secure:"blind_index=Phone"`
+	DocNumber   string     `bson:"doc_number" secure:"encrypt"`
+	Address     address    `bson:"address"`
+	Previous    []*address `bson:"previous"`
+	LastLoginAt *time.Time `bson:"last_login_at"`
+}
+
+func newTestEncryptor(t *testing.T, current string) *Encryptor {
+	t.Helper()
+	provider, err := NewStaticKeyProvider(current, map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	})
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	encryptor, err := NewEncryptor(provider, Config{IndexKey: bytes.Repeat([]byte{9}, 32)})
+	if err != nil {
+		t.Fatalf("failed to create encryptor: %v", err)
+	}
+	return encryptor
+}
+
+func TestEncryptor_StructRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	encryptor := newTestEncryptor(t, "k1")
+
+	c := &customer{
+		Name:      "Alice",
+		Phone:     "+84912345678",
+		DocNumber: "B1234567",
+		Address:   address{Street: "1 Trang Tien", City: "Hanoi"},
+		Previous:  []*address{{Street: "2 Hang Bai"}, nil},
+	}
+	if err := encryptor.Encrypt(ctx, c); err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	for _, value := range []string{c.Phone, c.DocNumber, c.Address.Street, c.Previous[0].Street} {
+		if !IsEncrypted(value) || strings.Contains(value, "Trang") {
+			t.Errorf("expected encrypted value, got %q", value)
+		}
+	}
+	if c.Name != "Alice" || c.Address.City != "Hanoi" {
+		t.Error("expected untagged fields to stay in plaintext")
+	}
+	if c.PhoneIndex != encryptor.BlindIndex("phone_bidx", "+84912345678") || c.PhoneIndex == "" {
+		t.Errorf("unexpected blind index %q", c.PhoneIndex)
+	}
+
+	if err := encryptor.Decrypt(ctx, c); err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if c.Phone != "+84912345678" || c.DocNumber != "B1234567" || c.Address.Street != "1 Trang Tien" || c.Previous[0].Street != "2 Hang Bai" {
+		t.Errorf("unexpected decrypted struct: %+v", c)
+	}
+}
+
+func TestEncryptor_SealsValuesThatLookEncrypted(t *testing.T) {
+	ctx := context.Background()
+	encryptor := newTestEncryptor(t, "k1")
+	forged, err := encryptor.EncryptString(ctx, "doc_number", "B1234567")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	// A caller-supplied ciphertext must not be stored as is
+	c := &customer{Phone: forged}
+	if err := encryptor.Encrypt(ctx, c); err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if c.Phone == forged || c.PhoneIndex != encryptor.BlindIndex("phone_bidx", forged) {
+		t.Errorf("expected the value to be sealed again, got %q", c.Phone)
+	}
+	update := map[string]interface{}{"$set": map[string]interface{}{"phone": forged}}
+	if err := encryptor.EncryptUpdate(ctx, customer{}, update); err != nil || update["$set"].(map[string]interface{})["phone"] == forged {
+		t.Errorf("expected the $set value to be sealed again, got %v (%v)", update["$set"], err)
+	}
+
+	if err := encryptor.Decrypt(ctx, c); err != nil || c.Phone != forged {
+		t.Errorf("expected the literal input back, got %q (%v)", c.Phone, err)
+	}
+}
+
+func TestEncryptor_DecryptErrors(t *testing.T) {
+	ctx := context.Background()
+	encryptor := newTestEncryptor(t, "k1")
+	encrypted, err := encryptor.EncryptString(ctx, "phone", "+84912345678")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	tampered := []byte(encrypted)
+	tampered[len(tampered)-2] ^= 1
+
+	tests := []struct {
+		name  string
+		field string
+		value string
+		want  string
+		err   error
+	}{
+		{"plaintext passes through", "phone", "+8400000", "+8400000", nil},
+		{"round trip", "phone", encrypted, "+84912345678", nil},
+		{"other field", "doc_number", encrypted, "", ErrDecryptionFailed},
+		{"tampered", "phone", string(tampered), "", ErrDecryptionFailed},
+		{"truncated", "phone", prefix + "AAE", "", ErrDecryptionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encryptor.DecryptString(ctx, tt.field, tt.value)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.want, tt.err, got, err)
+			}
+		})
+	}
+}
+
+func TestEncryptor_Rotate(t *testing.T) {
+	ctx := context.Background()
+	old := newTestEncryptor(t, "k1")
+	c := &customer{Phone: "+84912345678"}
+	if err := old.Encrypt(ctx, c); err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	current := newTestEncryptor(t, "k2")
+	changed, err := current.Rotate(ctx, c)
+	if err != nil || !changed {
+		t.Fatalf("expected rotation, got %v (%v)", changed, err)
+	}
+	if keyID, _ := KeyID(c.Phone); keyID != "k2" {
+		t.Errorf("expected value encrypted with k2, got %s", keyID)
+	}
+	if changed, _ := current.Rotate(ctx, c); changed {
+		t.Error("expected no change for values with the current key")
+	}
+	if err := current.Decrypt(ctx, c); err != nil || c.Phone != "+84912345678" {
+		t.Errorf("unexpected decrypted phone %q (%v)", c.Phone, err)
+	}
+}
+
+func TestEncryptor_DataKeyTTL(t *testing.T) {
+	ctx := context.Background()
+	encryptor := newTestEncryptor(t, "k1")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	encryptor.now = func() time.Time { return now }
+
+	first, _ := encryptor.activeKey(ctx)
+	same, _ := encryptor.activeKey(ctx)
+	now = now.Add(25 * time.Hour)
+	renewed, _ := encryptor.activeKey(ctx)
+	if first != same || first == renewed {
+		t.Error("expected the data key to be reused until it expires")
+	}
+}
+
+func TestEncryptor_EncryptUpdate(t *testing.T) {
+	ctx := context.Background()
+	encryptor := newTestEncryptor(t, "k1")
+
+	set := map[string]interface{}{"phone": "+84987654321", "address.street": "3 Ly Thai To", "name": "Bob"}
+	update := map[string]interface{}{"$set": set}
+	if err := encryptor.EncryptUpdate(ctx, customer{}, update); err != nil {
+		t.Fatalf("failed to encrypt update: %v", err)
+	}
+	if set["phone"] != "+84987654321" {
+		t.Error("expected the caller's $set to be left unchanged")
+	}
+	set = update["$set"].(map[string]interface{})
+	if !IsEncrypted(set["phone"].(string)) || !IsEncrypted(set["address.street"].(string)) || set["name"] != "Bob" {
+		t.Errorf("unexpected update: %v", set)
+	}
+	if set["phone_bidx"] != encryptor.BlindIndex("phone_bidx", "+84987654321") {
+		t.Errorf("expected blind index to be set, got %v", set["phone_bidx"])
+	}
+	if street, err := encryptor.DecryptString(ctx, "address.street", set["address.street"].(string)); err != nil || street != "3 Ly Thai To" {
+		t.Errorf("unexpected street %q (%v)", street, err)
+	}
+}
+
+func TestEncryptor_EncryptUpdateDocuments(t *testing.T) {
+	ctx := context.Background()
+	encryptor := newTestEncryptor(t, "k1")
+	home := &address{Street: "1 Trang Tien", City: "Hanoi"}
+
+	update := map[string]interface{}{
+		"$set":         map[string]interface{}{"address": *home, "previous.0.street": "2 Hang Bai"},
+		"$setOnInsert": map[string]interface{}{"phone": "+84912345678", "previous": []*address{home}},
+	}
+	if err := encryptor.EncryptUpdate(ctx, customer{}, update); err != nil {
+		t.Fatalf("failed to encrypt update: %v", err)
+	}
+	if home.Street != "1 Trang Tien" {
+		t.Error("expected the caller's struct to be left unchanged")
+	}
+
+	set := update["$set"].(map[string]interface{})
+	onInsert := update["$setOnInsert"].(map[string]interface{})
+	for _, value := range []string{
+		set["address"].(address).Street,
+		set["previous.0.street"].(string),
+		onInsert["phone"].(string),
+		onInsert["previous"].([]*address)[0].Street,
+	} {
+		if !IsEncrypted(value) {
+			t.Errorf("expected encrypted value, got %q", value)
+		}
+	}
+	if street, err := encryptor.DecryptString(ctx, "previous.street", set["previous.0.street"].(string)); err != nil || street != "2 Hang Bai" {
+		t.Errorf("unexpected street %q (%v)", street, err)
+	}
+	if onInsert["phone_bidx"] != encryptor.BlindIndex("phone_bidx", "+84912345678") {
+		t.Errorf("expected the blind index in $setOnInsert, got %v", onInsert["phone_bidx"])
+	}
+}
+
+func TestEncryptor_EncryptUpdateRejectsPlaintext(t *testing.T) {
+	encryptor := newTestEncryptor(t, "k1")
+
+	tests := []struct {
+		name   string
+		update map[string]interface{}
+	}{
+		{"map for embedded document", map[string]interface{}{"$set": map[string]interface{}{"address": map[string]interface{}{"street": "x"}}}},
+		{"other struct type", map[string]interface{}{"$set": map[string]interface{}{"address": struct{ Street string }{"x"}}}},
+		{"non-string value", map[string]interface{}{"$set": map[string]interface{}{"phone": 84912345678}}},
+		{"push", map[string]interface{}{"$push": map[string]interface{}{"previous": address{Street: "x"}}}},
+		{"set is not a document", map[string]interface{}{"$set": []string{"phone"}}},
+		{"rename into encrypted field", map[string]interface{}{"$rename": map[string]interface{}{"phone_tmp": "phone"}}},
+		{"rename out of encrypted field", map[string]interface{}{"$rename": map[string]interface{}{"phone": "phone_plain"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := encryptor.EncryptUpdate(context.Background(), customer{}, tt.update); !errors.Is(err, ErrUnsupportedField) {
+				t.Errorf("expected ErrUnsupportedField, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptor_Validation(t *testing.T) {
+	if _, err := NewStaticKeyProvider("missing", map[string][]byte{"k1": make([]byte, 32)}); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+	if _, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": make([]byte, 7)}); err == nil {
+		t.Error("expected invalid key size error")
+	}
+
+	encryptor := newTestEncryptor(t, "k1")
+	var invalid struct {
+		Age int `secure:"encrypt"`
+	}
+	if err := encryptor.Encrypt(context.Background(), &invalid); !errors.Is(err, ErrUnsupportedField) {
+		t.Errorf("expected ErrUnsupportedField, got %v", err)
+	}
+}
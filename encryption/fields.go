@@ -0,0 +1,578 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// field describes a struct field that is tagged or may contain tagged fields
+type field struct {
+	index   int
+	path    string // BSON field name
+	inline  bool
+	encrypt bool
+	indexOf string // Go name of the source field of a blind index
+	nested  bool
+}
+
+var fieldCache sync.Map // reflect.Type -> []field
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structFields returns the tagged and nested fields of a struct type
+func structFields(t reflect.Type) ([]field, error) {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field), nil
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, inline, skip := bsonName(sf)
+		if skip {
+			continue
+		}
+
+		f := field{index: i, path: name, inline: inline}
+		tag := sf.Tag.Get("secure")
+		switch {
+		case tag == "encrypt":
+			f.encrypt = true
+		case strings.HasPrefix(tag, "blind_index="):
+			f.indexOf = strings.TrimPrefix(tag, "blind_index=")
+			source, ok := t.FieldByName(f.indexOf)
+			if !ok || source.Type.Kind() != reflect.String {
+				return nil, fmt.Errorf("%w: blind index %s.%s needs a string source field %q", ErrUnsupportedField, t.Name(), sf.Name, f.indexOf)
+			}
+		case tag != "":
+			return nil, fmt.Errorf("%w: unknown secure tag %q on %s.%s", ErrUnsupportedField, tag, t.Name(), sf.Name)
+		default:
+			f.nested = isNested(sf.Type)
+			if !f.nested {
+				continue
+			}
+		}
+		if (f.encrypt || f.indexOf != "") && sf.Type.Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: %s.%s must be a string", ErrUnsupportedField, t.Name(), sf.Name)
+		}
+		fields = append(fields, f)
+	}
+
+	fieldCache.Store(t, fields)
+	return fields, nil
+}
+
+// bsonName returns the BSON name of a field like the driver's default struct codec
+func bsonName(sf reflect.StructField) (name string, inline, skip bool) {
+	tag := sf.Tag.Get("bson")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "inline" {
+			inline = true
+		}
+	}
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(sf.Name)
+	}
+	return name, inline, false
+}
+
+// isNested reports whether a field type may contain tagged fields
+func isNested(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		t = t.Elem()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+type fieldMode int
+
+const (
+	modeEncrypt fieldMode = iota
+	modeDecrypt
+	modeRotate
+)
+
+// fieldOp applies a mode to the tagged fields of a struct tree
+type fieldOp struct {
+	ctx     context.Context
+	mode    fieldMode
+	current string // current KEK ID when rotating
+	changed bool
+}
+
+// Encrypt computes the blind indexes of a struct and encrypts its tagged
+// fields, including those of nested structs and slices of structs, in place.
+// v must be a pointer to a struct. Empty values are left as is; every other
+// value is treated as plaintext and sealed, even one that looks encrypted, so
+// callers cannot store ciphertext of their choosing. Values that are already
+// sealed are re-encrypted with Rotate.
+func (e *Encryptor) Encrypt(ctx context.Context, v interface{}) error {
+	return e.apply(&fieldOp{ctx: ctx, mode: modeEncrypt}, v)
+}
+
+// Decrypt decrypts the tagged fields of a struct in place. v must be a pointer to a struct.
+func (e *Encryptor) Decrypt(ctx context.Context, v interface{}) error {
+	return e.apply(&fieldOp{ctx: ctx, mode: modeDecrypt}, v)
+}
+
+// Rotate re-encrypts the tagged fields encrypted with a key other than the
+// current one and reports whether anything changed, so the caller knows to
+// save the struct. v must be a pointer to a struct.
+func (e *Encryptor) Rotate(ctx context.Context, v interface{}) (bool, error) {
+	current, err := e.provider.CurrentKeyID(ctx)
+	if err != nil {
+		return false, err
+	}
+	op := &fieldOp{ctx: ctx, mode: modeRotate, current: current}
+	if err := e.apply(op, v); err != nil {
+		return false, err
+	}
+	return op.changed, nil
+}
+
+func (e *Encryptor) apply(op *fieldOp, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("encryption target must be a non-nil pointer to a struct")
+	}
+	return e.applyStruct(op, rv.Elem(), "")
+}
+
+func (e *Encryptor) applyStruct(op *fieldOp, s reflect.Value, prefix string) error {
+	fields, err := structFields(s.Type())
+	if err != nil {
+		return err
+	}
+
+	// Blind indexes are computed from the plaintext, before encryption
+	if op.mode == modeEncrypt {
+		for _, f := range fields {
+			if f.indexOf == "" {
+				continue
+			}
+			source := s.FieldByName(f.indexOf).String()
+			index := ""
+			if source != "" {
+				index = e.BlindIndex(joinPath(prefix, f), source)
+			}
+			s.Field(f.index).SetString(index)
+		}
+	}
+
+	for _, f := range fields {
+		value := s.Field(f.index)
+		switch {
+		case f.encrypt:
+			if err := e.applyValue(op, value, joinPath(prefix, f)); err != nil {
+				return err
+			}
+		case f.nested:
+			if err := e.applyNested(op, value, joinPath(prefix, f)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Encryptor) applyNested(op *fieldOp, value reflect.Value, path string) error {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return e.applyNested(op, value.Elem(), path)
+	case reflect.Struct:
+		return e.applyStruct(op, value, path)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := e.applyNested(op, value.Index(i), path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Encryptor) applyValue(op *fieldOp, value reflect.Value, path string) error {
+	current := value.String()
+	switch op.mode {
+	case modeEncrypt:
+		if current == "" {
+			return nil
+		}
+		encrypted, err := e.EncryptString(op.ctx, path, current)
+		if err != nil {
+			return err
+		}
+		value.SetString(encrypted)
+	case modeDecrypt:
+		decrypted, err := e.DecryptString(op.ctx, path, current)
+		if err != nil {
+			return err
+		}
+		value.SetString(decrypted)
+	case modeRotate:
+		if !IsEncrypted(current) {
+			return nil
+		}
+		if keyID, err := KeyID(current); err != nil || keyID == op.current {
+			return err
+		}
+		plaintext, err := e.DecryptString(op.ctx, path, current)
+		if err != nil {
+			return err
+		}
+		encrypted, err := e.EncryptString(op.ctx, path, plaintext)
+		if err != nil {
+			return err
+		}
+		value.SetString(encrypted)
+		op.changed = true
+	}
+	return nil
+}
+
+// EncryptUpdate encrypts the sensitive values of an update document such as
+// {"$set": {...}} and sets their blind indexes. sample is a value or pointer
+// of the document's struct type, used to find the tagged fields by BSON path.
+// In $set and $setOnInsert, strings at encrypted paths and structs (or
+// slices of structs) containing encrypted fields are sealed like Encrypt
+// does, including at array positions such as "previous.0.street"; the
+// operator documents are replaced with encrypted copies. Any other value
+// that would write an encrypted field, such as a map, a $push or a $rename
+// from or to an encrypted path, is rejected with ErrUnsupportedField rather
+// than stored in plaintext.
+func (e *Encryptor) EncryptUpdate(ctx context.Context, sample interface{}, update map[string]interface{}) error {
+	t := reflect.TypeOf(sample)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return errors.New("encryption sample must be a struct")
+	}
+
+	paths := &updatePaths{
+		encrypted: make(map[string]bool),
+		indexes:   make(map[string][]string),
+		documents: make(map[string]reflect.Type),
+	}
+	if err := paths.collect(t, "", 0); err != nil {
+		return err
+	}
+
+	operators := make([]string, 0, len(update))
+	for operator := range update {
+		operators = append(operators, operator)
+	}
+	for _, operator := range operators {
+		value := update[operator]
+		if value == nil {
+			continue
+		}
+		doc, ok := stringMap(value)
+		switch {
+		case operator == "$set" || operator == "$setOnInsert":
+			if !ok {
+				return fmt.Errorf("%w: %s must be a document", ErrUnsupportedField, operator)
+			}
+			if err := e.encryptSet(ctx, paths, doc); err != nil {
+				return err
+			}
+			update[operator] = withMapType(value, doc)
+		case operator == "$unset":
+		case ok:
+			for key, target := range doc {
+				if path := normalizePath(key); paths.encrypted[path] || paths.contains(path) {
+					return fmt.Errorf("%w: %s cannot write encrypted field %q", ErrUnsupportedField, operator, key)
+				}
+				// $rename moves the plaintext of its source into the target path
+				if target, isString := target.(string); operator == "$rename" && isString {
+					if path := normalizePath(target); paths.encrypted[path] || paths.contains(path) {
+						return fmt.Errorf("%w: %s cannot write encrypted field %q", ErrUnsupportedField, operator, target)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// encryptSet encrypts the values of a copied $set or $setOnInsert document
+func (e *Encryptor) encryptSet(ctx context.Context, paths *updatePaths, set map[string]interface{}) error {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		path := normalizePath(key)
+		switch {
+		case paths.encrypted[path]:
+			if set[key] == nil {
+				continue
+			}
+			value, ok := set[key].(string)
+			if !ok {
+				return fmt.Errorf("%w: %q must be a string", ErrUnsupportedField, key)
+			}
+			for _, indexPath := range paths.indexes[path] {
+				index := ""
+				if value != "" {
+					index = e.BlindIndex(indexPath, value)
+				}
+				set[siblingKey(key, indexPath)] = index
+			}
+			if value == "" {
+				continue
+			}
+			encrypted, err := e.EncryptString(ctx, path, value)
+			if err != nil {
+				return err
+			}
+			set[key] = encrypted
+		case paths.contains(path):
+			encrypted, err := e.encryptDocument(ctx, paths.documents[path], set[key], path)
+			if err != nil {
+				return fmt.Errorf("%q: %w", key, err)
+			}
+			set[key] = encrypted
+		}
+	}
+	return nil
+}
+
+// encryptDocument returns an encrypted copy of a struct, pointer to struct
+// or slice of them of type doc, stored at path
+func (e *Encryptor) encryptDocument(ctx context.Context, doc reflect.Type, value interface{}, path string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(value)
+	base := rv.Type()
+	for base.Kind() == reflect.Ptr || base.Kind() == reflect.Slice || base.Kind() == reflect.Array {
+		base = base.Elem()
+	}
+	if base != doc {
+		return nil, fmt.Errorf("%w: expected %s values, got %T", ErrUnsupportedField, doc, value)
+	}
+
+	copied, err := cloneValue(rv)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.applyNested(&fieldOp{ctx: ctx, mode: modeEncrypt}, copied, path); err != nil {
+		return nil, err
+	}
+	return copied.Interface(), nil
+}
+
+// cloneValue copies a value down to its tagged fields, so that encrypting
+// the copy leaves the original unchanged
+func cloneValue(v reflect.Value) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, nil
+		}
+		elem, err := cloneValue(v.Elem())
+		if err != nil {
+			return v, err
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(elem)
+		return copied, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v, nil
+		}
+		var copied reflect.Value
+		if v.Kind() == reflect.Slice {
+			copied = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		} else {
+			copied = reflect.New(v.Type()).Elem()
+		}
+		for i := 0; i < v.Len(); i++ {
+			elem, err := cloneValue(v.Index(i))
+			if err != nil {
+				return v, err
+			}
+			copied.Index(i).Set(elem)
+		}
+		return copied, nil
+	case reflect.Struct:
+		fields, err := structFields(v.Type())
+		if err != nil {
+			return v, err
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for _, f := range fields {
+			if !f.nested {
+				continue
+			}
+			elem, err := cloneValue(v.Field(f.index))
+			if err != nil {
+				return v, err
+			}
+			copied.Field(f.index).Set(elem)
+		}
+		return copied, nil
+	}
+	return v, nil
+}
+
+// stringMap returns a copy of a map with string keys, such as bson.M
+func stringMap(value interface{}) (map[string]interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	copied := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		copied[iter.Key().String()] = iter.Value().Interface()
+	}
+	return copied, true
+}
+
+// withMapType converts doc back to the map type of original
+func withMapType(original interface{}, doc map[string]interface{}) interface{} {
+	t := reflect.TypeOf(original)
+	if t == reflect.TypeOf(doc) || t.Elem().Kind() != reflect.Interface {
+		return doc
+	}
+	converted := reflect.MakeMapWithSize(t, len(doc))
+	for key, value := range doc {
+		elem := reflect.Zero(t.Elem())
+		if value != nil {
+			elem = reflect.ValueOf(value)
+		}
+		converted.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+	}
+	return converted.Interface()
+}
+
+// normalizePath drops the array positions of an update key, so that
+// "previous.0.street" and "previous.$[].street" map to "previous.street"
+func normalizePath(key string) string {
+	segments := strings.Split(key, ".")
+	kept := segments[:0]
+	for _, segment := range segments {
+		if segment == "$" || strings.HasPrefix(segment, "$[") || isIndex(segment) {
+			continue
+		}
+		kept = append(kept, segment)
+	}
+	return strings.Join(kept, ".")
+}
+
+func isIndex(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, r := range segment {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// siblingKey returns the update key of the blind index stored next to the
+// field written by key, keeping the key's array positions
+func siblingKey(key, indexPath string) string {
+	name := indexPath[strings.LastIndex(indexPath, ".")+1:]
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i+1] + name
+	}
+	return name
+}
+
+// updatePaths maps the dotted BSON paths of encrypted fields, their blind
+// indexes and the embedded documents containing them
+type updatePaths struct {
+	encrypted map[string]bool
+	indexes   map[string][]string     // source path -> blind index paths
+	documents map[string]reflect.Type // embedded document path -> struct type
+}
+
+// maxUpdateDepth bounds the walk of recursive struct types
+const maxUpdateDepth = 8
+
+// contains reports whether path is an embedded document holding encrypted fields
+func (p *updatePaths) contains(path string) bool {
+	if _, ok := p.documents[path]; !ok {
+		return false
+	}
+	for encrypted := range p.encrypted {
+		if strings.HasPrefix(encrypted, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *updatePaths) collect(t reflect.Type, prefix string, depth int) error {
+	if depth > maxUpdateDepth {
+		return nil
+	}
+	fields, err := structFields(t)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		path := joinPath(prefix, f)
+		switch {
+		case f.encrypt:
+			p.encrypted[path] = true
+		case f.indexOf != "":
+			source, _ := t.FieldByName(f.indexOf)
+			name, _, _ := bsonName(source)
+			sourcePath := name
+			if prefix != "" {
+				sourcePath = prefix + "." + name
+			}
+			p.indexes[sourcePath] = append(p.indexes[sourcePath], path)
+		case f.nested:
+			ft := t.Field(f.index).Type
+			for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+				ft = ft.Elem()
+			}
+			if !f.inline {
+				p.documents[path] = ft
+			}
+			if err := p.collect(ft, path, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinPath(prefix string, f field) string {
+	if f.inline {
+		return prefix
+	}
+	if prefix == "" {
+		return f.path
+	}
+	return prefix + "." + f.path
+}
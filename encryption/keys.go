@@ -0,0 +1,86 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// KeyProvider manages key-encryption keys (KEKs). Data keys are generated by
+// the Encryptor and stored with each value wrapped (encrypted) by a KEK, so
+// the KEKs never leave the provider, which may be backed by a KMS.
+type KeyProvider interface {
+	// CurrentKeyID returns the ID of the KEK used to wrap new data keys
+	CurrentKeyID(ctx context.Context) (string, error)
+	// WrapKey encrypts a data key with the KEK
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped with the KEK; it returns
+	// ErrUnknownKey when the KEK does not exist
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// StaticKeyProvider is a KeyProvider over fixed AES keys, e.g. loaded from
+// configuration. Rotate keys by adding a new key and making it current;
+// older keys must be kept until every value has been re-encrypted.
+type StaticKeyProvider struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewStaticKeyProvider creates a key provider; keys must be 16, 24 or 32 bytes
+func NewStaticKeyProvider(current string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: current key %q is not configured", ErrUnknownKey, current)
+	}
+
+	provider := &StaticKeyProvider{current: current, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		provider.keys[id] = aead
+	}
+	return provider, nil
+}
+
+// CurrentKeyID returns the ID of the current key
+func (p *StaticKeyProvider) CurrentKeyID(ctx context.Context) (string, error) {
+	return p.current, nil
+}
+
+// WrapKey encrypts a data key with the key
+func (p *StaticKeyProvider) WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+// UnwrapKey decrypts a data key wrapped with the key
+func (p *StaticKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	nonceSize := aead.NonceSize()
+	if len(wrapped) < nonceSize {
+		return nil, ErrDecryptionFailed
+	}
+	dataKey, err := aead.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], []byte(keyID))
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return dataKey, nil
+}
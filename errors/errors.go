@@ -2,6 +2,7 @@ package errors
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 )
@@ -99,11 +100,18 @@ func (e *AppError) ToJSON() []byte {
 	return data
 }
 
-// FromError converts a standard error to AppError
+// FromError converts a standard error to AppError. Wrapped AppErrors and
+// errors with an AppError() *AppError method (e.g. mongodb.VersionConflictError)
+// keep their code and status; anything else becomes an internal error.
 func FromError(err error) *AppError {
-	if appErr, ok := err.(*AppError); ok {
+	var appErr *AppError
+	if stderrors.As(err, &appErr) {
 		return appErr
 	}
+	var converter interface{ AppError() *AppError }
+	if stderrors.As(err, &converter) {
+		return converter.AppError()
+	}
 	return Internal(err.Error())
 }
 
@@ -22,9 +22,27 @@ type Claims struct {
 	Email       string   `json:"email"`
 	Roles       []string `json:"roles"`
 	Permissions []string `json:"permissions,omitempty"`
+	// MFAVerified is set once the user has completed a second factor
+	MFAVerified bool `json:"mfa,omitempty"`
+	// Actor is set on impersonation tokens and identifies the real user (RFC 8693 "act" claim)
+	Actor *Actor `json:"act,omitempty"`
+	// BlockedPermissions are denied for the lifetime of the token, regardless of Permissions
+	BlockedPermissions []string `json:"blocked_permissions,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor identifies the user acting on behalf of the token subject
+type Actor struct {
+	UserID   string `json:"sub"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+// IsImpersonation reports whether the token was issued for impersonation
+func (c *Claims) IsImpersonation() bool {
+	return c.Actor != nil
+}
+
 // Manager handles JWT operations
 type Manager struct {
 	secret            []byte
@@ -66,6 +84,41 @@ func (m *Manager) GenerateToken(userID, tenantID, email string, roles, permissio
 	return tokenString, nil
 }
 
+// GenerateTokenWithClaims signs the given claims as an access token
+// Time-based registered claims are filled in when not already set
+func (m *Manager) GenerateTokenWithClaims(claims Claims) (string, error) {
+	now := time.Now()
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(m.expiration))
+	}
+	if claims.IssuedAt == nil {
+		claims.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if claims.NotBefore == nil {
+		claims.NotBefore = jwt.NewNumericDate(now)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(m.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// GenerateMFAToken generates an access token marked as second-factor verified
+func (m *Manager) GenerateMFAToken(userID, tenantID, email string, roles, permissions []string) (string, error) {
+	return m.GenerateTokenWithClaims(Claims{
+		UserID:      userID,
+		TenantID:    tenantID,
+		Email:       email,
+		Roles:       roles,
+		Permissions: permissions,
+		MFAVerified: true,
+	})
+}
+
 // GenerateRefreshToken generates a refresh token
 func (m *Manager) GenerateRefreshToken(userID, tenantID string) (string, error) {
 	now := time.Now()
@@ -0,0 +1,89 @@
+package lifecycle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ArchiveFormatVersion is the version of the export archive layout
+const ArchiveFormatVersion = 1
+
+// Manifest describes the content of an export archive. It is stored as
+// manifest.json at the root of the zip archive; each store writes its files
+// under a directory named after the store.
+type Manifest struct {
+	Version    int              `json:"version"`
+	TenantID   string           `json:"tenant_id"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Stores     []*ManifestStore `json:"stores"`
+}
+
+// ManifestStore lists the files exported by one data store
+type ManifestStore struct {
+	Name  string   `json:"name"`
+	Files []string `json:"files"`
+}
+
+// Archive writes the files of one data store into an export archive
+type Archive struct {
+	zip   *zip.Writer
+	store *ManifestStore
+}
+
+// Create adds a file to the archive under the store's directory. The
+// returned writer is valid until the next call to Create.
+func (a *Archive) Create(name string) (io.Writer, error) {
+	clean := path.Clean("/" + name)[1:]
+	if clean == "" || strings.HasPrefix(clean, "../") {
+		return nil, fmt.Errorf("invalid archive file name: %q", name)
+	}
+
+	file := a.store.Name + "/" + clean
+	w, err := a.zip.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	a.store.Files = append(a.store.Files, file)
+	return w, nil
+}
+
+// archiveWriter builds a zip export archive
+type archiveWriter struct {
+	zip      *zip.Writer
+	manifest Manifest
+}
+
+func newArchiveWriter(w io.Writer, tenantID string, now time.Time) *archiveWriter {
+	return &archiveWriter{
+		zip: zip.NewWriter(w),
+		manifest: Manifest{
+			Version:    ArchiveFormatVersion,
+			TenantID:   tenantID,
+			ExportedAt: now,
+		},
+	}
+}
+
+func (w *archiveWriter) store(name string) *Archive {
+	store := &ManifestStore{Name: name, Files: []string{}}
+	w.manifest.Stores = append(w.manifest.Stores, store)
+	return &Archive{zip: w.zip, store: store}
+}
+
+func (w *archiveWriter) close() error {
+	manifest, err := w.zip.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifest)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(w.manifest); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
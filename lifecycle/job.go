@@ -0,0 +1,156 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobKind is the lifecycle operation tracked by a job
+type JobKind string
+
+const (
+	JobProvision JobKind = "provision"
+	JobDelete    JobKind = "delete"
+)
+
+// JobStatus is the status of a job or of one of its steps
+type JobStatus string
+
+const (
+	StatusPending   JobStatus = "pending"
+	StatusRunning   JobStatus = "running"
+	StatusCompleted JobStatus = "completed"
+	StatusFailed    JobStatus = "failed"
+)
+
+// StepProgress records the progress of one provisioning step or data store
+type StepProgress struct {
+	Name        string     `bson:"name" json:"name"`
+	Status      JobStatus  `bson:"status" json:"status"`
+	Removed     int64      `bson:"removed,omitempty" json:"removed,omitempty"`
+	Error       string     `bson:"error,omitempty" json:"error,omitempty"`
+	CompletedAt *time.Time `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// Job tracks a lifecycle operation for a tenant. Jobs are persisted after
+// every step so an interrupted operation resumes where it stopped.
+type Job struct {
+	ID          string         `bson:"_id" json:"id"`
+	TenantID    string         `bson:"tenant_id" json:"tenant_id"`
+	Kind        JobKind        `bson:"kind" json:"kind"`
+	Status      JobStatus      `bson:"status" json:"status"`
+	Steps       []StepProgress `bson:"steps" json:"steps"`
+	StartedAt   time.Time      `bson:"started_at" json:"started_at"`
+	UpdatedAt   time.Time      `bson:"updated_at" json:"updated_at"`
+	CompletedAt *time.Time     `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// Step returns the progress of a named step, or nil
+func (j *Job) Step(name string) *StepProgress {
+	for i := range j.Steps {
+		if j.Steps[i].Name == name {
+			return &j.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Progress returns the number of completed steps and the total number of steps
+func (j *Job) Progress() (completed, total int) {
+	for _, step := range j.Steps {
+		if step.Status == StatusCompleted {
+			completed++
+		}
+	}
+	return completed, len(j.Steps)
+}
+
+func jobID(kind JobKind, tenantID string) string {
+	return string(kind) + ":" + tenantID
+}
+
+// JobStore persists lifecycle jobs
+type JobStore interface {
+	// Get returns a job by ID or ErrJobNotFound
+	Get(ctx context.Context, id string) (*Job, error)
+	// Save creates or replaces a job
+	Save(ctx context.Context, job *Job) error
+}
+
+// MongoJobStore stores lifecycle jobs in MongoDB
+type MongoJobStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoJobStore creates a MongoDB-backed job store
+func NewMongoJobStore(collection *mongo.Collection) *MongoJobStore {
+	return &MongoJobStore{collection: collection}
+}
+
+// Get returns a job by ID
+func (s *MongoJobStore) Get(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Save creates or replaces a job
+func (s *MongoJobStore) Save(ctx context.Context, job *Job) error {
+	_, err := s.collection.ReplaceOne(ctx,
+		bson.M{"_id": job.ID},
+		job,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// MemoryJobStore is an in-memory JobStore, intended for tests
+type MemoryJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// NewMemoryJobStore creates an in-memory job store
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Get returns a job by ID
+func (s *MemoryJobStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return copyJob(job), nil
+}
+
+// Save creates or replaces a job
+func (s *MemoryJobStore) Save(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = copyJob(job)
+	return nil
+}
+
+func copyJob(job *Job) *Job {
+	copied := *job
+	copied.Steps = append([]StepProgress(nil), job.Steps...)
+	return &copied
+}
@@ -0,0 +1,235 @@
+// Package lifecycle runs tenant lifecycle operations: provisioning a new
+// tenant, exporting all of a tenant's data into a portable archive, and
+// verified hard deletion across every registered data store.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/vhvplatform/go-shared/tenant"
+)
+
+var (
+	// ErrJobNotFound is returned when no lifecycle job exists for a tenant
+	ErrJobNotFound = errors.New("lifecycle job not found")
+	// ErrVerificationFailed is returned when tenant data remains in a store after deletion
+	ErrVerificationFailed = errors.New("tenant data remains after deletion")
+)
+
+// Provisioner is a step run when a tenant is provisioned. Steps must be
+// idempotent: a failed provisioning job re-runs the step when resumed.
+type Provisioner interface {
+	Name() string
+	Provision(ctx context.Context, tenantID string) error
+}
+
+type stepFunc struct {
+	name string
+	fn   func(ctx context.Context, tenantID string) error
+}
+
+func (s stepFunc) Name() string { return s.name }
+
+func (s stepFunc) Provision(ctx context.Context, tenantID string) error { return s.fn(ctx, tenantID) }
+
+// NewStep creates a provisioning step from a function
+func NewStep(name string, fn func(ctx context.Context, tenantID string) error) Provisioner {
+	return stepFunc{name: name, fn: fn}
+}
+
+// DataStore is a store holding tenant data that takes part in export and deletion
+type DataStore interface {
+	// Name identifies the store in jobs and in the export archive
+	Name() string
+	// Export writes all of the tenant's data to the archive
+	Export(ctx context.Context, tenantID string, archive *Archive) error
+	// Delete removes all of the tenant's data and returns the number of items removed.
+	// It must be idempotent so interrupted deletions can be resumed.
+	Delete(ctx context.Context, tenantID string) (int64, error)
+	// Count returns the number of items the tenant still has in the store
+	Count(ctx context.Context, tenantID string) (int64, error)
+}
+
+// Config configures a Manager
+type Config struct {
+	// Registry, when set, marks the tenant deleted before its data is purged so it stops serving requests
+	Registry *tenant.Registry
+	// OnProgress, when set, is called with a snapshot of the job after every step
+	OnProgress func(job Job)
+}
+
+// Manager runs tenant lifecycle operations over the registered provisioning
+// steps and data stores. Steps and stores must be registered during setup,
+// before the manager is used.
+type Manager struct {
+	jobs         JobStore
+	registry     *tenant.Registry
+	onProgress   func(job Job)
+	provisioners []Provisioner
+	stores       []DataStore
+	now          func() time.Time
+}
+
+// NewManager creates a lifecycle manager
+func NewManager(jobs JobStore, config Config) *Manager {
+	return &Manager{
+		jobs:       jobs,
+		registry:   config.Registry,
+		onProgress: config.OnProgress,
+		now:        time.Now,
+	}
+}
+
+// RegisterProvisioner adds provisioning steps, run in registration order
+func (m *Manager) RegisterProvisioner(provisioners ...Provisioner) {
+	m.provisioners = append(m.provisioners, provisioners...)
+}
+
+// RegisterStore adds data stores, exported and deleted in registration order
+func (m *Manager) RegisterStore(stores ...DataStore) {
+	m.stores = append(m.stores, stores...)
+}
+
+// Provision runs the provisioning steps for a tenant. Completed steps of a
+// previous run are skipped, so a failed provisioning can be retried.
+func (m *Manager) Provision(ctx context.Context, tenantID string) (*Job, error) {
+	names := make([]string, len(m.provisioners))
+	for i, p := range m.provisioners {
+		names[i] = p.Name()
+	}
+
+	return m.run(ctx, JobProvision, tenantID, names, func(i int, step *StepProgress) error {
+		return m.provisioners[i].Provision(ctx, tenantID)
+	})
+}
+
+// Export writes all of the tenant's data from every registered store into a
+// zip archive and returns its manifest
+func (m *Manager) Export(ctx context.Context, tenantID string, w io.Writer) (*Manifest, error) {
+	archive := newArchiveWriter(w, tenantID, m.now())
+	for _, store := range m.stores {
+		if err := store.Export(ctx, tenantID, archive.store(store.Name())); err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", store.Name(), err)
+		}
+	}
+	if err := archive.close(); err != nil {
+		return nil, fmt.Errorf("failed to write export archive: %w", err)
+	}
+	return &archive.manifest, nil
+}
+
+// Delete permanently removes the tenant's data from every registered store
+// and verifies that nothing remains. Progress is persisted after every store;
+// calling Delete again after a failure resumes with the first unfinished store.
+func (m *Manager) Delete(ctx context.Context, tenantID string) (*Job, error) {
+	if m.registry != nil {
+		err := m.registry.SetStatus(ctx, tenantID, tenant.StatusDeleted)
+		if err != nil && !errors.Is(err, tenant.ErrTenantNotFound) {
+			return nil, fmt.Errorf("failed to mark tenant deleted: %w", err)
+		}
+	}
+
+	names := make([]string, len(m.stores))
+	for i, s := range m.stores {
+		names[i] = s.Name()
+	}
+
+	return m.run(ctx, JobDelete, tenantID, names, func(i int, step *StepProgress) error {
+		store := m.stores[i]
+		removed, err := store.Delete(ctx, tenantID)
+		step.Removed += removed
+		if err != nil {
+			return err
+		}
+
+		remaining, err := store.Count(ctx, tenantID)
+		if err != nil {
+			return fmt.Errorf("failed to verify deletion: %w", err)
+		}
+		if remaining > 0 {
+			return fmt.Errorf("%w: %d items remain", ErrVerificationFailed, remaining)
+		}
+		return nil
+	})
+}
+
+// Job returns the latest job of a kind for a tenant
+func (m *Manager) Job(ctx context.Context, kind JobKind, tenantID string) (*Job, error) {
+	return m.jobs.Get(ctx, jobID(kind, tenantID))
+}
+
+// run executes the named steps in order. A failed or interrupted job is
+// resumed, skipping its completed steps; otherwise a fresh job replaces the
+// previous one, so running again after completion repeats every step.
+func (m *Manager) run(ctx context.Context, kind JobKind, tenantID string, names []string, runStep func(i int, step *StepProgress) error) (*Job, error) {
+	job, err := m.jobs.Get(ctx, jobID(kind, tenantID))
+	if err != nil && !errors.Is(err, ErrJobNotFound) {
+		return nil, fmt.Errorf("failed to load %s job: %w", kind, err)
+	}
+	if err != nil || (job.Status != StatusFailed && job.Status != StatusRunning) {
+		job = &Job{ID: jobID(kind, tenantID), TenantID: tenantID, Kind: kind, StartedAt: m.now()}
+	}
+
+	// Steps registered after a previous run are added as pending
+	for _, name := range names {
+		if job.Step(name) == nil {
+			job.Steps = append(job.Steps, StepProgress{Name: name, Status: StatusPending})
+		}
+	}
+	job.Status = StatusRunning
+	job.CompletedAt = nil
+	if err := m.save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	for i, name := range names {
+		step := job.Step(name)
+		if step.Status == StatusCompleted {
+			continue
+		}
+
+		step.Status = StatusRunning
+		step.Error = ""
+		stepErr := runStep(i, step)
+
+		if stepErr != nil {
+			step.Status = StatusFailed
+			step.Error = stepErr.Error()
+			job.Status = StatusFailed
+			if err := m.save(ctx, job); err != nil {
+				return job, err
+			}
+			return job, fmt.Errorf("%s step %s failed: %w", kind, name, stepErr)
+		}
+
+		completedAt := m.now()
+		step.Status = StatusCompleted
+		step.CompletedAt = &completedAt
+		if err := m.save(ctx, job); err != nil {
+			return job, err
+		}
+	}
+
+	completedAt := m.now()
+	job.Status = StatusCompleted
+	job.CompletedAt = &completedAt
+	if err := m.save(ctx, job); err != nil {
+		return job, err
+	}
+	return job, nil
+}
+
+func (m *Manager) save(ctx context.Context, job *Job) error {
+	job.UpdatedAt = m.now()
+	if err := m.jobs.Save(ctx, job); err != nil {
+		return fmt.Errorf("failed to save %s job: %w", job.Kind, err)
+	}
+	if m.onProgress != nil {
+		m.onProgress(*copyJob(job))
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package lifecycle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/vhvplatform/go-shared/tenant"
+)
+
+// memoryStore is a DataStore over an in-memory map of tenant items
+type memoryStore struct {
+	name      string
+	items     map[string][]string
+	failAfter int // when > 0, Delete removes at most failAfter items and fails
+}
+
+func (s *memoryStore) Name() string { return s.name }
+
+func (s *memoryStore) Export(ctx context.Context, tenantID string, archive *Archive) error {
+	w, err := archive.Create("items.txt")
+	if err != nil {
+		return err
+	}
+	for _, item := range s.items[tenantID] {
+		io.WriteString(w, item+"\n")
+	}
+	return nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, tenantID string) (int64, error) {
+	items := s.items[tenantID]
+	if s.failAfter > 0 && len(items) > s.failAfter {
+		s.items[tenantID] = items[s.failAfter:]
+		return int64(s.failAfter), errors.New("connection reset")
+	}
+	delete(s.items, tenantID)
+	return int64(len(items)), nil
+}
+
+func (s *memoryStore) Count(ctx context.Context, tenantID string) (int64, error) {
+	return int64(len(s.items[tenantID])), nil
+}
+
+func TestManager_Provision(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager(NewMemoryJobStore(), Config{})
+
+	calls := map[string]int{}
+	failing := true
+	manager.RegisterProvisioner(
+		NewStep("indexes", func(ctx context.Context, tenantID string) error {
+			calls["indexes"]++
+			return nil
+		}),
+		NewStep("defaults", func(ctx context.Context, tenantID string) error {
+			calls["defaults"]++
+			if failing {
+				return errors.New("temporarily unavailable")
+			}
+			return nil
+		}),
+	)
+
+	job, err := manager.Provision(ctx, "acme")
+	if err == nil || job.Status != StatusFailed || job.Step("defaults").Error == "" {
+		t.Fatalf("expected failed provisioning, got %+v (%v)", job, err)
+	}
+
+	failing = false
+	job, err = manager.Provision(ctx, "acme")
+	if err != nil || job.Status != StatusCompleted {
+		t.Fatalf("expected completed provisioning, got %+v (%v)", job, err)
+	}
+	if calls["indexes"] != 1 || calls["defaults"] != 2 {
+		t.Errorf("expected completed steps to be skipped on resume, got %v", calls)
+	}
+}
+
+func TestManager_DeleteResumesAndVerifies(t *testing.T) {
+	ctx := context.Background()
+	registry := tenant.NewRegistry(tenant.NewMemoryRegistryStore(), nil, tenant.RegistryConfig{})
+	registry.Save(ctx, &tenant.Tenant{ID: "acme"})
+
+	var snapshots []Job
+	manager := NewManager(NewMemoryJobStore(), Config{
+		Registry:   registry,
+		OnProgress: func(job Job) { snapshots = append(snapshots, job) },
+	})
+
+	documents := &memoryStore{name: "documents", items: map[string][]string{"acme": {"a", "b"}, "globex": {"c"}}}
+	objects := &memoryStore{name: "objects", items: map[string][]string{"acme": {"1", "2", "3"}}, failAfter: 2}
+	manager.RegisterStore(documents, objects)
+
+	job, err := manager.Delete(ctx, "acme")
+	if err == nil || job.Status != StatusFailed {
+		t.Fatalf("expected interrupted deletion, got %+v (%v)", job, err)
+	}
+	if completed, total := job.Progress(); completed != 1 || total != 2 {
+		t.Errorf("expected 1/2 steps completed, got %d/%d", completed, total)
+	}
+	if _, err := registry.GetActive(ctx, "acme"); !errors.Is(err, tenant.ErrTenantDeleted) {
+		t.Errorf("expected tenant to be marked deleted, got %v", err)
+	}
+
+	objects.failAfter = 0
+	job, err = manager.Delete(ctx, "acme")
+	if err != nil || job.Status != StatusCompleted {
+		t.Fatalf("expected completed deletion, got %+v (%v)", job, err)
+	}
+	if removed := job.Step("objects").Removed; removed != 3 {
+		t.Errorf("expected 3 objects removed across runs, got %d", removed)
+	}
+	if len(documents.items["globex"]) != 1 {
+		t.Error("expected other tenants to be untouched")
+	}
+	if len(snapshots) == 0 || snapshots[len(snapshots)-1].Status != StatusCompleted {
+		t.Error("expected progress snapshots ending with the completed job")
+	}
+
+	stored, err := manager.Job(ctx, JobDelete, "acme")
+	if err != nil || stored.Status != StatusCompleted {
+		t.Errorf("expected persisted completed job, got %+v (%v)", stored, err)
+	}
+}
+
+func TestManager_RerunsCompletedJob(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager(NewMemoryJobStore(), Config{})
+	documents := &memoryStore{name: "documents", items: map[string][]string{"acme": {"a", "b"}}}
+	manager.RegisterStore(documents)
+
+	if job, err := manager.Delete(ctx, "acme"); err != nil || job.Status != StatusCompleted {
+		t.Fatalf("expected completed deletion, got %+v (%v)", job, err)
+	}
+
+	// Data written after the first deletion, e.g. by a re-created tenant
+	documents.items["acme"] = []string{"c"}
+	job, err := manager.Delete(ctx, "acme")
+	if err != nil || job.Status != StatusCompleted {
+		t.Fatalf("expected completed deletion, got %+v (%v)", job, err)
+	}
+	if len(documents.items["acme"]) != 0 || job.Step("documents").Removed != 1 {
+		t.Errorf("expected the second deletion to remove the new data, got %v (%+v)", documents.items, job.Step("documents"))
+	}
+}
+
+func TestEscapeGlob(t *testing.T) {
+	tests := []struct {
+		tenantID string
+		expected string
+	}{
+		{"acme", "acme"},
+		{"*", `\*`},
+		{"a?c[1]", `a\?c\[1\]`},
+		{`a\b`, `a\\b`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tenantID, func(t *testing.T) {
+			if escaped := escapeGlob(tt.tenantID); escaped != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, escaped)
+			}
+		})
+	}
+}
+
+func TestObjectStore_TenantPrefix(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"tenants/{tenant}", "tenants/acme/"},
+		{"tenants/{tenant}/", "tenants/acme/"},
+		{"{tenant}/uploads/", "acme/uploads/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			store := NewObjectStore(nil, "bucket", tt.prefix)
+			if prefix := store.tenantPrefix("acme"); prefix != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, prefix)
+			}
+		})
+	}
+}
+
+// leakyStore reports remaining items after deletion
+type leakyStore struct{ memoryStore }
+
+func (s *leakyStore) Count(ctx context.Context, tenantID string) (int64, error) { return 1, nil }
+
+func TestManager_DeleteVerificationFailure(t *testing.T) {
+	manager := NewManager(NewMemoryJobStore(), Config{})
+	manager.RegisterStore(&leakyStore{memoryStore{name: "cache", items: map[string][]string{}}})
+
+	if _, err := manager.Delete(context.Background(), "acme"); !errors.Is(err, ErrVerificationFailed) {
+		t.Errorf("expected ErrVerificationFailed, got %v", err)
+	}
+}
+
+func TestManager_Export(t *testing.T) {
+	manager := NewManager(NewMemoryJobStore(), Config{})
+	manager.RegisterStore(
+		&memoryStore{name: "documents", items: map[string][]string{"acme": {"a", "b"}}},
+		&memoryStore{name: "objects", items: map[string][]string{"acme": {"1"}}},
+	)
+
+	var buf bytes.Buffer
+	manifest, err := manager.Export(context.Background(), "acme", &buf)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if manifest.TenantID != "acme" || len(manifest.Stores) != 2 || manifest.Stores[0].Files[0] != "documents/items.txt" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid archive: %v", err)
+	}
+	files := map[string]string{}
+	for _, file := range reader.File {
+		rc, _ := file.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[file.Name] = string(data)
+	}
+	if files["documents/items.txt"] != "a\nb\n" || files["objects/items.txt"] != "1\n" || files["manifest.json"] == "" {
+		t.Errorf("unexpected archive content: %v", files)
+	}
+}
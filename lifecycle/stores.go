@@ -0,0 +1,477 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/vhvplatform/go-shared/clickhouse"
+	"github.com/vhvplatform/go-shared/mongodb"
+	"github.com/vhvplatform/go-shared/redis"
+	"github.com/vhvplatform/go-shared/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TenantPlaceholder is replaced by the tenant ID in Redis key patterns and object storage prefixes
+const TenantPlaceholder = "{tenant}"
+
+// TenantIndexStep returns a provisioning step that creates the tenant_id index
+// on the tenant's routed collections
+func TenantIndexStep(router *mongodb.TenantRouter, collections ...string) Provisioner {
+	return NewStep("mongodb_tenant_indexes", func(ctx context.Context, tenantID string) error {
+		for _, name := range collections {
+			collection, err := router.TenantCollection(ctx, tenantID, name)
+			if err != nil {
+				return err
+			}
+			if err := mongodb.NewTenantRepository(collection, tenantID).EnsureTenantIndex(ctx); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// MongoStore is a DataStore over MongoDB collections isolated by tenant_id,
+// routed per tenant through a TenantRouter
+type MongoStore struct {
+	router      *mongodb.TenantRouter
+	collections []string
+}
+
+// NewMongoStore creates a MongoDB data store for the named collections
+func NewMongoStore(router *mongodb.TenantRouter, collections ...string) *MongoStore {
+	return &MongoStore{router: router, collections: collections}
+}
+
+// Name returns "mongodb"
+func (s *MongoStore) Name() string {
+	return "mongodb"
+}
+
+// Export writes each collection as canonical Extended JSON lines
+func (s *MongoStore) Export(ctx context.Context, tenantID string, archive *Archive) error {
+	for _, name := range s.collections {
+		collection, err := s.router.TenantCollection(ctx, tenantID, name)
+		if err != nil {
+			return err
+		}
+
+		w, err := archive.Create(name + ".jsonl")
+		if err != nil {
+			return err
+		}
+
+		cursor, err := collection.Find(ctx, bson.M{"tenant_id": tenantID})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for cursor.Next(ctx) {
+			line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+			if err != nil {
+				cursor.Close(ctx)
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Delete removes the tenant's documents from every collection
+func (s *MongoStore) Delete(ctx context.Context, tenantID string) (int64, error) {
+	var removed int64
+	for _, name := range s.collections {
+		collection, err := s.router.TenantCollection(ctx, tenantID, name)
+		if err != nil {
+			return removed, err
+		}
+		result, err := collection.DeleteMany(ctx, bson.M{"tenant_id": tenantID})
+		if err != nil {
+			return removed, fmt.Errorf("%s: %w", name, err)
+		}
+		removed += result.DeletedCount
+	}
+	return removed, nil
+}
+
+// Count returns the number of the tenant's documents across the collections
+func (s *MongoStore) Count(ctx context.Context, tenantID string) (int64, error) {
+	var total int64
+	for _, name := range s.collections {
+		collection, err := s.router.TenantCollection(ctx, tenantID, name)
+		if err != nil {
+			return total, err
+		}
+		count, err := collection.CountDocuments(ctx, bson.M{"tenant_id": tenantID})
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", name, err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// RedisStore is a DataStore over Redis keys matching tenant key patterns
+type RedisStore struct {
+	client   *redis.Client
+	patterns []string
+}
+
+// NewRedisStore creates a Redis data store. Patterns are SCAN match patterns
+// containing TenantPlaceholder, e.g. "cache:{tenant}:*".
+func NewRedisStore(client *redis.Client, patterns ...string) *RedisStore {
+	return &RedisStore{client: client, patterns: patterns}
+}
+
+// Name returns "redis"
+func (s *RedisStore) Name() string {
+	return "redis"
+}
+
+type redisEntry struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	TTLMs int64       `json:"ttl_ms,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// Export writes every matching key with its type, TTL and value as JSON lines
+func (s *RedisStore) Export(ctx context.Context, tenantID string, archive *Archive) error {
+	w, err := archive.Create("keys.jsonl")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+
+	return s.scan(ctx, tenantID, func(keys []string) error {
+		for _, key := range keys {
+			entry, err := s.entry(ctx, key)
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				continue
+			}
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete unlinks every matching key
+func (s *RedisStore) Delete(ctx context.Context, tenantID string) (int64, error) {
+	var removed int64
+	err := s.scan(ctx, tenantID, func(keys []string) error {
+		n, err := s.client.Unlink(ctx, keys...).Result()
+		removed += n
+		return err
+	})
+	return removed, err
+}
+
+// Count returns the number of matching keys
+func (s *RedisStore) Count(ctx context.Context, tenantID string) (int64, error) {
+	var total int64
+	err := s.scan(ctx, tenantID, func(keys []string) error {
+		total += int64(len(keys))
+		return nil
+	})
+	return total, err
+}
+
+func (s *RedisStore) scan(ctx context.Context, tenantID string, fn func(keys []string) error) error {
+	for _, pattern := range s.patterns {
+		match := strings.ReplaceAll(pattern, TenantPlaceholder, escapeGlob(tenantID))
+		var cursor uint64
+		for {
+			keys, next, err := s.client.Scan(ctx, cursor, match, 500).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err := fn(keys); err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+	return nil
+}
+
+// escapeGlob escapes SCAN MATCH metacharacters so a tenant ID only matches itself
+func escapeGlob(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// entry reads a key for export; it returns nil when the key expired meanwhile
+func (s *RedisStore) entry(ctx context.Context, key string) (*redisEntry, error) {
+	keyType, err := s.client.Type(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &redisEntry{Key: key, Type: keyType}
+	switch keyType {
+	case "none":
+		return nil, nil
+	case "string":
+		entry.Value, err = s.client.Client.Get(ctx, key).Result()
+	case "hash":
+		entry.Value, err = s.client.HGetAll(ctx, key).Result()
+	case "list":
+		entry.Value, err = s.client.LRange(ctx, key, 0, -1).Result()
+	case "set":
+		entry.Value, err = s.client.SMembers(ctx, key).Result()
+	case "zset":
+		entry.Value, err = s.client.ZRangeWithScores(ctx, key, 0, -1).Result()
+	default:
+		return nil, fmt.Errorf("unsupported redis type %q for key %s", keyType, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl, err := s.client.PTTL(ctx, key).Result(); err == nil && ttl > 0 {
+		entry.TTLMs = ttl.Milliseconds()
+	}
+	return entry, nil
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ClickHouseStore is a DataStore over ClickHouse tables with a tenant_id column
+type ClickHouseStore struct {
+	client *clickhouse.Client
+	tables []string
+}
+
+// NewClickHouseStore creates a ClickHouse data store for the named tables
+func NewClickHouseStore(client *clickhouse.Client, tables ...string) (*ClickHouseStore, error) {
+	for _, table := range tables {
+		if !identifierPattern.MatchString(table) {
+			return nil, fmt.Errorf("invalid table name: %q", table)
+		}
+	}
+	return &ClickHouseStore{client: client, tables: tables}, nil
+}
+
+// Name returns "clickhouse"
+func (s *ClickHouseStore) Name() string {
+	return "clickhouse"
+}
+
+// Export writes each table's rows as JSON lines
+func (s *ClickHouseStore) Export(ctx context.Context, tenantID string, archive *Archive) error {
+	for _, table := range s.tables {
+		w, err := archive.Create(table + ".jsonl")
+		if err != nil {
+			return err
+		}
+		if err := s.exportTable(ctx, tenantID, table, w); err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func (s *ClickHouseStore) exportTable(ctx context.Context, tenantID, table string, w io.Writer) error {
+	rows, err := s.client.GetConn().Query(ctx, "SELECT * FROM "+table+" WHERE tenant_id = ?", tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns := rows.ColumnTypes()
+	encoder := json.NewEncoder(w)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			values[i] = reflect.New(column.ScanType()).Interface()
+		}
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column.Name()] = reflect.ValueOf(values[i]).Elem().Interface()
+		}
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Delete removes the tenant's rows with synchronous mutations
+func (s *ClickHouseStore) Delete(ctx context.Context, tenantID string) (int64, error) {
+	var removed int64
+	for _, table := range s.tables {
+		count, err := s.countTable(ctx, tenantID, table)
+		if err != nil {
+			return removed, err
+		}
+		if count == 0 {
+			continue
+		}
+		err = s.client.Exec(ctx, "ALTER TABLE "+table+" DELETE WHERE tenant_id = ? SETTINGS mutations_sync = 2", tenantID)
+		if err != nil {
+			return removed, fmt.Errorf("%s: %w", table, err)
+		}
+		removed += count
+	}
+	return removed, nil
+}
+
+// Count returns the number of the tenant's rows across the tables
+func (s *ClickHouseStore) Count(ctx context.Context, tenantID string) (int64, error) {
+	var total int64
+	for _, table := range s.tables {
+		count, err := s.countTable(ctx, tenantID, table)
+		if err != nil {
+			return total, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func (s *ClickHouseStore) countTable(ctx context.Context, tenantID, table string) (int64, error) {
+	var count uint64
+	if err := s.client.QueryRow(ctx, &count, "SELECT count() FROM "+table+" WHERE tenant_id = ?", tenantID); err != nil {
+		return 0, fmt.Errorf("%s: %w", table, err)
+	}
+	return int64(count), nil
+}
+
+// ObjectStore is a DataStore over objects stored under a per-tenant key prefix
+type ObjectStore struct {
+	client storage.Client
+	bucket string
+	prefix string
+}
+
+// NewObjectStore creates an object storage data store. The prefix contains
+// TenantPlaceholder, e.g. "tenants/{tenant}/". A prefix ending with the
+// placeholder gets a trailing "/", so tenant "acme" does not match "acme2/".
+func NewObjectStore(client storage.Client, bucket, prefix string) *ObjectStore {
+	if strings.HasSuffix(prefix, TenantPlaceholder) {
+		prefix += "/"
+	}
+	return &ObjectStore{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Name returns "objects"
+func (s *ObjectStore) Name() string {
+	return "objects"
+}
+
+// Export copies every object into the archive, keyed relative to the tenant prefix
+func (s *ObjectStore) Export(ctx context.Context, tenantID string, archive *Archive) error {
+	prefix := s.tenantPrefix(tenantID)
+	return s.list(ctx, prefix, func(objects []storage.Object) error {
+		for _, object := range objects {
+			if err := s.exportObject(ctx, archive, strings.TrimPrefix(object.Key, prefix), object.Key); err != nil {
+				return fmt.Errorf("%s: %w", object.Key, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *ObjectStore) exportObject(ctx context.Context, archive *Archive, name, key string) error {
+	body, err := s.client.Download(ctx, s.bucket, &storage.DownloadInput{Key: key})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, body)
+	return err
+}
+
+// Delete removes every object under the tenant prefix
+func (s *ObjectStore) Delete(ctx context.Context, tenantID string) (int64, error) {
+	var removed int64
+	prefix := s.tenantPrefix(tenantID)
+	for {
+		output, err := s.client.List(ctx, s.bucket, &storage.ListInput{Prefix: prefix, MaxKeys: 1000})
+		if err != nil {
+			return removed, err
+		}
+		if len(output.Objects) == 0 {
+			return removed, nil
+		}
+
+		keys := make([]string, len(output.Objects))
+		for i, object := range output.Objects {
+			keys[i] = object.Key
+		}
+		if err := s.client.DeleteMultiple(ctx, s.bucket, keys); err != nil {
+			return removed, err
+		}
+		removed += int64(len(keys))
+	}
+}
+
+// Count returns the number of objects under the tenant prefix
+func (s *ObjectStore) Count(ctx context.Context, tenantID string) (int64, error) {
+	var total int64
+	err := s.list(ctx, s.tenantPrefix(tenantID), func(objects []storage.Object) error {
+		total += int64(len(objects))
+		return nil
+	})
+	return total, err
+}
+
+func (s *ObjectStore) tenantPrefix(tenantID string) string {
+	return strings.ReplaceAll(s.prefix, TenantPlaceholder, tenantID)
+}
+
+func (s *ObjectStore) list(ctx context.Context, prefix string, fn func(objects []storage.Object) error) error {
+	input := &storage.ListInput{Prefix: prefix, MaxKeys: 1000}
+	for {
+		output, err := s.client.List(ctx, s.bucket, input)
+		if err != nil {
+			return err
+		}
+		if err := fn(output.Objects); err != nil {
+			return err
+		}
+		if !output.IsTruncated || output.NextMarker == "" {
+			return nil
+		}
+		input.Marker = output.NextMarker
+	}
+}
@@ -0,0 +1,211 @@
+// Package metering records per-tenant usage (API calls, storage, messages
+// sent) in Redis counters, flushes it periodically to long-term storage such
+// as ClickHouse, and enforces plan-based quotas.
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrQuotaExceeded is returned when usage would exceed a hard quota
+	ErrQuotaExceeded = errors.New("usage quota exceeded")
+)
+
+// Metric is a metered usage dimension
+type Metric string
+
+const (
+	MetricAPICalls     Metric = "api_calls"
+	MetricStorageBytes Metric = "storage_bytes"
+	MetricEmailsSent   Metric = "emails_sent"
+	MetricSMSSent      Metric = "sms_sent"
+)
+
+// Quota limits a metric per billing period
+type Quota struct {
+	Limit int64 `json:"limit"`
+	// Hard quotas reject usage beyond the limit; soft quotas only report it
+	Hard bool `json:"hard"`
+}
+
+// Plans maps plan names to their quotas. Metrics without a quota are unlimited.
+type Plans map[string]map[Metric]Quota
+
+// Quota returns the quota of a metric for a plan
+func (p Plans) Quota(plan string, metric Metric) (Quota, bool) {
+	quota, ok := p[plan][metric]
+	return quota, ok
+}
+
+// Usage is the usage of a metric in the current billing period
+type Usage struct {
+	Metric  Metric    `json:"metric"`
+	Used    int64     `json:"used"`
+	Limit   int64     `json:"limit,omitempty"`
+	Limited bool      `json:"limited"`
+	Hard    bool      `json:"hard,omitempty"`
+	ResetAt time.Time `json:"reset_at"`
+}
+
+// Remaining returns the usage left before the quota is reached (0 when unlimited or exhausted)
+func (u *Usage) Remaining() int64 {
+	if !u.Limited || u.Used >= u.Limit {
+		return 0
+	}
+	return u.Limit - u.Used
+}
+
+// Exceeded reports whether usage is beyond the quota
+func (u *Usage) Exceeded() bool {
+	return u.Limited && u.Used > u.Limit
+}
+
+// Config configures a Meter
+type Config struct {
+	Plans       Plans
+	DefaultPlan string        // plan used when a tenant has none
+	Retention   time.Duration // how long counters are kept after their period ends (default: 7 days)
+}
+
+// Meter records tenant usage and enforces quotas. Billing periods are calendar months in UTC.
+type Meter struct {
+	store       Store
+	plans       Plans
+	defaultPlan string
+	retention   time.Duration
+	now         func() time.Time
+}
+
+// NewMeter creates a usage meter
+func NewMeter(store Store, config Config) *Meter {
+	if config.Retention <= 0 {
+		config.Retention = 7 * 24 * time.Hour
+	}
+	return &Meter{
+		store:       store,
+		plans:       config.Plans,
+		defaultPlan: config.DefaultPlan,
+		retention:   config.Retention,
+		now:         time.Now,
+	}
+}
+
+// Record adds usage without enforcing quotas, e.g. for storage bytes (amount may be negative)
+func (m *Meter) Record(ctx context.Context, tenantID string, metric Metric, amount int64) error {
+	counter, _, ttl := m.counter(tenantID, metric)
+	_, _, err := m.store.Add(ctx, counter, amount, -1, ttl)
+	return err
+}
+
+// Consume adds usage for a tenant on a plan. When a hard quota would be
+// exceeded nothing is recorded and ErrQuotaExceeded is returned together with
+// the current usage; soft quotas are recorded and reported via Usage.Exceeded.
+func (m *Meter) Consume(ctx context.Context, tenantID, plan string, metric Metric, amount int64) (*Usage, error) {
+	counter, resetAt, ttl := m.counter(tenantID, metric)
+	usage := &Usage{Metric: metric, ResetAt: resetAt}
+
+	limit := int64(-1)
+	if quota, ok := m.quota(plan, metric); ok {
+		usage.Limited, usage.Limit, usage.Hard = true, quota.Limit, quota.Hard
+		if quota.Hard {
+			limit = quota.Limit
+		}
+	}
+
+	added, used, err := m.store.Add(ctx, counter, amount, limit, ttl)
+	if err != nil {
+		return nil, err
+	}
+	usage.Used = used
+	if !added {
+		return usage, fmt.Errorf("%w: %s", ErrQuotaExceeded, metric)
+	}
+	return usage, nil
+}
+
+// Usage returns the current-period usage of a metric for a tenant on a plan
+func (m *Meter) Usage(ctx context.Context, tenantID, plan string, metric Metric) (*Usage, error) {
+	counter, resetAt, _ := m.counter(tenantID, metric)
+	used, err := m.store.Get(ctx, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{Metric: metric, Used: used, ResetAt: resetAt}
+	if quota, ok := m.quota(plan, metric); ok {
+		usage.Limited, usage.Limit, usage.Hard = true, quota.Limit, quota.Hard
+	}
+	return usage, nil
+}
+
+// Flush writes the usage recorded since the last flush to the sink and
+// returns the number of records written
+func (m *Meter) Flush(ctx context.Context, sink Sink) (int, error) {
+	written := 0
+	err := m.store.Flush(ctx, func(ctx context.Context, deltas map[Counter]int64) error {
+		flushedAt := m.now().UTC()
+		records := make([]Record, 0, len(deltas))
+		for counter, amount := range deltas {
+			records = append(records, Record{
+				TenantID:  counter.TenantID,
+				Metric:    counter.Metric,
+				Period:    counter.Period,
+				Amount:    amount,
+				FlushedAt: flushedAt,
+			})
+		}
+		if err := sink.Write(ctx, records); err != nil {
+			return err
+		}
+		written = len(records)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to flush usage: %w", err)
+	}
+	return written, nil
+}
+
+// Run flushes usage to the sink every interval until ctx is cancelled, with a
+// final flush on shutdown. Flush errors are passed to onError when set and
+// retried on the next tick.
+func (m *Meter) Run(ctx context.Context, sink Sink, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			if _, err := m.Flush(flushCtx, sink); err != nil && onError != nil {
+				onError(err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if _, err := m.Flush(ctx, sink); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (m *Meter) quota(plan string, metric Metric) (Quota, bool) {
+	if plan == "" {
+		plan = m.defaultPlan
+	}
+	return m.plans.Quota(plan, metric)
+}
+
+// counter returns the counter of the current period, its reset time and the counter TTL
+func (m *Meter) counter(tenantID string, metric Metric) (Counter, time.Time, time.Duration) {
+	now := m.now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	resetAt := start.AddDate(0, 1, 0)
+	counter := Counter{TenantID: tenantID, Metric: metric, Period: start.Format("2006-01")}
+	return counter, resetAt, resetAt.Sub(now) + m.retention
+}
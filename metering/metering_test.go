@@ -0,0 +1,100 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMeter_Consume(t *testing.T) {
+	ctx := context.Background()
+	meter := NewMeter(NewMemoryStore(), Config{
+		DefaultPlan: "free",
+		Plans: Plans{
+			"free": {MetricAPICalls: {Limit: 2, Hard: true}, MetricEmailsSent: {Limit: 1}},
+		},
+	})
+	meter.now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		metric    Metric
+		amount    int64
+		used      int64
+		remaining int64
+		exceeded  bool
+		err       error
+	}{
+		{"first call", MetricAPICalls, 1, 1, 1, false, nil},
+		{"last call", MetricAPICalls, 1, 2, 0, false, nil},
+		{"hard quota", MetricAPICalls, 1, 2, 0, false, ErrQuotaExceeded},
+		{"soft quota", MetricEmailsSent, 3, 3, 0, true, nil},
+		{"unlimited", MetricSMSSent, 5, 5, 0, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage, err := meter.Consume(ctx, "acme", "", tt.metric, tt.amount)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if usage.Used != tt.used || usage.Remaining() != tt.remaining || usage.Exceeded() != tt.exceeded {
+				t.Errorf("unexpected usage: %+v", usage)
+			}
+			if !usage.ResetAt.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("expected reset at the start of next month, got %v", usage.ResetAt)
+			}
+		})
+	}
+
+	// A new billing period starts from zero
+	meter.now = func() time.Time { return time.Date(2026, 11, 1, 0, 0, 1, 0, time.UTC) }
+	if _, err := meter.Consume(ctx, "acme", "", MetricAPICalls, 1); err != nil {
+		t.Errorf("expected quota to reset, got %v", err)
+	}
+}
+
+func TestMeter_Flush(t *testing.T) {
+	ctx := context.Background()
+	meter := NewMeter(NewMemoryStore(), Config{})
+	meter.Record(ctx, "acme|eu", MetricStorageBytes, 1024)
+	meter.Record(ctx, "acme|eu", MetricStorageBytes, -24)
+	meter.Record(ctx, "globex", MetricAPICalls, 1)
+
+	failing := SinkFunc(func(ctx context.Context, records []Record) error {
+		return errors.New("clickhouse unavailable")
+	})
+	if _, err := meter.Flush(ctx, failing); err == nil {
+		t.Fatal("expected flush error")
+	}
+
+	var records []Record
+	sink := SinkFunc(func(ctx context.Context, batch []Record) error {
+		records = append(records, batch...)
+		return nil
+	})
+	if n, err := meter.Flush(ctx, sink); err != nil || n != 2 {
+		t.Fatalf("expected 2 records after retry, got %d (%v)", n, err)
+	}
+	for _, record := range records {
+		if record.TenantID == "acme|eu" && record.Amount != 1000 {
+			t.Errorf("expected aggregated storage delta of 1000, got %d", record.Amount)
+		}
+	}
+
+	if n, _ := meter.Flush(ctx, sink); n != 0 {
+		t.Errorf("expected nothing left to flush, got %d records", n)
+	}
+}
+
+func TestParseCounter(t *testing.T) {
+	counter := Counter{TenantID: "acme|eu", Metric: MetricSMSSent, Period: "2026-10"}
+	parsed, ok := parseCounter(counter.field())
+	if !ok || parsed != counter {
+		t.Errorf("expected %+v, got %+v", counter, parsed)
+	}
+	if _, ok := parseCounter("invalid"); ok {
+		t.Error("expected invalid field to be rejected")
+	}
+}
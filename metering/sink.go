@@ -0,0 +1,56 @@
+package metering
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vhvplatform/go-shared/clickhouse"
+)
+
+// Sink persists flushed usage records, typically for billing
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+}
+
+// SinkFunc adapts a function to Sink
+type SinkFunc func(ctx context.Context, records []Record) error
+
+// Write calls f(ctx, records)
+func (f SinkFunc) Write(ctx context.Context, records []Record) error {
+	return f(ctx, records)
+}
+
+// ClickHouseSink writes usage records to a ClickHouse table with the columns
+// (tenant_id String, metric String, period String, amount Int64, flushed_at DateTime)
+type ClickHouseSink struct {
+	client *clickhouse.Client
+	query  string
+}
+
+// NewClickHouseSink creates a ClickHouse usage sink for the table
+func NewClickHouseSink(client *clickhouse.Client, table string) *ClickHouseSink {
+	return &ClickHouseSink{
+		client: client,
+		query:  fmt.Sprintf("INSERT INTO %s (tenant_id, metric, period, amount, flushed_at)", table),
+	}
+}
+
+// Write inserts the records in a single batch
+func (s *ClickHouseSink) Write(ctx context.Context, records []Record) error {
+	batch, err := s.client.NewBatchInserter(ctx, s.query)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := batch.Append(record.TenantID, string(record.Metric), record.Period, record.Amount, record.FlushedAt); err != nil {
+			batch.Abort()
+			return fmt.Errorf("failed to append usage record: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to send usage records: %w", err)
+	}
+	return nil
+}
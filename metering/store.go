@@ -0,0 +1,231 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/vhvplatform/go-shared/redis"
+)
+
+// Counter identifies a usage counter of a tenant for one billing period
+type Counter struct {
+	TenantID string
+	Metric   Metric
+	Period   string
+}
+
+func (c Counter) field() string {
+	return c.TenantID + "|" + string(c.Metric) + "|" + c.Period
+}
+
+// parseCounter parses a field produced by Counter.field; the tenant ID may itself contain "|"
+func parseCounter(field string) (Counter, bool) {
+	periodSep := strings.LastIndexByte(field, '|')
+	if periodSep <= 0 {
+		return Counter{}, false
+	}
+	metricSep := strings.LastIndexByte(field[:periodSep], '|')
+	if metricSep <= 0 {
+		return Counter{}, false
+	}
+	return Counter{
+		TenantID: field[:metricSep],
+		Metric:   Metric(field[metricSep+1 : periodSep]),
+		Period:   field[periodSep+1:],
+	}, true
+}
+
+// Record is a usage delta flushed to long-term storage
+type Record struct {
+	TenantID  string
+	Metric    Metric
+	Period    string
+	Amount    int64
+	FlushedAt time.Time
+}
+
+// Store keeps per-period usage counters and the deltas not yet flushed
+type Store interface {
+	// Add adds amount to the counter and to the pending deltas, unless limit
+	// is not negative and the counter would exceed it. It returns whether the
+	// amount was added and the resulting counter value.
+	Add(ctx context.Context, counter Counter, amount, limit int64, ttl time.Duration) (bool, int64, error)
+	// Get returns the counter value
+	Get(ctx context.Context, counter Counter) (int64, error)
+	// Flush passes the pending deltas to write and clears them once write succeeds.
+	// Deltas recorded during the flush are kept for the next flush.
+	Flush(ctx context.Context, write func(ctx context.Context, deltas map[Counter]int64) error) error
+}
+
+// addScript atomically checks the limit, increments the counter and records the pending delta
+var addScript = goredis.NewScript(`
+local current = tonumber(redis.call("get", KEYS[1]) or "0")
+local amount = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+if limit >= 0 and current + amount > limit then
+    return {0, current}
+end
+local total = redis.call("incrby", KEYS[1], amount)
+redis.call("expire", KEYS[1], ARGV[3])
+redis.call("hincrby", KEYS[2], ARGV[4], amount)
+return {1, total}
+`)
+
+// RedisStore keeps usage counters in Redis. Pending deltas are kept in a
+// hash that is renamed while flushing, so a failed flush is retried with the
+// same deltas and concurrent increments are never lost. Flushes are
+// serialized across instances with a distributed lock.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a Redis-backed usage store
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "usage"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) counterKey(counter Counter) string {
+	return fmt.Sprintf("%s:%s:%s:%s", s.prefix, counter.TenantID, counter.Metric, counter.Period)
+}
+
+// Add adds amount to the counter unless the limit would be exceeded
+func (s *RedisStore) Add(ctx context.Context, counter Counter, amount, limit int64, ttl time.Duration) (bool, int64, error) {
+	result, err := addScript.Run(ctx, s.client.Client,
+		[]string{s.counterKey(counter), s.prefix + ":pending"},
+		amount, limit, int64(ttl.Seconds()), counter.field(),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to record usage: %w", err)
+	}
+	return result[0] == 1, result[1], nil
+}
+
+// Get returns the counter value
+func (s *RedisStore) Get(ctx context.Context, counter Counter) (int64, error) {
+	value, err := s.client.Client.Get(ctx, s.counterKey(counter)).Int64()
+	if errors.Is(err, goredis.Nil) {
+		return 0, nil
+	}
+	return value, err
+}
+
+// Flush passes the pending deltas to write. It is a no-op when another instance is flushing.
+func (s *RedisStore) Flush(ctx context.Context, write func(ctx context.Context, deltas map[Counter]int64) error) error {
+	lock := redis.NewRedisLock(s.client.Client, s.prefix+":flush_lock", time.Minute)
+	if err := lock.Acquire(ctx, 0); err != nil {
+		if errors.Is(err, redis.ErrLockNotAcquired) {
+			return nil
+		}
+		return err
+	}
+	defer lock.Release(context.WithoutCancel(ctx))
+
+	pending, flushing := s.prefix+":pending", s.prefix+":flushing"
+
+	// A leftover flushing hash is from a failed flush and is retried first
+	exists, err := s.client.Client.Exists(ctx, flushing).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		if err := s.client.Rename(ctx, pending, flushing).Err(); err != nil {
+			if strings.Contains(err.Error(), "no such key") {
+				return nil
+			}
+			return err
+		}
+	}
+
+	fields, err := s.client.HGetAll(ctx, flushing).Result()
+	if err != nil {
+		return err
+	}
+
+	deltas := make(map[Counter]int64, len(fields))
+	for field, value := range fields {
+		counter, ok := parseCounter(field)
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if !ok || err != nil {
+			continue
+		}
+		if amount != 0 {
+			deltas[counter] += amount
+		}
+	}
+
+	if len(deltas) > 0 {
+		if err := write(ctx, deltas); err != nil {
+			return err
+		}
+	}
+	return s.client.Client.Del(ctx, flushing).Err()
+}
+
+// MemoryStore is an in-memory Store, intended for tests
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[Counter]int64
+	pending  map[Counter]int64
+}
+
+// NewMemoryStore creates an in-memory usage store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[Counter]int64),
+		pending:  make(map[Counter]int64),
+	}
+}
+
+// Add adds amount to the counter unless the limit would be exceeded
+func (s *MemoryStore) Add(ctx context.Context, counter Counter, amount, limit int64, ttl time.Duration) (bool, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.counters[counter]
+	if limit >= 0 && current+amount > limit {
+		return false, current, nil
+	}
+	s.counters[counter] = current + amount
+	s.pending[counter] += amount
+	return true, current + amount, nil
+}
+
+// Get returns the counter value
+func (s *MemoryStore) Get(ctx context.Context, counter Counter) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.counters[counter], nil
+}
+
+// Flush passes the pending deltas to write
+func (s *MemoryStore) Flush(ctx context.Context, write func(ctx context.Context, deltas map[Counter]int64) error) error {
+	s.mu.Lock()
+	deltas := s.pending
+	s.pending = make(map[Counter]int64)
+	s.mu.Unlock()
+
+	if len(deltas) == 0 {
+		return nil
+	}
+	if err := write(ctx, deltas); err != nil {
+		// Restore the deltas so the next flush retries them
+		s.mu.Lock()
+		for counter, amount := range deltas {
+			s.pending[counter] += amount
+		}
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/apikey"
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"github.com/vhvplatform/go-shared/response"
+)
+
+// APIKeyAuth authenticates machine clients by API key and sets the same
+// user context as Auth. The key is read from the X-API-Key header or from
+// "Authorization: ApiKey <key>". The key owner becomes user_id and its scopes
+// become permissions.
+func APIKeyAuth(service *apikey.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := extractAPIKey(c)
+		if rawKey == "" {
+			response.Unauthorized(c, "API key required")
+			c.Abort()
+			return
+		}
+
+		key, err := service.Authenticate(c.Request.Context(), rawKey)
+		if err != nil {
+			switch {
+			case errors.Is(err, apikey.ErrKeyExpired):
+				response.Unauthorized(c, "API key expired")
+			case errors.Is(err, apikey.ErrKeyRevoked):
+				response.Unauthorized(c, "API key revoked")
+			case errors.Is(err, apikey.ErrInvalidKey):
+				response.Unauthorized(c, "Invalid API key")
+			default:
+				response.InternalServerError(c, "Failed to authenticate API key")
+			}
+			c.Abort()
+			return
+		}
+
+		setAPIKeyContext(c, key)
+		c.Next()
+	}
+}
+
+// RequireScopes checks that the authenticated API key grants all scopes
+// This middleware should be used after APIKeyAuth
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("api_key")
+		key, ok := value.(*apikey.Key)
+		if !exists || !ok {
+			response.Forbidden(c, "API key required")
+			c.Abort()
+			return
+		}
+
+		for _, scope := range scopes {
+			if !key.HasScope(scope) {
+				response.Error(c, http.StatusForbidden, "INSUFFICIENT_SCOPE", "API key is missing scope: "+scope)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func setAPIKeyContext(c *gin.Context, key *apikey.Key) {
+	c.Set("user_id", key.OwnerID)
+	c.Set("tenant_id", key.TenantID)
+	c.Set("roles", key.Roles)
+	c.Set("permissions", key.Scopes)
+	c.Set("api_key", key)
+	c.Set("api_key_id", key.ID)
+
+	// Expose the identity to permission checks that read the request context
+	c.Request = c.Request.WithContext(pkgctx.GinToStdContext(c))
+}
+
+// extractAPIKey reads the key from X-API-Key or "Authorization: ApiKey <key>"
+func extractAPIKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) == 2 && strings.EqualFold(parts[0], "ApiKey") {
+		return strings.TrimSpace(parts[1])
+	}
+	return ""
+}
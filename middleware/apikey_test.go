@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/apikey"
+)
+
+func TestAPIKeyAuth_RequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := apikey.NewService(apikey.NewMemoryStore(), apikey.Config{})
+	issued, err := service.Issue(context.Background(), apikey.IssueRequest{
+		TenantID: "tenant-1",
+		OwnerID:  "svc-1",
+		Scopes:   []string{"orders:read"},
+	})
+	if err != nil {
+		t.Fatalf("failed to issue key: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(APIKeyAuth(service))
+	router.GET("/orders", RequirePermission("orders:read"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/orders", RequirePermission("orders:write"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		want   int
+	}{
+		{"granted scope", http.MethodGet, issued.Plaintext, http.StatusOK},
+		{"missing scope", http.MethodPost, issued.Plaintext, http.StatusForbidden},
+		{"no key", http.MethodGet, "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/orders", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -42,15 +42,34 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 		}
 
 		// Set user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("tenant_id", claims.TenantID)
-		c.Set("email", claims.Email)
-		c.Set("roles", claims.Roles)
+		setClaimsContext(c, claims)
 
 		c.Next()
 	}
 }
 
+// setClaimsContext sets the user info from JWT claims in the Gin and request contexts
+func setClaimsContext(c *gin.Context, claims *jwt.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("tenant_id", claims.TenantID)
+	c.Set("email", claims.Email)
+	c.Set("roles", claims.Roles)
+	c.Set("permissions", claims.Permissions)
+	c.Set("mfa_verified", claims.MFAVerified)
+
+	if claims.IsImpersonation() {
+		c.Set("actor", &pkgctx.Actor{
+			UserID:   claims.Actor.UserID,
+			TenantID: claims.Actor.TenantID,
+			Email:    claims.Actor.Email,
+		})
+		c.Set("blocked_permissions", claims.BlockedPermissions)
+	}
+
+	// Expose the identity to permission checks that read the request context
+	c.Request = c.Request.WithContext(pkgctx.GinToStdContext(c))
+}
+
 // TenantScope ensures tenant context exists
 // This middleware checks if a tenant ID is present in the context,
 // either from JWT claims or from the X-Tenant-ID header
@@ -139,10 +158,7 @@ func OptionalAuth(jwtSecret string) gin.HandlerFunc {
 		}
 
 		// Set user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("tenant_id", claims.TenantID)
-		c.Set("email", claims.Email)
-		c.Set("roles", claims.Roles)
+		setClaimsContext(c, claims)
 
 		c.Next()
 	}
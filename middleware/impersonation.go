@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/auth"
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"github.com/vhvplatform/go-shared/logger"
+	"github.com/vhvplatform/go-shared/response"
+	"go.uber.org/zap"
+)
+
+// ImpersonationAudit records every request made with an impersonation token
+// This middleware should be used after Auth
+func ImpersonationAudit(auditLog auth.ImpersonationAuditLog, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := pkgctx.GetActorFromGin(c)
+		if actor == nil {
+			c.Next()
+			return
+		}
+
+		c.Next()
+
+		event := &auth.ImpersonationAuditEvent{
+			Type:          auth.ImpersonationRequest,
+			ActorID:       actor.UserID,
+			ActorTenantID: actor.TenantID,
+			ActorEmail:    actor.Email,
+			UserID:        c.GetString("user_id"),
+			TenantID:      c.GetString("tenant_id"),
+			Method:        c.Request.Method,
+			Path:          c.Request.URL.Path,
+			StatusCode:    c.Writer.Status(),
+			IPAddress:     c.ClientIP(),
+			CorrelationID: c.GetString("correlation_id"),
+			Timestamp:     time.Now(),
+		}
+		if err := auditLog.Record(c.Request.Context(), event); err != nil && log != nil {
+			log.Error("Failed to record impersonated request",
+				zap.String("actor_id", actor.UserID),
+				zap.String("user_id", event.UserID),
+				zap.String("path", event.Path),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// DenyImpersonation blocks routes that must only be called by the real user
+// This middleware should be used after Auth
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if pkgctx.GetActorFromGin(c) != nil {
+			response.Error(c, http.StatusForbidden, "IMPERSONATION_FORBIDDEN", "This action is not allowed while impersonating")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
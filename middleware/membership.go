@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/auth"
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"github.com/vhvplatform/go-shared/response"
+	"github.com/vhvplatform/go-shared/tenant"
+)
+
+// RequireTenantMembership verifies that the authenticated user has an active
+// membership in the tenant resolved for the request. Roles are taken from the
+// membership so they always match the resolved tenant, even when the token
+// was issued for another tenant. Super admins (not impersonating) may access
+// any tenant. This middleware should be used after Auth.
+func RequireTenantMembership(resolver *tenant.Resolver, memberships *auth.MembershipService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if userID == "" {
+			response.Unauthorized(c, "Authentication required")
+			c.Abort()
+			return
+		}
+
+		tenantID, domain, err := resolver.Resolve(c)
+		if err != nil {
+			response.BadRequest(c, "Tenant could not be resolved")
+			c.Abort()
+			return
+		}
+
+		rc := *pkgctx.FromGinContext(c)
+		rc.TenantID = tenantID
+		rc.TenantDomain = domain
+
+		if pkgctx.HasRoleFromGin(c, "super_admin") && rc.Actor == nil {
+			setMembershipContext(c, &rc)
+			c.Next()
+			return
+		}
+
+		relation, err := memberships.Verify(c.Request.Context(), userID, tenantID)
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrNotMember):
+				response.Error(c, http.StatusForbidden, "TENANT_ACCESS_DENIED", "You are not a member of this tenant")
+			case errors.Is(err, auth.ErrMembershipInactive):
+				response.Error(c, http.StatusForbidden, "TENANT_MEMBERSHIP_INACTIVE", "Your membership in this tenant is inactive")
+			default:
+				response.InternalServerError(c, "Failed to verify tenant membership")
+			}
+			c.Abort()
+			return
+		}
+
+		// Token permissions only apply to the tenant the token was issued for
+		if c.GetString("tenant_id") != tenantID {
+			permissions, err := memberships.Permissions(c.Request.Context(), relation)
+			if err != nil {
+				response.InternalServerError(c, "Failed to resolve tenant permissions")
+				c.Abort()
+				return
+			}
+			rc.Permissions = permissions
+		}
+		rc.Roles = relation.Roles
+
+		c.Set("tenant_relation", relation)
+		setMembershipContext(c, &rc)
+		c.Next()
+	}
+}
+
+func setMembershipContext(c *gin.Context, rc *pkgctx.RequestContext) {
+	pkgctx.ToGinContext(c, rc)
+	c.Request = c.Request.WithContext(pkgctx.WithRequestContext(c.Request.Context(), rc))
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/metering"
+	"github.com/vhvplatform/go-shared/response"
+	"github.com/vhvplatform/go-shared/tenant"
+)
+
+// Quota meters API calls of the resolved tenant and rejects requests with 429
+// once a hard API call quota is exhausted. When the tenant has an API call
+// quota, X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset headers are set.
+// The plan is taken from the tenant record attached by the tenant resolver.
+// Requests without a tenant are not metered, and metering errors fail open
+// so a Redis outage does not take the API down. This middleware should be
+// used after tenant resolution.
+func Quota(meter *metering.Meter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.GetString("tenant_id")
+		if tenantID == "" {
+			c.Next()
+			return
+		}
+
+		plan := ""
+		if t, ok := tenant.FromGinContext(c); ok {
+			plan = t.Plan
+		}
+
+		usage, err := meter.Consume(c.Request.Context(), tenantID, plan, metering.MetricAPICalls, 1)
+		if usage != nil && usage.Limited {
+			c.Header("X-Quota-Limit", strconv.FormatInt(usage.Limit, 10))
+			c.Header("X-Quota-Remaining", strconv.FormatInt(usage.Remaining(), 10))
+			c.Header("X-Quota-Reset", strconv.FormatInt(usage.ResetAt.Unix(), 10))
+		}
+
+		if errors.Is(err, metering.ErrQuotaExceeded) {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(usage.ResetAt).Seconds())+1))
+			response.Error(c, http.StatusTooManyRequests, "QUOTA_EXCEEDED", "API call quota exceeded for this billing period")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
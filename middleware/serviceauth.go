@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/response"
+	"github.com/vhvplatform/go-shared/serviceauth"
+)
+
+// ServiceAuth authenticates internal service calls by the X-Internal-Token
+// header or the verified mTLS client certificate, and enforces the
+// authenticator's route policy. The caller is stored as "service_name".
+func ServiceAuth(authenticator *serviceauth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var chains [][]*x509.Certificate
+		if c.Request.TLS != nil {
+			chains = c.Request.TLS.VerifiedChains
+		}
+
+		route := serviceauth.HTTPRoute(c.Request.Method, c.Request.URL.Path)
+		identity, err := authenticator.Authenticate(c.GetHeader(serviceauth.HeaderName), chains, route)
+		if err != nil {
+			if errors.Is(err, serviceauth.ErrNotAllowed) {
+				response.Forbidden(c, "Service not allowed to call this route")
+			} else {
+				response.Unauthorized(c, "Invalid service credentials")
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("service_name", identity.Service)
+		c.Request = c.Request.WithContext(serviceauth.WithIdentity(c.Request.Context(), identity))
+		c.Next()
+	}
+}
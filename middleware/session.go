@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"github.com/vhvplatform/go-shared/response"
+	"github.com/vhvplatform/go-shared/session"
+)
+
+// Session authenticates requests by server-side session cookie and fills the
+// request context the same way Auth does for JWTs
+func Session(manager *session.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, err := manager.FromRequest(c.Request)
+		if err != nil {
+			switch {
+			case errors.Is(err, session.ErrSessionNotFound),
+				errors.Is(err, session.ErrSessionExpired),
+				errors.Is(err, session.ErrInvalidCookie):
+				manager.ClearCookie(c.Writer)
+				response.Unauthorized(c, "Invalid or expired session")
+			default:
+				response.InternalServerError(c, "Failed to load session")
+			}
+			c.Abort()
+			return
+		}
+
+		setSessionContext(c, s)
+		c.Next()
+	}
+}
+
+// OptionalSession is similar to Session but doesn't require a session
+// If a valid session cookie is present, it sets the user context, otherwise continues without it
+func OptionalSession(manager *session.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s, err := manager.FromRequest(c.Request); err == nil {
+			setSessionContext(c, s)
+		}
+		c.Next()
+	}
+}
+
+func setSessionContext(c *gin.Context, s *session.Session) {
+	rc := s.RequestContext()
+	rc.CorrelationID = c.GetString("correlation_id")
+	rc.TenantDomain = c.GetString("tenant_domain")
+
+	pkgctx.ToGinContext(c, rc)
+	c.Set("mfa_verified", s.MFAVerified)
+	c.Set("session_id", s.ID)
+	c.Set("session", s)
+	c.Request = c.Request.WithContext(pkgctx.WithRequestContext(c.Request.Context(), rc))
+}
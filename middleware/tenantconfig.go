@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/config"
+	"github.com/vhvplatform/go-shared/response"
+)
+
+// TenantConfig loads the effective configuration of the resolved tenant and
+// stores it in the request context (see config.TenantConfigFromContext) and
+// under the "tenant_config" gin key. This middleware should be used after
+// tenant resolution.
+func TenantConfig[T any](service *config.TenantService[T]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.GetString("tenant_id")
+		if tenantID == "" {
+			response.BadRequest(c, "Tenant could not be resolved")
+			c.Abort()
+			return
+		}
+
+		tenantConfig, err := service.Get(c.Request.Context(), tenantID)
+		if err != nil {
+			response.InternalServerError(c, "Failed to load tenant configuration")
+			c.Abort()
+			return
+		}
+
+		c.Set("tenant_config", tenantConfig)
+		c.Request = c.Request.WithContext(config.WithTenantConfig(c.Request.Context(), tenantConfig))
+		c.Next()
+	}
+}
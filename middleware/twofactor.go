@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/auth"
+	"github.com/vhvplatform/go-shared/response"
+)
+
+// LoginConfigLookup resolves the tenant login configuration for a request
+type LoginConfigLookup func(c *gin.Context) (*auth.TenantLoginConfig, error)
+
+// RequireTwoFactor blocks access until the JWT carries a 2FA-verified claim
+// This middleware should be used after Auth
+func RequireTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("mfa_verified") {
+			response.Error(c, http.StatusForbidden, "MFA_REQUIRED", "Two-factor authentication required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireTwoFactorForTenant enforces RequireTwoFactor only for tenants whose
+// login config has Require2FA enabled
+func RequireTwoFactorForTenant(lookup LoginConfigLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		config, err := lookup(c)
+		if err != nil {
+			response.InternalServerError(c, "Failed to load tenant login configuration")
+			c.Abort()
+			return
+		}
+
+		if config != nil && config.Require2FA && !c.GetBool("mfa_verified") {
+			response.Error(c, http.StatusForbidden, "MFA_REQUIRED", "Two-factor authentication required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,335 @@
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vhvplatform/go-shared/audit"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxAuditAttempts bounds the retries of an audited single-document update
+// whose document keeps changing between being loaded and written
+const maxAuditAttempts = 5
+
+// errAuditConflict is returned when an audited update could not be applied
+// to an unchanged document within maxAuditAttempts
+var errAuditConflict = errors.New("document changed concurrently during audited update")
+
+// auditedUpdateOne updates a single document and records its exact states
+// before and after the write with the auditor. The document is loaded first
+// and the update is applied only if it is still unchanged, retrying
+// otherwise, so no concurrent write falls between the two states. An upsert
+// is recorded as a create. Without an auditor the update runs as is.
+func auditedUpdateOne(ctx context.Context, auditor *audit.Auditor, collection *mongo.Collection, filter bson.M, update interface{}, op audit.Operation, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if auditor == nil {
+		return collection.UpdateOne(ctx, filter, update, opts...)
+	}
+
+	for attempt := 0; attempt < maxAuditAttempts; attempt++ {
+		var before bson.Raw
+		err := collection.FindOne(ctx, filter).Decode(&before)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Nothing to change; an upsert inserts a new document
+			result, err := collection.UpdateOne(ctx, filter, update, opts...)
+			if err != nil || result.UpsertedID == nil {
+				return result, err
+			}
+			return result, auditInsert(ctx, auditor, collection, result.UpsertedID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load documents for audit: %w", err)
+		}
+
+		var after bson.Raw
+		err = collection.FindOneAndUpdate(ctx, unchangedFilter(before), update, findOneAndUpdateOptions(opts)).Decode(&after)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result := &mongo.UpdateResult{MatchedCount: 1}
+		if !bytes.Equal(before, after) {
+			result.ModifiedCount = 1
+		}
+		change := audit.Change{Operation: op}
+		if change.Before, err = rawToMap(before); err != nil {
+			return result, err
+		}
+		if change.After, err = rawToMap(after); err != nil {
+			return result, err
+		}
+		return result, auditor.Record(ctx, collection.Name(), change)
+	}
+	return nil, errAuditConflict
+}
+
+// auditedDeleteOne deletes a single document with FindOneAndDelete and
+// records the exact document deleted. Without an auditor the delete runs as is.
+func auditedDeleteOne(ctx context.Context, auditor *audit.Auditor, collection *mongo.Collection, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	if auditor == nil {
+		return collection.DeleteOne(ctx, filter, opts...)
+	}
+
+	var before bson.M
+	err := collection.FindOneAndDelete(ctx, filter, findOneAndDeleteOptions(opts)).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &mongo.DeleteResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &mongo.DeleteResult{DeletedCount: 1}
+	return result, auditor.Record(ctx, collection.Name(), audit.Change{Operation: audit.OperationDelete, Before: before})
+}
+
+// auditBatchSize bounds the documents loaded, written and recorded at once
+// by auditedMany, keeping memory and the size of the $in filter bounded
+const auditBatchSize = 500
+
+// auditedMany runs a write on all documents matching filter and records
+// their states before and after the write with the auditor. The matches are
+// read in _id order and processed in batches of auditBatchSize: each batch
+// is written with the filter restricted to the documents loaded for it, so
+// exactly the audited documents are changed, and is recorded separately.
+// The operation is therefore not atomic: when a batch fails, earlier
+// batches stay applied and recorded, and the results accumulated so far are
+// returned together with the error. Without an auditor the write runs with
+// filter as is.
+func auditedMany[R any](ctx context.Context, auditor *audit.Auditor, collection *mongo.Collection, filter bson.M, op audit.Operation, write func(filter bson.M) (R, error)) (R, error) {
+	if auditor == nil {
+		return write(filter)
+	}
+
+	var total R
+	cursor, err := collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetBatchSize(auditBatchSize))
+	if err != nil {
+		return total, fmt.Errorf("failed to load documents for audit: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	processed := false
+	batch := make([]bson.M, 0, auditBatchSize)
+	for {
+		more := cursor.Next(ctx)
+		if more {
+			var doc bson.M
+			if err := cursor.Decode(&doc); err != nil {
+				return total, fmt.Errorf("failed to load documents for audit: %w", err)
+			}
+			batch = append(batch, doc)
+		}
+		// Run an empty batch only when nothing matched, so an upsert still applies
+		if len(batch) == auditBatchSize || (!more && (len(batch) > 0 || !processed)) {
+			result, err := auditBatch(ctx, auditor, collection, filter, op, batch, write)
+			total = addResult(total, result)
+			if err != nil {
+				return total, err
+			}
+			processed = true
+			batch = batch[:0]
+		}
+		if !more {
+			break
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return total, fmt.Errorf("failed to load documents for audit: %w", err)
+	}
+	return total, nil
+}
+
+// auditBatch writes one batch of auditedMany and records its changes
+func auditBatch[R any](ctx context.Context, auditor *audit.Auditor, collection *mongo.Collection, filter bson.M, op audit.Operation, before []bson.M, write func(filter bson.M) (R, error)) (R, error) {
+	ids := make(bson.A, len(before))
+	for i, doc := range before {
+		ids[i] = doc["_id"]
+	}
+	result, err := write(restrictToIDs(filter, ids))
+	if err != nil {
+		return result, err
+	}
+
+	if update, ok := any(result).(*mongo.UpdateResult); ok && update != nil && update.UpsertedID != nil {
+		if err := auditInsert(ctx, auditor, collection, update.UpsertedID); err != nil {
+			return result, err
+		}
+	}
+	if len(before) == 0 {
+		return result, nil
+	}
+
+	var after map[string]bson.M
+	if op != audit.OperationDelete {
+		docs, err := findForAudit(ctx, collection, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return result, err
+		}
+		after = make(map[string]bson.M, len(docs))
+		for _, doc := range docs {
+			after[audit.DocumentID(doc["_id"])] = doc
+		}
+	}
+
+	changes := make([]audit.Change, 0, len(before))
+	for _, doc := range before {
+		change := audit.Change{Operation: op, Before: doc}
+		if op != audit.OperationDelete {
+			next, ok := after[audit.DocumentID(doc["_id"])]
+			if !ok {
+				// Removed by a concurrent write
+				continue
+			}
+			change.After = next
+		}
+		changes = append(changes, change)
+	}
+	return result, auditor.Record(ctx, collection.Name(), changes...)
+}
+
+// addResult adds the counts of a batch result to the running total of
+// auditedMany; a nil result leaves the total unchanged
+func addResult[R any](total, next R) R {
+	switch n := any(next).(type) {
+	case *mongo.UpdateResult:
+		t, _ := any(total).(*mongo.UpdateResult)
+		if n == nil {
+			return total
+		}
+		if t == nil {
+			t = &mongo.UpdateResult{}
+		}
+		t.MatchedCount += n.MatchedCount
+		t.ModifiedCount += n.ModifiedCount
+		t.UpsertedCount += n.UpsertedCount
+		if t.UpsertedID == nil {
+			t.UpsertedID = n.UpsertedID
+		}
+		return any(t).(R)
+	case *mongo.DeleteResult:
+		t, _ := any(total).(*mongo.DeleteResult)
+		if n == nil {
+			return total
+		}
+		if t == nil {
+			t = &mongo.DeleteResult{}
+		}
+		t.DeletedCount += n.DeletedCount
+		return any(t).(R)
+	}
+	return next
+}
+
+// unchangedFilter matches the document only while it is identical to before
+func unchangedFilter(before bson.Raw) bson.M {
+	return bson.M{
+		"_id":   before.Lookup("_id"),
+		"$expr": bson.M{"$eq": bson.A{"$$ROOT", bson.M{"$literal": before}}},
+	}
+}
+
+// restrictToIDs limits filter to the documents with the given IDs
+func restrictToIDs(filter bson.M, ids bson.A) bson.M {
+	return bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$in": ids}}}}
+}
+
+// findOneAndUpdateOptions converts update options for the compare-and-swap
+// write of auditedUpdateOne. Upsert is left out, since the compare-and-swap
+// filter must never insert.
+func findOneAndUpdateOptions(opts []*options.UpdateOptions) *options.FindOneAndUpdateOptions {
+	converted := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.ArrayFilters != nil {
+			converted.SetArrayFilters(*opt.ArrayFilters)
+		}
+		if opt.BypassDocumentValidation != nil {
+			converted.SetBypassDocumentValidation(*opt.BypassDocumentValidation)
+		}
+		if opt.Collation != nil {
+			converted.SetCollation(opt.Collation)
+		}
+		if opt.Comment != nil {
+			converted.SetComment(opt.Comment)
+		}
+		if opt.Hint != nil {
+			converted.SetHint(opt.Hint)
+		}
+		if opt.Let != nil {
+			converted.SetLet(opt.Let)
+		}
+	}
+	return converted
+}
+
+func findOneAndDeleteOptions(opts []*options.DeleteOptions) *options.FindOneAndDeleteOptions {
+	converted := options.FindOneAndDelete()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Collation != nil {
+			converted.SetCollation(opt.Collation)
+		}
+		if opt.Comment != nil {
+			converted.SetComment(opt.Comment)
+		}
+		if opt.Hint != nil {
+			converted.SetHint(opt.Hint)
+		}
+		if opt.Let != nil {
+			converted.SetLet(opt.Let)
+		}
+	}
+	return converted
+}
+
+func rawToMap(raw bson.Raw) (bson.M, error) {
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document for audit: %w", err)
+	}
+	return doc, nil
+}
+
+// auditInsert records the creation of the documents with the given IDs
+func auditInsert(ctx context.Context, auditor *audit.Auditor, collection *mongo.Collection, ids ...interface{}) error {
+	if auditor == nil || len(ids) == 0 {
+		return nil
+	}
+
+	docs, err := findForAudit(ctx, collection, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	changes := make([]audit.Change, len(docs))
+	for i, doc := range docs {
+		changes[i] = audit.Change{Operation: audit.OperationCreate, After: doc}
+	}
+	return auditor.Record(ctx, collection.Name(), changes...)
+}
+
+// findForAudit loads the current state of the documents matching filter
+func findForAudit(ctx context.Context, collection *mongo.Collection, filter bson.M) ([]bson.M, error) {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load documents for audit: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to load documents for audit: %w", err)
+	}
+	return docs, nil
+}
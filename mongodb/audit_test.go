@@ -0,0 +1,68 @@
+package mongodb
+
+import (
+	"bytes"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestUnchangedFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+	before, err := bson.Marshal(bson.D{{Key: "_id", Value: id}, {Key: "status", Value: "open"}})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	filter := unchangedFilter(before)
+	if filter["_id"].(bson.RawValue).ObjectID() != id {
+		t.Errorf("expected the filter to select the loaded document, got %v", filter["_id"])
+	}
+	expr := filter["$expr"].(bson.M)["$eq"].(bson.A)
+	if expr[0] != "$$ROOT" || !bytes.Equal(expr[1].(bson.M)["$literal"].(bson.Raw), before) {
+		t.Errorf("expected the filter to compare the whole document, got %v", expr)
+	}
+}
+
+func TestRestrictToIDs(t *testing.T) {
+	ids := bson.A{primitive.NewObjectID()}
+	filter := restrictToIDs(bson.M{"_id": "x", "status": "open"}, ids)
+
+	and := filter["$and"].(bson.A)
+	if len(filter) != 1 || and[0].(bson.M)["_id"] != "x" || and[1].(bson.M)["_id"].(bson.M)["$in"].(bson.A)[0] != ids[0] {
+		t.Errorf("expected the original filter and the IDs to both apply, got %v", filter)
+	}
+}
+
+func TestAddResult(t *testing.T) {
+	var updated *mongo.UpdateResult
+	updated = addResult(updated, &mongo.UpdateResult{MatchedCount: 500, ModifiedCount: 499})
+	updated = addResult(updated, nil)
+	updated = addResult(updated, &mongo.UpdateResult{MatchedCount: 2, ModifiedCount: 2})
+	if updated.MatchedCount != 502 || updated.ModifiedCount != 501 {
+		t.Errorf("expected batch update counts to add up, got %+v", updated)
+	}
+
+	var deleted *mongo.DeleteResult
+	deleted = addResult(deleted, &mongo.DeleteResult{DeletedCount: 500})
+	deleted = addResult(deleted, &mongo.DeleteResult{DeletedCount: 3})
+	if deleted.DeletedCount != 503 {
+		t.Errorf("expected batch delete counts to add up, got %+v", deleted)
+	}
+}
+
+func TestFindOneAndUpdateOptions(t *testing.T) {
+	converted := findOneAndUpdateOptions([]*options.UpdateOptions{
+		options.Update().SetUpsert(true).SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"x": 1}}}),
+		nil,
+	})
+	if converted.Upsert != nil {
+		t.Error("expected upsert to be dropped from the compare-and-swap write")
+	}
+	if converted.ArrayFilters == nil || *converted.ReturnDocument != options.After {
+		t.Errorf("unexpected options: %+v", converted)
+	}
+}
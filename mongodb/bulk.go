@@ -0,0 +1,412 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrBulkWriterClosed is returned when adding operations to a closed BulkWriter
+	ErrBulkWriterClosed = errors.New("bulk writer is closed")
+	// ErrBulkWriteFailed is returned by BulkWriter.Close when some operations failed
+	ErrBulkWriteFailed = errors.New("bulk write failed")
+	// ErrBulkWriterUnaudited is returned when creating a bulk writer for a
+	// repository with an auditor without setting AllowUnaudited
+	ErrBulkWriterUnaudited = errors.New("bulk writes are not audited")
+)
+
+// BulkWriterConfig configures a BulkWriter
+type BulkWriterConfig struct {
+	Collection *mongo.Collection
+	BatchSize  int // default: 1000
+	// Ordered stops each batch at its first failure; ordered batches are
+	// written one at a time
+	Ordered     bool
+	Concurrency int    // batches written in parallel (default: 1)
+	TenantID    string // set on inserted documents and added to every filter
+	SoftDelete  bool   // deletes set deleted_at and filters skip deleted documents
+	Versioning  bool   // inserts start at version 1 and updates increment it
+	// AllowUnaudited allows creating a writer for a repository with an
+	// auditor; its writes bypass the audit trail
+	AllowUnaudited bool
+}
+
+// BulkFailure describes an operation that was not applied
+type BulkFailure struct {
+	Index   int    `json:"index"` // position of the operation in the order it was added
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// BulkResult summarizes the writes of a BulkWriter
+type BulkResult struct {
+	Operations int           `json:"operations"`
+	Inserted   int64         `json:"inserted"`
+	Matched    int64         `json:"matched"`
+	Modified   int64         `json:"modified"` // includes soft deletes
+	Upserted   int64         `json:"upserted"`
+	Deleted    int64         `json:"deleted"`
+	Failures   []BulkFailure `json:"failures,omitempty"` // sorted by index
+}
+
+// BulkWriter accumulates insert, update, upsert and delete operations and
+// writes them in batches. When all concurrent batches are in flight, adding
+// an operation that completes a batch blocks until one finishes. Failed
+// operations do not stop the writer; they are reported by Close. Bulk writes
+// are not recorded by an auditor (see AllowUnaudited).
+//
+// A BulkWriter is safe for concurrent use.
+type BulkWriter struct {
+	collection *mongo.Collection
+	batchSize  int
+	ordered    bool
+	tenantID   string
+	softDelete bool
+	versioning bool
+	now        func() time.Time
+
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	pending bulkBatch
+	next    int
+	closed  bool
+
+	resultMu  sync.Mutex
+	result    BulkResult
+	batchErrs []error
+}
+
+type bulkBatch struct {
+	models  []mongo.WriteModel
+	indexes []int
+}
+
+// NewBulkWriter creates a bulk writer
+func NewBulkWriter(config BulkWriterConfig) *BulkWriter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1000
+	}
+	if config.Concurrency <= 0 || config.Ordered {
+		config.Concurrency = 1
+	}
+	return &BulkWriter{
+		collection: config.Collection,
+		batchSize:  config.BatchSize,
+		ordered:    config.Ordered,
+		tenantID:   config.TenantID,
+		softDelete: config.SoftDelete,
+		versioning: config.Versioning,
+		now:        time.Now,
+		slots:      make(chan struct{}, config.Concurrency),
+	}
+}
+
+// BulkWriter creates a bulk writer for the repository's collection with its
+// soft delete and versioning settings. Bulk writes are not audited, so for a
+// repository with an auditor it returns ErrBulkWriterUnaudited unless
+// config.AllowUnaudited is set.
+func (r *BaseRepository) BulkWriter(config BulkWriterConfig) (*BulkWriter, error) {
+	if r.auditor != nil && !config.AllowUnaudited {
+		return nil, ErrBulkWriterUnaudited
+	}
+	config.Collection = r.collection
+	config.SoftDelete = r.softDelete
+	config.Versioning = r.versioning
+	return NewBulkWriter(config), nil
+}
+
+// BulkWriter creates a bulk writer scoped to the repository's tenant. Like
+// BaseRepository.BulkWriter, it requires config.AllowUnaudited when the
+// repository has an auditor.
+func (tr *TenantRepository) BulkWriter(config BulkWriterConfig) (*BulkWriter, error) {
+	if tr.auditor != nil && !config.AllowUnaudited {
+		return nil, ErrBulkWriterUnaudited
+	}
+	config.Collection = tr.collection
+	config.TenantID = tr.tenantID
+	return NewBulkWriter(config), nil
+}
+
+// Insert adds an insert, setting timestamps, tenant and version on the document
+func (w *BulkWriter) Insert(ctx context.Context, document interface{}) error {
+	return w.add(ctx, mongo.NewInsertOneModel().SetDocument(w.prepareInsert(document)))
+}
+
+// Update adds an update of the first document matching the filter
+func (w *BulkWriter) Update(ctx context.Context, filter, update bson.M) error {
+	prepared, err := w.prepareUpdate(update, false)
+	if err != nil {
+		return err
+	}
+	return w.add(ctx, mongo.NewUpdateOneModel().
+		SetFilter(w.scopedFilter(filter)).
+		SetUpdate(prepared))
+}
+
+// Upsert adds an update of the first document matching the filter, inserting
+// one when none matches. Equality conditions of the filter (including the
+// tenant) are copied to inserted documents, and created_at is set on insert.
+func (w *BulkWriter) Upsert(ctx context.Context, filter, update bson.M) error {
+	prepared, err := w.prepareUpdate(update, true)
+	if err != nil {
+		return err
+	}
+	return w.add(ctx, mongo.NewUpdateOneModel().
+		SetFilter(w.scopedFilter(filter)).
+		SetUpdate(prepared).
+		SetUpsert(true))
+}
+
+// Delete adds a delete (soft delete if enabled) of the first document matching the filter
+func (w *BulkWriter) Delete(ctx context.Context, filter bson.M) error {
+	filter = w.scopedFilter(filter)
+	if w.softDelete {
+		now := w.now()
+		return w.add(ctx, mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{"$set": bson.M{"deleted_at": now, "updated_at": now}}))
+	}
+	return w.add(ctx, mongo.NewDeleteOneModel().SetFilter(filter))
+}
+
+// Flush writes the pending operations and waits for all batches in flight
+func (w *BulkWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	batch := w.takePending()
+	w.mu.Unlock()
+
+	var err error
+	if len(batch.models) > 0 {
+		err = w.dispatch(ctx, batch)
+	}
+	w.wg.Wait()
+	return err
+}
+
+// Close flushes the writer and returns the result. The error wraps
+// ErrBulkWriteFailed when any operation failed, together with errors that
+// failed whole batches (e.g. network errors); the result is returned either way.
+func (w *BulkWriter) Close(ctx context.Context) (*BulkResult, error) {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+
+	flushErr := w.Flush(ctx)
+
+	w.resultMu.Lock()
+	defer w.resultMu.Unlock()
+	result := w.result
+	result.Failures = append([]BulkFailure(nil), w.result.Failures...)
+	sort.Slice(result.Failures, func(i, j int) bool {
+		return result.Failures[i].Index < result.Failures[j].Index
+	})
+
+	if len(result.Failures) == 0 && flushErr == nil && len(w.batchErrs) == 0 {
+		return &result, nil
+	}
+	errs := []error{fmt.Errorf("%w: %d of %d operations failed", ErrBulkWriteFailed, len(result.Failures), result.Operations)}
+	if flushErr != nil {
+		errs = append(errs, flushErr)
+	}
+	errs = append(errs, w.batchErrs...)
+	return &result, errors.Join(errs...)
+}
+
+func (w *BulkWriter) add(ctx context.Context, model mongo.WriteModel) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrBulkWriterClosed
+	}
+	w.pending.models = append(w.pending.models, model)
+	w.pending.indexes = append(w.pending.indexes, w.next)
+	w.next++
+	var batch bulkBatch
+	if len(w.pending.models) >= w.batchSize {
+		batch = w.takePending()
+	}
+	w.mu.Unlock()
+
+	if len(batch.models) == 0 {
+		return nil
+	}
+	return w.dispatch(ctx, batch)
+}
+
+// takePending removes the pending batch; callers must hold w.mu
+func (w *BulkWriter) takePending() bulkBatch {
+	batch := w.pending
+	w.pending = bulkBatch{}
+	return batch
+}
+
+// dispatch writes a batch in the background once a slot is free
+func (w *BulkWriter) dispatch(ctx context.Context, batch bulkBatch) error {
+	select {
+	case w.slots <- struct{}{}:
+	case <-ctx.Done():
+		w.record(batch, nil, ctx.Err())
+		return ctx.Err()
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.slots
+			w.wg.Done()
+		}()
+		opts := options.BulkWrite().SetOrdered(w.ordered)
+		result, err := w.collection.BulkWrite(ctx, batch.models, opts)
+		w.record(batch, result, err)
+	}()
+	return nil
+}
+
+func (w *BulkWriter) record(batch bulkBatch, result *mongo.BulkWriteResult, err error) {
+	w.resultMu.Lock()
+	defer w.resultMu.Unlock()
+
+	w.result.Operations += len(batch.models)
+	if result != nil {
+		w.result.Inserted += result.InsertedCount
+		w.result.Matched += result.MatchedCount
+		w.result.Modified += result.ModifiedCount
+		w.result.Upserted += result.UpsertedCount
+		w.result.Deleted += result.DeletedCount
+	}
+	w.result.Failures = append(w.result.Failures, batchFailures(batch.indexes, err, w.ordered)...)
+
+	var bulkErr mongo.BulkWriteException
+	switch {
+	case err == nil:
+	case errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0:
+		if bulkErr.WriteConcernError != nil {
+			w.batchErrs = append(w.batchErrs, bulkErr.WriteConcernError)
+		}
+	default:
+		w.batchErrs = append(w.batchErrs, err)
+	}
+}
+
+// batchFailures maps the errors of a batch to the operations that were not
+// applied. Operations after the first failure of an ordered batch are
+// skipped by the server, and errors without write errors fail the whole batch.
+func batchFailures(indexes []int, err error, ordered bool) []BulkFailure {
+	if err == nil {
+		return nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+		failures := make([]BulkFailure, len(indexes))
+		for i, index := range indexes {
+			failures[i] = BulkFailure{Index: index, Message: err.Error()}
+		}
+		return failures
+	}
+
+	failures := make([]BulkFailure, 0, len(bulkErr.WriteErrors))
+	last := 0
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Index < 0 || writeErr.Index >= len(indexes) {
+			continue
+		}
+		failures = append(failures, BulkFailure{Index: indexes[writeErr.Index], Code: writeErr.Code, Message: writeErr.Message})
+		if writeErr.Index > last {
+			last = writeErr.Index
+		}
+	}
+	if ordered {
+		for i := last + 1; i < len(indexes); i++ {
+			failures = append(failures, BulkFailure{Index: indexes[i], Message: "skipped after an earlier failure in the ordered batch"})
+		}
+	}
+	return failures
+}
+
+func (w *BulkWriter) prepareInsert(document interface{}) interface{} {
+	now := w.now()
+	if model, ok := document.(interface {
+		SetCreatedAt(time.Time)
+		SetUpdatedAt(time.Time)
+	}); ok {
+		model.SetCreatedAt(now)
+		model.SetUpdatedAt(now)
+	}
+	if w.tenantID != "" {
+		if tenantAware, ok := document.(TenantAware); ok {
+			tenantAware.SetTenantID(w.tenantID)
+		}
+	}
+	if w.versioning {
+		if model, ok := document.(Versioned); ok {
+			model.SetVersion(1)
+		}
+	}
+
+	var doc map[string]interface{}
+	switch d := document.(type) {
+	case bson.M:
+		doc = d
+	case map[string]interface{}:
+		doc = d
+	}
+	if doc != nil {
+		doc["created_at"] = now
+		doc["updated_at"] = now
+		if w.tenantID != "" {
+			doc["tenant_id"] = w.tenantID
+		}
+		if w.versioning {
+			doc["version"] = int64(1)
+		}
+	}
+	return document
+}
+
+func (w *BulkWriter) scopedFilter(filter bson.M) bson.M {
+	scoped := copyFilter(filter)
+	if w.tenantID != "" {
+		scoped["tenant_id"] = w.tenantID
+	}
+	if w.softDelete {
+		scoped["deleted_at"] = bson.M{"$exists": false}
+	}
+	return scoped
+}
+
+func (w *BulkWriter) prepareUpdate(update bson.M, upsert bool) (bson.M, error) {
+	now := w.now()
+	prepared, err := withUpdatedAt(update, now)
+	if err != nil {
+		return nil, err
+	}
+	if w.versioning {
+		if err := incVersion(prepared); err != nil {
+			return nil, err
+		}
+	}
+	if upsert {
+		if _, ok := prepared["$set"].(bson.M)["created_at"]; !ok {
+			setOnInsert, err := operatorFields(prepared, "$setOnInsert")
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := setOnInsert["created_at"]; !ok {
+				setOnInsert["created_at"] = now
+			}
+			prepared["$setOnInsert"] = setOnInsert
+		}
+	}
+	return prepared, nil
+}
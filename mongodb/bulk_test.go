@@ -0,0 +1,171 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vhvplatform/go-shared/audit"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestBatchFailures(t *testing.T) {
+	indexes := []int{10, 11, 12, 13}
+	writeErrs := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+		{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}},
+	}}
+
+	tests := []struct {
+		name     string
+		err      error
+		ordered  bool
+		expected []BulkFailure
+	}{
+		{"no error", nil, false, nil},
+		{
+			name: "unordered write error",
+			err:  writeErrs,
+			expected: []BulkFailure{
+				{Index: 11, Code: 11000, Message: "duplicate key"},
+			},
+		},
+		{
+			name:    "ordered batch skips the rest",
+			err:     writeErrs,
+			ordered: true,
+			expected: []BulkFailure{
+				{Index: 11, Code: 11000, Message: "duplicate key"},
+				{Index: 12, Message: "skipped after an earlier failure in the ordered batch"},
+				{Index: 13, Message: "skipped after an earlier failure in the ordered batch"},
+			},
+		},
+		{
+			name: "batch error fails every operation",
+			err:  errors.New("connection reset"),
+			expected: []BulkFailure{
+				{Index: 10, Message: "connection reset"},
+				{Index: 11, Message: "connection reset"},
+				{Index: 12, Message: "connection reset"},
+				{Index: 13, Message: "connection reset"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures := batchFailures(indexes, tt.err, tt.ordered)
+			if !reflect.DeepEqual(failures, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, failures)
+			}
+		})
+	}
+}
+
+func TestBulkWriter_PreparesOperations(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	w := NewBulkWriter(BulkWriterConfig{TenantID: "acme", SoftDelete: true, Versioning: true})
+	w.now = func() time.Time { return now }
+
+	order := &testOrder{TenantID: "globex"}
+	w.prepareInsert(order)
+	if order.TenantID != "acme" || order.CreatedAt != now || order.Version != 1 {
+		t.Errorf("unexpected inserted document: %+v", order)
+	}
+
+	filter := bson.M{"sku": "A-1"}
+	scoped := w.scopedFilter(filter)
+	if scoped["tenant_id"] != "acme" || scoped["deleted_at"] == nil || len(filter) != 1 {
+		t.Errorf("unexpected filter %v (caller's filter %v)", scoped, filter)
+	}
+
+	update, err := w.prepareUpdate(bson.M{"$set": bson.M{"qty": 5}}, true)
+	if err != nil {
+		t.Fatalf("failed to prepare update: %v", err)
+	}
+	expected := bson.M{
+		"$set":         bson.M{"qty": 5, "updated_at": now},
+		"$inc":         bson.M{"version": int64(1)},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	if !reflect.DeepEqual(update, expected) {
+		t.Errorf("expected %v, got %v", expected, update)
+	}
+}
+
+func TestBulkWriter_MergesSetOnInsert(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	w := NewBulkWriter(BulkWriterConfig{})
+	w.now = func() time.Time { return now }
+
+	for _, setOnInsert := range []interface{}{
+		map[string]interface{}{"source": "import"},
+		bson.D{{Key: "source", Value: "import"}},
+	} {
+		update, err := w.prepareUpdate(bson.M{"$set": bson.M{"qty": 5}, "$setOnInsert": setOnInsert}, true)
+		if err != nil {
+			t.Fatalf("failed to prepare update: %v", err)
+		}
+		if expected := (bson.M{"source": "import", "created_at": now}); !reflect.DeepEqual(update["$setOnInsert"], expected) {
+			t.Errorf("expected %v for %T, got %v", expected, setOnInsert, update["$setOnInsert"])
+		}
+	}
+	if _, err := w.prepareUpdate(bson.M{"$setOnInsert": &testOrder{}}, true); !errors.Is(err, ErrUnsupportedUpdate) {
+		t.Errorf("expected ErrUnsupportedUpdate, got %v", err)
+	}
+}
+
+func TestBulkWriter_AuditedRepository(t *testing.T) {
+	repo := NewBaseRepository(RepositoryConfig{
+		Collection: lazyClient(t, "mongodb://localhost:27017", "app").Collection("orders"),
+		Auditor:    audit.NewAuditor(audit.NewMemoryStore(), audit.Config{}),
+	})
+
+	if _, err := repo.BulkWriter(BulkWriterConfig{}); !errors.Is(err, ErrBulkWriterUnaudited) {
+		t.Errorf("expected ErrBulkWriterUnaudited, got %v", err)
+	}
+	if w, err := repo.BulkWriter(BulkWriterConfig{AllowUnaudited: true}); err != nil || w == nil {
+		t.Errorf("expected an explicit opt-out to create a writer, got %v", err)
+	}
+}
+
+func TestBulkWriter_Close(t *testing.T) {
+	ctx := context.Background()
+	w := NewBulkWriter(BulkWriterConfig{Ordered: true, Concurrency: 4})
+	if cap(w.slots) != 1 {
+		t.Errorf("expected ordered batches to be written one at a time, got %d slots", cap(w.slots))
+	}
+
+	result, err := w.Close(ctx)
+	if err != nil || result.Operations != 0 {
+		t.Fatalf("unexpected result %+v (%v)", result, err)
+	}
+	if err := w.Insert(ctx, bson.M{"sku": "A-1"}); !errors.Is(err, ErrBulkWriterClosed) {
+		t.Errorf("expected ErrBulkWriterClosed, got %v", err)
+	}
+}
+
+func TestBulkWriter_CanceledContextFailsBatch(t *testing.T) {
+	w := NewBulkWriter(BulkWriterConfig{BatchSize: 2})
+	w.slots <- struct{}{} // all slots busy
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.Insert(ctx, bson.M{"sku": "A-1"}); err != nil {
+		t.Fatalf("unexpected error before the batch is full: %v", err)
+	}
+	if err := w.Delete(ctx, bson.M{"sku": "A-2"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the batch dispatch to fail, got %v", err)
+	}
+	<-w.slots
+
+	result, err := w.Close(context.Background())
+	if !errors.Is(err, ErrBulkWriteFailed) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a bulk write error, got %v", err)
+	}
+	if result.Operations != 2 || len(result.Failures) != 2 || result.Failures[1].Index != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
@@ -0,0 +1,404 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/vhvplatform/go-shared/redis"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChangeOperation is the type of a change stream event
+type ChangeOperation string
+
+const (
+	ChangeInsert  ChangeOperation = "insert"
+	ChangeUpdate  ChangeOperation = "update"
+	ChangeReplace ChangeOperation = "replace"
+	ChangeDelete  ChangeOperation = "delete"
+)
+
+// ChangeEvent is a typed change stream event
+type ChangeEvent[T any] struct {
+	Token       bson.Raw        `json:"-"`
+	Operation   ChangeOperation `json:"operation"`
+	Database    string          `json:"database"`
+	Collection  string          `json:"collection"`
+	DocumentID  interface{}     `json:"document_id"`
+	TenantID    string          `json:"tenant_id,omitempty"`
+	ClusterTime time.Time       `json:"cluster_time"`
+	// Document is the document after the change; nil for deletes
+	Document *T `json:"document,omitempty"`
+	// Before is the document before the change, when pre-images are enabled on the collection
+	Before        *T       `json:"before,omitempty"`
+	UpdatedFields bson.M   `json:"updated_fields,omitempty"`
+	RemovedFields []string `json:"removed_fields,omitempty"`
+}
+
+// ChangeHandler handles change events
+type ChangeHandler[T any] func(ctx context.Context, event ChangeEvent[T]) error
+
+// SubscriptionConfig configures a change stream subscription
+type SubscriptionConfig struct {
+	// Name identifies the subscription; resume tokens are stored under it (required)
+	Name       string
+	Collection string // required
+	// TenantIDs restricts events to these tenants. Deletes only carry the
+	// tenant when it is part of the shard key or pre-images are enabled.
+	TenantIDs   []string
+	TenantField string            // default: "tenant_id"
+	Operations  []ChangeOperation // default: insert, update, replace and delete
+	Pipeline    mongo.Pipeline    // additional stages appended to the generated filter
+	// Tokens persists resume tokens; without it every Run starts at the current time
+	Tokens       ResumeTokenStore
+	PreImages    bool          // request pre-images (requires changeStreamPreAndPostImages on the collection)
+	MaxRetries   int           // handler retries per event (default: 3)
+	RetryBackoff time.Duration // first retry delay, doubled per retry (default: 1 second)
+	// OnError receives events whose handler still fails after the retries;
+	// the event is then skipped. Without it Run stops and returns the error,
+	// and the event is redelivered by the next Run.
+	OnError func(ctx context.Context, event bson.Raw, err error)
+}
+
+// Subscription dispatches change stream events of a collection to typed
+// handlers, persisting a resume token after every handled event so that a
+// restarted service resumes where it stopped
+type Subscription[T any] struct {
+	client   *Client
+	config   SubscriptionConfig
+	handlers []ChangeHandler[T]
+}
+
+// NewSubscription creates a change stream subscription on the client's database
+func NewSubscription[T any](client *Client, config SubscriptionConfig, handlers ...ChangeHandler[T]) (*Subscription[T], error) {
+	if config.Name == "" || config.Collection == "" {
+		return nil, errors.New("subscription name and collection are required")
+	}
+	if len(handlers) == 0 {
+		return nil, errors.New("at least one change handler is required")
+	}
+	if config.TenantField == "" {
+		config.TenantField = "tenant_id"
+	}
+	if len(config.Operations) == 0 {
+		config.Operations = []ChangeOperation{ChangeInsert, ChangeUpdate, ChangeReplace, ChangeDelete}
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 3
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Second
+	}
+
+	return &Subscription[T]{
+		client:   client,
+		config:   config,
+		handlers: handlers,
+	}, nil
+}
+
+// Run watches the collection and dispatches events until the context is
+// cancelled (returning nil) or the stream or a handler fails. Calling Run
+// again resumes after the last handled event.
+func (s *Subscription[T]) Run(ctx context.Context) error {
+	streamOptions := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if s.config.PreImages {
+		streamOptions.SetFullDocumentBeforeChange(options.WhenAvailable)
+	}
+	if s.config.Tokens != nil {
+		token, err := s.config.Tokens.Load(ctx, s.config.Name)
+		if err != nil {
+			return fmt.Errorf("failed to load resume token: %w", err)
+		}
+		if token != nil {
+			streamOptions.SetResumeAfter(token)
+		}
+	}
+
+	stream, err := s.client.Collection(s.config.Collection).Watch(ctx, s.pipeline(), streamOptions)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		if err := s.handle(ctx, stream.Current); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if s.config.Tokens != nil {
+			if err := s.config.Tokens.Save(ctx, s.config.Name, stream.ResumeToken()); err != nil {
+				return fmt.Errorf("failed to save resume token: %w", err)
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := stream.Err(); err != nil {
+		return fmt.Errorf("change stream failed: %w", err)
+	}
+	return nil
+}
+
+// pipeline builds the $match stage for the configured operations and tenants
+func (s *Subscription[T]) pipeline() mongo.Pipeline {
+	match := bson.D{{Key: "operationType", Value: bson.M{"$in": s.config.Operations}}}
+	if len(s.config.TenantIDs) > 0 {
+		tenants := bson.M{"$in": s.config.TenantIDs}
+		match = append(match, bson.E{Key: "$or", Value: bson.A{
+			bson.M{"fullDocument." + s.config.TenantField: tenants},
+			bson.M{"fullDocumentBeforeChange." + s.config.TenantField: tenants},
+			bson.M{"documentKey." + s.config.TenantField: tenants},
+		}})
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+	return append(pipeline, s.config.Pipeline...)
+}
+
+// handle decodes an event and dispatches it to every handler with retries
+func (s *Subscription[T]) handle(ctx context.Context, raw bson.Raw) error {
+	event, err := decodeChangeEvent[T](raw, s.config.TenantField)
+	if err == nil {
+		for _, handler := range s.handlers {
+			if err = s.retry(ctx, handler, event); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	if s.config.OnError != nil {
+		s.config.OnError(ctx, raw, err)
+		return nil
+	}
+	return err
+}
+
+// retry calls the handler until it succeeds, the retries are exhausted or the context is done
+func (s *Subscription[T]) retry(ctx context.Context, handler ChangeHandler[T], event ChangeEvent[T]) error {
+	backoff := s.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := handler(ctx, event)
+		if err == nil {
+			return nil
+		}
+		if attempt >= s.config.MaxRetries {
+			return fmt.Errorf("change handler failed after %d attempts: %w", attempt+1, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// rawChangeEvent is the change stream event layout
+type rawChangeEvent struct {
+	ID            bson.Raw `bson:"_id"`
+	OperationType string   `bson:"operationType"`
+	NS            struct {
+		DB   string `bson:"db"`
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey              bson.Raw            `bson:"documentKey"`
+	FullDocument             bson.Raw            `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.Raw            `bson:"fullDocumentBeforeChange"`
+	ClusterTime              primitive.Timestamp `bson:"clusterTime"`
+	UpdateDescription        *struct {
+		UpdatedFields bson.M   `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+func decodeChangeEvent[T any](raw bson.Raw, tenantField string) (ChangeEvent[T], error) {
+	var decoded rawChangeEvent
+	if err := bson.Unmarshal(raw, &decoded); err != nil {
+		return ChangeEvent[T]{}, fmt.Errorf("failed to decode change event: %w", err)
+	}
+
+	event := ChangeEvent[T]{
+		Token:       decoded.ID,
+		Operation:   ChangeOperation(decoded.OperationType),
+		Database:    decoded.NS.DB,
+		Collection:  decoded.NS.Coll,
+		ClusterTime: time.Unix(int64(decoded.ClusterTime.T), 0).UTC(),
+	}
+	if decoded.UpdateDescription != nil {
+		event.UpdatedFields = decoded.UpdateDescription.UpdatedFields
+		event.RemovedFields = decoded.UpdateDescription.RemovedFields
+	}
+	if len(decoded.DocumentKey) > 0 {
+		var key bson.M
+		if err := bson.Unmarshal(decoded.DocumentKey, &key); err != nil {
+			return ChangeEvent[T]{}, fmt.Errorf("failed to decode document key: %w", err)
+		}
+		event.DocumentID = key["_id"]
+	}
+
+	for _, doc := range []struct {
+		raw    bson.Raw
+		target **T
+	}{{decoded.FullDocument, &event.Document}, {decoded.FullDocumentBeforeChange, &event.Before}} {
+		if len(doc.raw) == 0 {
+			continue
+		}
+		value := new(T)
+		if err := bson.Unmarshal(doc.raw, value); err != nil {
+			return ChangeEvent[T]{}, fmt.Errorf("failed to decode changed document: %w", err)
+		}
+		*doc.target = value
+	}
+
+	for _, doc := range []bson.Raw{decoded.FullDocument, decoded.FullDocumentBeforeChange, decoded.DocumentKey} {
+		if len(doc) == 0 {
+			continue
+		}
+		if tenantID, ok := doc.Lookup(tenantField).StringValueOK(); ok {
+			event.TenantID = tenantID
+			break
+		}
+	}
+	return event, nil
+}
+
+// Publisher publishes messages, e.g. *rabbitmq.Client
+type Publisher interface {
+	Publish(ctx context.Context, routingKey string, body []byte) error
+}
+
+// ForwardChanges returns a handler publishing events as JSON with the routing
+// key "<prefix>.<collection>.<operation>", e.g. "mongo.orders.insert"
+func ForwardChanges[T any](publisher Publisher, prefix string) ChangeHandler[T] {
+	return func(ctx context.Context, event ChangeEvent[T]) error {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode change event: %w", err)
+		}
+		routingKey := event.Collection + "." + string(event.Operation)
+		if prefix != "" {
+			routingKey = prefix + "." + routingKey
+		}
+		return publisher.Publish(ctx, routingKey, body)
+	}
+}
+
+// ResumeTokenStore persists change stream resume tokens by subscription name
+type ResumeTokenStore interface {
+	// Load returns the saved token, or nil when there is none
+	Load(ctx context.Context, name string) (bson.Raw, error)
+	// Save stores the token
+	Save(ctx context.Context, name string, token bson.Raw) error
+}
+
+// MongoResumeTokenStore stores resume tokens in a MongoDB collection
+type MongoResumeTokenStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoResumeTokenStore creates a MongoDB-backed resume token store
+func NewMongoResumeTokenStore(collection *mongo.Collection) *MongoResumeTokenStore {
+	return &MongoResumeTokenStore{collection: collection}
+}
+
+// Load returns the saved token
+func (s *MongoResumeTokenStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	var doc struct {
+		Token bson.Raw `bson:"token"`
+	}
+	if err := s.collection.FindOne(ctx, bson.M{"_id": name}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return doc.Token, nil
+}
+
+// Save stores the token
+func (s *MongoResumeTokenStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": name},
+		bson.M{"$set": bson.M{"token": token, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// RedisResumeTokenStore stores resume tokens in Redis
+type RedisResumeTokenStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisResumeTokenStore creates a Redis-backed resume token store
+func NewRedisResumeTokenStore(client *redis.Client, prefix string) *RedisResumeTokenStore {
+	if prefix == "" {
+		prefix = "changestream:token"
+	}
+	return &RedisResumeTokenStore{client: client, prefix: prefix}
+}
+
+// Load returns the saved token
+func (s *RedisResumeTokenStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	data, err := s.client.Client.Get(ctx, s.prefix+":"+name).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bson.Raw(data), nil
+}
+
+// Save stores the token
+func (s *RedisResumeTokenStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	return s.client.Client.Set(ctx, s.prefix+":"+name, []byte(token), 0).Err()
+}
+
+// MemoryResumeTokenStore is an in-memory ResumeTokenStore, intended for tests
+type MemoryResumeTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]bson.Raw
+}
+
+// NewMemoryResumeTokenStore creates an in-memory resume token store
+func NewMemoryResumeTokenStore() *MemoryResumeTokenStore {
+	return &MemoryResumeTokenStore{tokens: make(map[string]bson.Raw)}
+}
+
+// Load returns the saved token
+func (s *MemoryResumeTokenStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tokens[name], nil
+}
+
+// Save stores the token
+func (s *MemoryResumeTokenStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[name] = append(bson.Raw(nil), token...)
+	return nil
+}
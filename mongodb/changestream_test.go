@@ -0,0 +1,171 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type changeOrder struct {
+	ID       string `bson:"_id" json:"id"`
+	TenantID string `bson:"tenant_id" json:"tenant_id"`
+	Status   string `bson:"status" json:"status"`
+}
+
+func changeEventRaw(t *testing.T, event bson.M) bson.Raw {
+	t.Helper()
+	data, err := bson.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+	return data
+}
+
+func TestDecodeChangeEvent(t *testing.T) {
+	raw := changeEventRaw(t, bson.M{
+		"_id":           bson.M{"_data": "token-1"},
+		"operationType": "update",
+		"ns":            bson.M{"db": "app", "coll": "orders"},
+		"documentKey":   bson.M{"_id": "o1"},
+		"fullDocument":  bson.M{"_id": "o1", "tenant_id": "acme", "status": "paid"},
+		"clusterTime":   primitive.Timestamp{T: 1700000000},
+		"updateDescription": bson.M{
+			"updatedFields": bson.M{"status": "paid"},
+			"removedFields": bson.A{"note"},
+		},
+	})
+
+	event, err := decodeChangeEvent[changeOrder](raw, "tenant_id")
+	if err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if event.Operation != ChangeUpdate || event.Collection != "orders" || event.DocumentID != "o1" || event.TenantID != "acme" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if event.Document == nil || event.Document.Status != "paid" || event.Before != nil {
+		t.Errorf("unexpected documents: %+v / %+v", event.Document, event.Before)
+	}
+	if event.UpdatedFields["status"] != "paid" || !reflect.DeepEqual(event.RemovedFields, []string{"note"}) {
+		t.Errorf("unexpected update description: %v %v", event.UpdatedFields, event.RemovedFields)
+	}
+	if !event.ClusterTime.Equal(time.Unix(1700000000, 0)) || event.Token.Lookup("_data").StringValue() != "token-1" {
+		t.Errorf("unexpected token or cluster time: %v %v", event.Token, event.ClusterTime)
+	}
+
+	// Deletes take the tenant from the shard key
+	deleted, err := decodeChangeEvent[changeOrder](changeEventRaw(t, bson.M{
+		"operationType": "delete",
+		"documentKey":   bson.M{"_id": "o1", "tenant_id": "acme"},
+	}), "tenant_id")
+	if err != nil || deleted.Document != nil || deleted.TenantID != "acme" {
+		t.Errorf("unexpected delete event: %+v (%v)", deleted, err)
+	}
+}
+
+func TestSubscription_Pipeline(t *testing.T) {
+	handler := func(ctx context.Context, event ChangeEvent[changeOrder]) error { return nil }
+	extra := bson.D{{Key: "$project", Value: bson.M{"updateDescription": 0}}}
+	sub, err := NewSubscription[changeOrder](nil, SubscriptionConfig{
+		Name:       "billing",
+		Collection: "orders",
+		TenantIDs:  []string{"acme"},
+		Operations: []ChangeOperation{ChangeInsert},
+		Pipeline:   mongo.Pipeline{extra},
+	}, handler)
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	tenants := bson.M{"$in": []string{"acme"}}
+	want := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "operationType", Value: bson.M{"$in": []ChangeOperation{ChangeInsert}}},
+			{Key: "$or", Value: bson.A{
+				bson.M{"fullDocument.tenant_id": tenants},
+				bson.M{"fullDocumentBeforeChange.tenant_id": tenants},
+				bson.M{"documentKey.tenant_id": tenants},
+			}},
+		}}},
+		extra,
+	}
+	if got := sub.pipeline(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSubscription_HandleRetries(t *testing.T) {
+	raw := changeEventRaw(t, bson.M{"operationType": "insert", "documentKey": bson.M{"_id": "o1"}})
+	failure := errors.New("downstream unavailable")
+
+	tests := []struct {
+		name      string
+		failures  int
+		onError   bool
+		wantErr   bool
+		wantCalls int
+		reported  bool
+	}{
+		{"succeeds after retries", 2, false, false, 3, false},
+		{"fails after retries", 10, false, true, 4, false},
+		{"reported and skipped", 10, true, false, 4, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			reported := false
+			config := SubscriptionConfig{Name: "billing", Collection: "orders", RetryBackoff: time.Millisecond}
+			if tt.onError {
+				config.OnError = func(ctx context.Context, event bson.Raw, err error) { reported = errors.Is(err, failure) }
+			}
+			sub, _ := NewSubscription(nil, config, func(ctx context.Context, event ChangeEvent[changeOrder]) error {
+				calls++
+				if calls <= tt.failures {
+					return failure
+				}
+				return nil
+			})
+
+			err := sub.handle(context.Background(), raw)
+			if (err != nil) != tt.wantErr || calls != tt.wantCalls || reported != tt.reported {
+				t.Errorf("expected err=%v calls=%d reported=%v, got %v, %d, %v", tt.wantErr, tt.wantCalls, tt.reported, err, calls, reported)
+			}
+		})
+	}
+}
+
+type publisherFunc func(ctx context.Context, routingKey string, body []byte) error
+
+func (f publisherFunc) Publish(ctx context.Context, routingKey string, body []byte) error {
+	return f(ctx, routingKey, body)
+}
+
+func TestForwardChanges(t *testing.T) {
+	var routingKey string
+	var message map[string]interface{}
+	forward := ForwardChanges[changeOrder](publisherFunc(func(ctx context.Context, key string, body []byte) error {
+		routingKey = key
+		return json.Unmarshal(body, &message)
+	}), "mongo")
+
+	err := forward(context.Background(), ChangeEvent[changeOrder]{
+		Operation:  ChangeInsert,
+		Collection: "orders",
+		DocumentID: "o1",
+		TenantID:   "acme",
+		Document:   &changeOrder{ID: "o1", TenantID: "acme", Status: "new"},
+	})
+	if err != nil {
+		t.Fatalf("failed to forward event: %v", err)
+	}
+	if routingKey != "mongo.orders.insert" || message["tenant_id"] != "acme" || message["document"].(map[string]interface{})["status"] != "new" {
+		t.Errorf("unexpected message %q: %v", routingKey, message)
+	}
+}
@@ -0,0 +1,198 @@
+package mongodb
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrInvalidCursor is returned for malformed, tampered or mismatching pagination cursors
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+var (
+	cursorSecretMu sync.RWMutex
+	cursorSecret   = randomCursorSecret()
+)
+
+func randomCursorSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate cursor secret: %v", err))
+	}
+	return secret
+}
+
+// SetCursorSecret sets the default key used to sign pagination cursors. The
+// default is random per process, so services running several instances must
+// set a shared secret for cursors to be valid across instances.
+func SetCursorSecret(secret []byte) {
+	cursorSecretMu.Lock()
+	defer cursorSecretMu.Unlock()
+	cursorSecret = append([]byte(nil), secret...)
+}
+
+func defaultCursorSecret() []byte {
+	cursorSecretMu.RLock()
+	defer cursorSecretMu.RUnlock()
+	return cursorSecret
+}
+
+// cursorPayload is the signed content of a cursor: the direction, the sort
+// keys it was issued for and the sort values of the boundary document
+type cursorPayload struct {
+	Direction string          `bson:"d"`
+	Keys      []string        `bson:"k"`
+	Values    []bson.RawValue `bson:"v"`
+}
+
+func encodeCursor(secret []byte, payload cursorPayload) (string, error) {
+	data, err := bson.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(signCursor(secret, data)), nil
+}
+
+func decodeCursor(secret []byte, cursor string, sort bson.D) (*cursorPayload, error) {
+	encoded, signature, found := strings.Cut(cursor, ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, signCursor(secret, data)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := bson.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if payload.Direction != cursorNext && payload.Direction != cursorPrev {
+		return nil, ErrInvalidCursor
+	}
+	if len(payload.Keys) != len(sort) || len(payload.Values) != len(sort) {
+		return nil, fmt.Errorf("%w: cursor was issued for another sort order", ErrInvalidCursor)
+	}
+	for i, key := range payload.Keys {
+		if key != sort[i].Key {
+			return nil, fmt.Errorf("%w: cursor was issued for another sort order", ErrInvalidCursor)
+		}
+	}
+	return &payload, nil
+}
+
+func signCursor(secret, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)[:16]
+}
+
+// normalizeCursorSort returns the sort with _id appended as a unique tie-breaker
+func normalizeCursorSort(sort bson.D) bson.D {
+	normalized := make(bson.D, 0, len(sort)+1)
+	hasID := false
+	for _, field := range sort {
+		hasID = hasID || field.Key == "_id"
+		normalized = append(normalized, bson.E{Key: field.Key, Value: sortDirection(field.Value)})
+	}
+	if !hasID {
+		normalized = append(normalized, bson.E{Key: "_id", Value: 1})
+	}
+	return normalized
+}
+
+// sortDirection returns 1 for ascending and -1 for descending sort values
+func sortDirection(value interface{}) int {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return -1
+		}
+	case reflect.Float32, reflect.Float64:
+		if rv.Float() < 0 {
+			return -1
+		}
+	}
+	return 1
+}
+
+// reverseSort inverts every direction of a sort
+func reverseSort(sort bson.D) bson.D {
+	reversed := make(bson.D, len(sort))
+	for i, field := range sort {
+		reversed[i] = bson.E{Key: field.Key, Value: -sortDirection(field.Value)}
+	}
+	return reversed
+}
+
+// seekCondition matches documents strictly after the values in the given
+// sort order: (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with > replaced by
+// < for descending keys. Like MongoDB's sort, null and missing values are
+// equal to each other and lower than any other value, so "after null" means
+// any non-null value when ascending and nothing when descending, and null
+// values come after every other value when descending.
+func seekCondition(sort bson.D, values []bson.RawValue) bson.M {
+	or := make(bson.A, 0, len(sort))
+	for i, field := range sort {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[sort[j].Key] = values[j]
+		}
+		descending := sortDirection(field.Value) < 0
+		switch {
+		case isNullSortValue(values[i]) && descending:
+			continue
+		case isNullSortValue(values[i]):
+			clause[field.Key] = bson.M{"$ne": nil}
+		case descending:
+			clause["$or"] = bson.A{
+				bson.M{field.Key: bson.M{"$lt": values[i]}},
+				bson.M{field.Key: nil},
+			}
+		default:
+			clause[field.Key] = bson.M{"$gt": values[i]}
+		}
+		or = append(or, clause)
+	}
+	if len(or) == 0 {
+		// Nothing sorts after the values
+		return bson.M{"_id": bson.M{"$exists": false}}
+	}
+	return bson.M{"$or": or}
+}
+
+func isNullSortValue(value bson.RawValue) bool {
+	return value.Type == bson.TypeNull || value.Type == bson.TypeUndefined
+}
+
+// sortValues extracts the values of the sort keys (dotted paths allowed) from
+// a document. Missing fields are returned as null, which they sort as.
+func sortValues(doc bson.Raw, sort bson.D) []bson.RawValue {
+	values := make([]bson.RawValue, len(sort))
+	for i, field := range sort {
+		value, err := doc.LookupErr(strings.Split(field.Key, ".")...)
+		if err != nil {
+			value = bson.RawValue{Type: bson.TypeNull}
+		}
+		values[i] = value
+	}
+	return values
+}
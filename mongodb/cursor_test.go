@@ -0,0 +1,168 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func rawValues(t *testing.T, values ...interface{}) []bson.RawValue {
+	t.Helper()
+	doc := bson.D{}
+	for i, value := range values {
+		doc = append(doc, bson.E{Key: string(rune('a' + i)), Value: value})
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatalf("failed to marshal values: %v", err)
+	}
+	elements, _ := bson.Raw(data).Elements()
+	result := make([]bson.RawValue, len(elements))
+	for i, element := range elements {
+		result[i] = element.Value()
+	}
+	return result
+}
+
+func TestNormalizeCursorSort(t *testing.T) {
+	tests := []struct {
+		name string
+		sort bson.D
+		want bson.D
+	}{
+		{"empty", nil, bson.D{{Key: "_id", Value: 1}}},
+		{"appends id", bson.D{{Key: "created_at", Value: -1}}, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}},
+		{"keeps id", bson.D{{Key: "_id", Value: int32(-1)}}, bson.D{{Key: "_id", Value: -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeCursorSort(tt.sort); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSeekCondition(t *testing.T) {
+	values := rawValues(t, "acme", int32(5), int32(9))
+	sort := bson.D{{Key: "name", Value: 1}, {Key: "score", Value: -1}, {Key: "_id", Value: 1}}
+
+	tests := []struct {
+		name string
+		sort bson.D
+		want bson.M
+	}{
+		{"forward", sort, bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{"$gt": values[0]}},
+			bson.M{"name": values[0], "$or": bson.A{bson.M{"score": bson.M{"$lt": values[1]}}, bson.M{"score": nil}}},
+			bson.M{"name": values[0], "score": values[1], "_id": bson.M{"$gt": values[2]}},
+		}}},
+		{"backward", reverseSort(sort), bson.M{"$or": bson.A{
+			bson.M{"$or": bson.A{bson.M{"name": bson.M{"$lt": values[0]}}, bson.M{"name": nil}}},
+			bson.M{"name": values[0], "score": bson.M{"$gt": values[1]}},
+			bson.M{"name": values[0], "score": values[1], "$or": bson.A{bson.M{"_id": bson.M{"$lt": values[2]}}, bson.M{"_id": nil}}},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seekCondition(tt.sort, values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSeekCondition_Nulls(t *testing.T) {
+	values := rawValues(t, nil, int32(9))
+
+	tests := []struct {
+		name string
+		sort bson.D
+		want bson.M
+	}{
+		// Ascending, nulls come first: after null are the non-null values
+		{"ascending", bson.D{{Key: "rank", Value: 1}, {Key: "_id", Value: 1}}, bson.M{"$or": bson.A{
+			bson.M{"rank": bson.M{"$ne": nil}},
+			bson.M{"rank": values[0], "_id": bson.M{"$gt": values[1]}},
+		}}},
+		// Descending, nulls come last: only other nulls follow
+		{"descending", bson.D{{Key: "rank", Value: -1}, {Key: "_id", Value: 1}}, bson.M{"$or": bson.A{
+			bson.M{"rank": values[0], "_id": bson.M{"$gt": values[1]}},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seekCondition(tt.sort, values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSortValues_MissingField(t *testing.T) {
+	secret := []byte("secret")
+	sort := normalizeCursorSort(bson.D{{Key: "profile.rank", Value: 1}})
+	doc, _ := bson.Marshal(bson.D{{Key: "_id", Value: "doc-1"}})
+
+	values := sortValues(doc, sort)
+	if values[0].Type != bson.TypeNull || values[1].StringValue() != "doc-1" {
+		t.Fatalf("expected the missing field as null, got %v", values)
+	}
+	cursor, err := encodeCursor(secret, cursorPayload{Direction: cursorNext, Keys: []string{"profile.rank", "_id"}, Values: values})
+	if err != nil {
+		t.Fatalf("failed to encode cursor: %v", err)
+	}
+	payload, err := decodeCursor(secret, cursor, sort)
+	if err != nil || payload.Values[0].Type != bson.TypeNull {
+		t.Errorf("expected the null value to round-trip, got %+v (%v)", payload, err)
+	}
+}
+
+func TestCursorCodec(t *testing.T) {
+	secret := []byte("secret")
+	sort := normalizeCursorSort(bson.D{{Key: "profile.rank", Value: -1}})
+
+	doc, _ := bson.Marshal(bson.D{{Key: "_id", Value: "doc-1"}, {Key: "profile", Value: bson.D{{Key: "rank", Value: int64(3)}}}})
+	values := sortValues(doc, sort)
+	cursor, err := encodeCursor(secret, cursorPayload{Direction: cursorPrev, Keys: []string{"profile.rank", "_id"}, Values: values})
+	if err != nil {
+		t.Fatalf("failed to encode cursor: %v", err)
+	}
+
+	payload, err := decodeCursor(secret, cursor, sort)
+	if err != nil {
+		t.Fatalf("failed to decode cursor: %v", err)
+	}
+	if payload.Direction != cursorPrev || payload.Values[0].Int64() != 3 || payload.Values[1].StringValue() != "doc-1" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+
+	tampered := []byte(cursor)
+	tampered[2] ^= 1
+
+	tests := []struct {
+		name   string
+		secret []byte
+		cursor string
+		sort   bson.D
+	}{
+		{"tampered", secret, string(tampered), sort},
+		{"wrong secret", []byte("other"), cursor, sort},
+		{"other sort", secret, cursor, normalizeCursorSort(bson.D{{Key: "name", Value: 1}})},
+		{"legacy id cursor", secret, "507f1f77bcf86cd799439011", sort},
+		{"garbage", secret, "a.b", sort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.secret, tt.cursor, tt.sort); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("expected ErrInvalidCursor, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,772 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrNoCollection is returned when a typed repository has neither a collection nor a router
+	ErrNoCollection = errors.New("repository has no collection")
+	// ErrUnsupportedUpdate is returned when an update operator such as $set
+	// holds something other than a bson.M, bson.D or map[string]interface{}
+	ErrUnsupportedUpdate = errors.New("unsupported update operator document")
+)
+
+// TypedPaginationResult contains typed offset-based pagination results
+type TypedPaginationResult[T any] struct {
+	Data        []T   `json:"data"`
+	Total       int64 `json:"total"`
+	Page        int64 `json:"page"`
+	PageSize    int64 `json:"page_size"`
+	TotalPages  int64 `json:"total_pages"`
+	HasNext     bool  `json:"has_next"`
+	HasPrevious bool  `json:"has_previous"`
+}
+
+// TypedCursorResult contains typed cursor-based pagination results
+type TypedCursorResult[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+}
+
+// Hooks are called around repository writes, in order, e.g. for validation
+// or event emission. An error from a before hook aborts the operation; an
+// error from an after hook is returned although the write has been applied.
+type Hooks[T any] struct {
+	BeforeCreate []func(ctx context.Context, doc *T) error
+	AfterCreate  []func(ctx context.Context, doc *T) error
+	BeforeUpdate []func(ctx context.Context, filter, update bson.M) error
+	AfterUpdate  []func(ctx context.Context, doc *T) error
+	BeforeDelete []func(ctx context.Context, filter bson.M) error
+	AfterDelete  []func(ctx context.Context, doc *T) error
+}
+
+// FieldEncryptor encrypts and decrypts the sensitive fields of documents in
+// place, e.g. *encryption.Encryptor
+type FieldEncryptor interface {
+	Encrypt(ctx context.Context, doc interface{}) error
+	Decrypt(ctx context.Context, doc interface{}) error
+	// EncryptUpdate encrypts the values of sensitive fields in an update
+	// document, or rejects updates that would store them in plaintext
+	EncryptUpdate(ctx context.Context, sample interface{}, update map[string]interface{}) error
+}
+
+// KeyRotator re-encrypts the sensitive fields of a document that were
+// encrypted with an older key, e.g. *encryption.Encryptor
+type KeyRotator interface {
+	Rotate(ctx context.Context, doc interface{}) (bool, error)
+}
+
+// TypedRepositoryConfig configures a typed repository. Either Collection, or
+// Router and CollectionName (to route per tenant) must be set.
+type TypedRepositoryConfig[T any] struct {
+	Collection     *mongo.Collection
+	Router         *TenantRouter
+	CollectionName string
+	Client         *Client
+	SoftDelete     bool
+	// TenantScoped restricts every operation to the tenant in the request context
+	TenantScoped bool
+	// Versioning maintains the version field for optimistic concurrency (see UpdateWithVersion)
+	Versioning bool
+	Hooks      Hooks[T]
+	// Encryptor encrypts sensitive fields on writes ($set values included) and decrypts them on reads
+	Encryptor FieldEncryptor
+}
+
+// Repository is a typed repository returning *T and []T. It manages
+// created_at/updated_at timestamps, soft delete and tenant scoping like
+// BaseRepository and TenantRepository.
+type Repository[T any] struct {
+	collection     *mongo.Collection
+	router         *TenantRouter
+	collectionName string
+	client         *Client
+	softDelete     bool
+	tenantScoped   bool
+	versioning     bool
+	hooks          Hooks[T]
+	encryptor      FieldEncryptor
+}
+
+// NewRepository creates a typed repository. It panics when TenantScoped is
+// set and *T does not implement TenantAware, since inserted documents would
+// have no tenant and never be found again.
+func NewRepository[T any](config TypedRepositoryConfig[T]) *Repository[T] {
+	if _, ok := any(new(T)).(TenantAware); config.TenantScoped && !ok {
+		panic(fmt.Sprintf("mongodb: tenant-scoped repository requires *%T to implement TenantAware", *new(T)))
+	}
+	return &Repository[T]{
+		collection:     config.Collection,
+		router:         config.Router,
+		collectionName: config.CollectionName,
+		client:         config.Client,
+		softDelete:     config.SoftDelete,
+		tenantScoped:   config.TenantScoped,
+		versioning:     config.Versioning,
+		hooks:          config.Hooks,
+		encryptor:      config.Encryptor,
+	}
+}
+
+// Collection returns the collection used for the context
+func (r *Repository[T]) Collection(ctx context.Context) (*mongo.Collection, error) {
+	if r.router != nil {
+		return r.router.Collection(ctx, r.collectionName)
+	}
+	if r.collection == nil {
+		return nil, ErrNoCollection
+	}
+	return r.collection, nil
+}
+
+// Query returns a query builder that validates field names against T
+func (r *Repository[T]) Query() *QueryBuilder {
+	return NewTypedQueryBuilder[T]()
+}
+
+// Create inserts a document, setting its ID, timestamps and tenant
+func (r *Repository[T]) Create(ctx context.Context, doc *T) error {
+	collection, tenantID, err := r.target(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.prepareInsert(doc, tenantID, time.Now())
+	for _, hook := range r.hooks.BeforeCreate {
+		if err := hook(ctx, doc); err != nil {
+			return err
+		}
+	}
+
+	if err := r.encrypt(ctx, doc); err != nil {
+		return err
+	}
+	_, err = collection.InsertOne(ctx, doc)
+	if decryptErr := r.decrypt(ctx, doc); err == nil {
+		err = decryptErr
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range r.hooks.AfterCreate {
+		if err := hook(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateMany inserts documents, setting their IDs, timestamps and tenant
+func (r *Repository[T]) CreateMany(ctx context.Context, docs []*T) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	collection, tenantID, err := r.target(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	documents := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		r.prepareInsert(doc, tenantID, now)
+		for _, hook := range r.hooks.BeforeCreate {
+			if err := hook(ctx, doc); err != nil {
+				return err
+			}
+		}
+		if err := r.encrypt(ctx, doc); err != nil {
+			return err
+		}
+		documents[i] = doc
+	}
+
+	_, err = collection.InsertMany(ctx, documents)
+	for _, doc := range docs {
+		if decryptErr := r.decrypt(ctx, doc); err == nil {
+			err = decryptErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		for _, hook := range r.hooks.AfterCreate {
+			if err := hook(ctx, doc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RotateKeys re-encrypts the sensitive fields of the documents matching the
+// filter that were encrypted with an older key and returns the number of
+// documents rewritten. The Encryptor must implement KeyRotator. Stored
+// ciphertexts are rotated without being decrypted into the document, and a
+// document is only rewritten if its rotated fields have not changed since
+// they were read; hooks are not called.
+func (r *Repository[T]) RotateKeys(ctx context.Context, filter bson.M) (int64, error) {
+	rotator, ok := r.encryptor.(KeyRotator)
+	if !ok {
+		return 0, errors.New("repository encryptor does not support key rotation")
+	}
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var rotated int64
+	for cursor.Next(ctx) {
+		// Documents keep their field order, so embedded documents can be matched exactly
+		var before bson.D
+		if err := bson.Unmarshal(cursor.Current, &before); err != nil {
+			return rotated, err
+		}
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return rotated, err
+		}
+		changed, err := rotator.Rotate(ctx, &doc)
+		if err != nil {
+			return rotated, err
+		}
+		if !changed {
+			continue
+		}
+
+		data, err := bson.Marshal(&doc)
+		if err != nil {
+			return rotated, err
+		}
+		var after bson.D
+		if err := bson.Unmarshal(data, &after); err != nil {
+			return rotated, err
+		}
+		casFilter, set := rotationUpdate(before, after)
+		result, err := collection.UpdateOne(ctx, casFilter, bson.M{"$set": set})
+		if err != nil {
+			return rotated, err
+		}
+		rotated += result.ModifiedCount
+	}
+	return rotated, cursor.Err()
+}
+
+// FindByID finds a document by ID; it returns mongo.ErrNoDocuments when not found
+func (r *Repository[T]) FindByID(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	return r.FindOne(ctx, bson.M{"_id": id})
+}
+
+// FindOne finds a single document; it returns mongo.ErrNoDocuments when not found
+func (r *Repository[T]) FindOne(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) (*T, error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc T
+	if err := collection.FindOne(ctx, filter, opts...).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if err := r.decrypt(ctx, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Find finds all documents matching the filter
+func (r *Repository[T]) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]T, error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := make([]T, 0)
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	if err := r.decryptAll(ctx, docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// Update applies an update to a single document and returns the updated
+// document; it returns mongo.ErrNoDocuments when nothing matched
+func (r *Repository[T]) Update(ctx context.Context, filter, update bson.M) (*T, error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return r.update(ctx, collection, filter, filter, update, -1)
+}
+
+// UpdateWithVersion applies an update to a single document only if its
+// version equals version (compare-and-swap), incrementing the version. It
+// returns a *VersionConflictError when the document exists with another
+// version and mongo.ErrNoDocuments when it does not exist.
+func (r *Repository[T]) UpdateWithVersion(ctx context.Context, filter bson.M, version int64, update bson.M) (*T, error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	casFilter := copyFilter(filter)
+	casFilter["version"] = versionCondition(version)
+	return r.update(ctx, collection, filter, casFilter, update, version)
+}
+
+// UpdateByIDWithVersion applies an update to a document by ID if its version equals version
+func (r *Repository[T]) UpdateByIDWithVersion(ctx context.Context, id primitive.ObjectID, version int64, update bson.M) (*T, error) {
+	return r.UpdateWithVersion(ctx, bson.M{"_id": id}, version, update)
+}
+
+// update runs a single-document update; expected is the compare-and-swap version or -1
+func (r *Repository[T]) update(ctx context.Context, collection *mongo.Collection, filter, updateFilter, update bson.M, expected int64) (*T, error) {
+	update, err := withUpdatedAt(update, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if r.versioning || expected >= 0 {
+		if err := incVersion(update); err != nil {
+			return nil, err
+		}
+	}
+	for _, hook := range r.hooks.BeforeUpdate {
+		if err := hook(ctx, updateFilter, update); err != nil {
+			return nil, err
+		}
+	}
+	// Encrypt last, so that values set by hooks are encrypted too
+	if err := r.encryptUpdate(ctx, update); err != nil {
+		return nil, err
+	}
+
+	var doc T
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := collection.FindOneAndUpdate(ctx, updateFilter, update, opts).Decode(&doc); err != nil {
+		if expected >= 0 && errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, versionMismatch(ctx, collection, filter, expected)
+		}
+		return nil, err
+	}
+	if err := r.decrypt(ctx, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, hook := range r.hooks.AfterUpdate {
+		if err := hook(ctx, &doc); err != nil {
+			return &doc, err
+		}
+	}
+	return &doc, nil
+}
+
+// UpdateByID applies an update to a document by ID
+func (r *Repository[T]) UpdateByID(ctx context.Context, id primitive.ObjectID, update bson.M) (*T, error) {
+	return r.Update(ctx, bson.M{"_id": id}, update)
+}
+
+// UpdateMany applies an update to all matching documents and returns the
+// number modified. Only BeforeUpdate hooks are called.
+func (r *Repository[T]) UpdateMany(ctx context.Context, filter, update bson.M) (int64, error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	if update, err = withUpdatedAt(update, time.Now()); err != nil {
+		return 0, err
+	}
+	if r.versioning {
+		if err := incVersion(update); err != nil {
+			return 0, err
+		}
+	}
+	for _, hook := range r.hooks.BeforeUpdate {
+		if err := hook(ctx, filter, update); err != nil {
+			return 0, err
+		}
+	}
+	if err := r.encryptUpdate(ctx, update); err != nil {
+		return 0, err
+	}
+
+	result, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// Delete deletes a single document (soft delete if enabled) and returns it;
+// it returns mongo.ErrNoDocuments when nothing matched
+func (r *Repository[T]) Delete(ctx context.Context, filter bson.M) (*T, error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hook := range r.hooks.BeforeDelete {
+		if err := hook(ctx, filter); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc T
+	if r.softDelete {
+		now := time.Now()
+		update := bson.M{"$set": bson.M{"deleted_at": now, "updated_at": now}}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	} else {
+		err = collection.FindOneAndDelete(ctx, filter).Decode(&doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.decrypt(ctx, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, hook := range r.hooks.AfterDelete {
+		if err := hook(ctx, &doc); err != nil {
+			return &doc, err
+		}
+	}
+	return &doc, nil
+}
+
+// DeleteByID deletes a document by ID (soft delete if enabled)
+func (r *Repository[T]) DeleteByID(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	return r.Delete(ctx, bson.M{"_id": id})
+}
+
+// DeleteMany deletes all matching documents (soft delete if enabled) and
+// returns the number deleted. Only BeforeDelete hooks are called.
+func (r *Repository[T]) DeleteMany(ctx context.Context, filter bson.M) (int64, error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, hook := range r.hooks.BeforeDelete {
+		if err := hook(ctx, filter); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.softDelete {
+		now := time.Now()
+		result, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"deleted_at": now, "updated_at": now}})
+		if err != nil {
+			return 0, err
+		}
+		return result.ModifiedCount, nil
+	}
+
+	result, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// Restore restores a soft-deleted document and returns it
+func (r *Repository[T]) Restore(ctx context.Context, filter bson.M) (*T, error) {
+	if !r.softDelete {
+		return nil, errors.New("soft delete is not enabled for this repository")
+	}
+	collection, tenantID, err := r.target(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter = copyFilter(filter)
+	filter["deleted_at"] = bson.M{"$exists": true}
+	if r.tenantScoped {
+		filter["tenant_id"] = tenantID
+	}
+
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+
+	var doc T
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if err := r.decrypt(ctx, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Count counts documents matching the filter
+func (r *Repository[T]) Count(ctx context.Context, filter bson.M) (int64, error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, filter)
+}
+
+// Exists checks if a document matching the filter exists
+func (r *Repository[T]) Exists(ctx context.Context, filter bson.M) (bool, error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	count, err := collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Paginate performs offset-based pagination
+func (r *Repository[T]) Paginate(ctx context.Context, filter bson.M, params *PaginationParams) (*TypedPaginationResult[T], error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]T, 0)
+	result, err := Paginate(ctx, collection, filter, params, &docs)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.decryptAll(ctx, docs); err != nil {
+		return nil, err
+	}
+
+	return &TypedPaginationResult[T]{
+		Data:        docs,
+		Total:       result.Total,
+		Page:        result.Page,
+		PageSize:    result.PageSize,
+		TotalPages:  result.TotalPages,
+		HasNext:     result.HasNext,
+		HasPrevious: result.HasPrevious,
+	}, nil
+}
+
+// PaginateWithCursor performs cursor-based pagination
+func (r *Repository[T]) PaginateWithCursor(ctx context.Context, filter bson.M, params *CursorPagination) (*TypedCursorResult[T], error) {
+	collection, filter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []T
+	result, err := PaginateWithCursor(ctx, collection, filter, params, &docs)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.decryptAll(ctx, docs); err != nil {
+		return nil, err
+	}
+
+	return &TypedCursorResult[T]{
+		Data:       docs,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+		HasNext:    result.HasNext,
+		HasPrev:    result.HasPrev,
+	}, nil
+}
+
+// Transaction executes a function within a transaction
+func (r *Repository[T]) Transaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) error) error {
+	if r.client == nil {
+		return errors.New("client is required for transaction support")
+	}
+	return r.client.Transaction(ctx, fn)
+}
+
+// target returns the collection and, for tenant-scoped repositories, the tenant of the context
+func (r *Repository[T]) target(ctx context.Context) (*mongo.Collection, string, error) {
+	var tenantID string
+	if r.tenantScoped {
+		var err error
+		if tenantID, err = pkgctx.GetTenantID(ctx); err != nil {
+			return nil, "", err
+		}
+	}
+
+	collection, err := r.Collection(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return collection, tenantID, nil
+}
+
+// scoped returns the collection and a copy of the filter with the tenant and soft delete conditions
+func (r *Repository[T]) scoped(ctx context.Context, filter bson.M) (*mongo.Collection, bson.M, error) {
+	collection, tenantID, err := r.target(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter = copyFilter(filter)
+	if r.tenantScoped {
+		filter["tenant_id"] = tenantID
+	}
+	if r.softDelete {
+		filter["deleted_at"] = bson.M{"$exists": false}
+	}
+	return collection, filter, nil
+}
+
+func (r *Repository[T]) prepareInsert(doc *T, tenantID string, now time.Time) {
+	var model interface{} = doc
+	if identified, ok := model.(interface {
+		GetID() primitive.ObjectID
+		SetID(primitive.ObjectID)
+	}); ok && identified.GetID().IsZero() {
+		identified.SetID(primitive.NewObjectID())
+	}
+	if timestamped, ok := model.(interface {
+		SetCreatedAt(time.Time)
+		SetUpdatedAt(time.Time)
+	}); ok {
+		timestamped.SetCreatedAt(now)
+		timestamped.SetUpdatedAt(now)
+	}
+	if tenantAware, ok := model.(TenantAware); ok && r.tenantScoped {
+		tenantAware.SetTenantID(tenantID)
+	}
+	if versioned, ok := model.(Versioned); ok && r.versioning {
+		versioned.SetVersion(1)
+	}
+}
+
+func (r *Repository[T]) encrypt(ctx context.Context, doc *T) error {
+	if r.encryptor == nil {
+		return nil
+	}
+	return r.encryptor.Encrypt(ctx, doc)
+}
+
+func (r *Repository[T]) decrypt(ctx context.Context, doc *T) error {
+	if r.encryptor == nil {
+		return nil
+	}
+	return r.encryptor.Decrypt(ctx, doc)
+}
+
+func (r *Repository[T]) decryptAll(ctx context.Context, docs []T) error {
+	for i := range docs {
+		if err := r.decrypt(ctx, &docs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encryptUpdate encrypts sensitive values of the update, which withUpdatedAt
+// has already copied; the encryptor replaces operator documents with copies
+func (r *Repository[T]) encryptUpdate(ctx context.Context, update bson.M) error {
+	if r.encryptor == nil {
+		return nil
+	}
+	return r.encryptor.EncryptUpdate(ctx, new(T), update)
+}
+
+// rotationUpdate returns a filter matching the document only while the
+// top-level fields changed by a rotation still hold their old values, and
+// the $set writing the new ones
+func rotationUpdate(before, after bson.D) (filter, set bson.M) {
+	old := make(map[string]interface{}, len(before))
+	for _, e := range before {
+		old[e.Key] = e.Value
+	}
+	filter = bson.M{"_id": old["_id"]}
+	set = bson.M{}
+	for _, e := range after {
+		if e.Key == "_id" || reflect.DeepEqual(old[e.Key], e.Value) {
+			continue
+		}
+		filter[e.Key] = old[e.Key]
+		set[e.Key] = e.Value
+	}
+	return filter, set
+}
+
+func copyFilter(filter bson.M) bson.M {
+	copied := make(bson.M, len(filter)+2)
+	for key, value := range filter {
+		copied[key] = value
+	}
+	return copied
+}
+
+// withUpdatedAt returns a copy of the update that also sets updated_at. The
+// caller's $set fields are kept whatever document form they use.
+func withUpdatedAt(update bson.M, now time.Time) (bson.M, error) {
+	set, err := operatorFields(update, "$set")
+	if err != nil {
+		return nil, err
+	}
+	set["updated_at"] = now
+	copied := copyFilter(update)
+	copied["$set"] = set
+	return copied, nil
+}
+
+// operatorFields returns a copy of the fields of an update operator as a
+// bson.M, or an empty document when the update does not use the operator.
+// Values other than bson.M, bson.D and map[string]interface{}, such as
+// structs, are rejected with ErrUnsupportedUpdate rather than dropped.
+func operatorFields(update bson.M, operator string) (bson.M, error) {
+	fields := bson.M{}
+	switch existing := update[operator].(type) {
+	case nil:
+	case bson.M:
+		for key, value := range existing {
+			fields[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range existing {
+			fields[key] = value
+		}
+	case bson.D:
+		for _, e := range existing {
+			fields[e.Key] = e.Value
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s must be a document of fields, got %T", ErrUnsupportedUpdate, operator, existing)
+	}
+	return fields, nil
+}
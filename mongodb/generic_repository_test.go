@@ -0,0 +1,193 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"github.com/vhvplatform/go-shared/encryption"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testOrder struct {
+	BaseModel `bson:",inline"`
+	TenantID  string `bson:"tenant_id"`
+	Total     int64  `bson:"total"`
+}
+
+func (o *testOrder) GetTenantID() string         { return o.TenantID }
+func (o *testOrder) SetTenantID(tenantID string) { o.TenantID = tenantID }
+
+func TestRepository_CreateRunsBeforeHooks(t *testing.T) {
+	errInvalid := errors.New("total must be positive")
+	var seen *testOrder
+	repo := NewRepository(TypedRepositoryConfig[testOrder]{
+		Collection:   lazyClient(t, "mongodb://localhost:27017", "app").Collection("orders"),
+		TenantScoped: true,
+		Hooks: Hooks[testOrder]{
+			BeforeCreate: []func(ctx context.Context, doc *testOrder) error{
+				func(ctx context.Context, doc *testOrder) error {
+					seen = doc
+					if doc.Total <= 0 {
+						return errInvalid
+					}
+					return nil
+				},
+			},
+		},
+	})
+
+	if err := repo.Create(context.Background(), &testOrder{Total: 1}); !errors.Is(err, pkgctx.ErrTenantNotFound) {
+		t.Errorf("expected missing tenant error, got %v", err)
+	}
+
+	ctx := pkgctx.WithTenantID(context.Background(), "acme")
+	if err := repo.Create(ctx, &testOrder{}); !errors.Is(err, errInvalid) {
+		t.Fatalf("expected hook error, got %v", err)
+	}
+	if seen.ID.IsZero() || seen.CreatedAt.IsZero() || seen.TenantID != "acme" {
+		t.Errorf("expected ID, timestamps and tenant to be set before hooks, got %+v", seen)
+	}
+}
+
+func TestNewRepository_TenantScopedRequiresTenantAware(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a tenant-scoped model without TenantAware")
+		}
+	}()
+	NewRepository(TypedRepositoryConfig[testCustomer]{
+		Collection:   lazyClient(t, "mongodb://localhost:27017", "app").Collection("customers"),
+		TenantScoped: true,
+	})
+}
+
+func TestRepository_ScopedFilter(t *testing.T) {
+	repo := NewRepository(TypedRepositoryConfig[testOrder]{
+		Collection:   lazyClient(t, "mongodb://localhost:27017", "app").Collection("orders"),
+		SoftDelete:   true,
+		TenantScoped: true,
+	})
+
+	filter := bson.M{"total": bson.M{"$gt": 10}, "tenant_id": "globex"}
+	_, scoped, err := repo.scoped(pkgctx.WithTenantID(context.Background(), "acme"), filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if scoped["tenant_id"] != "acme" || scoped["deleted_at"] == nil || scoped["total"] == nil {
+		t.Errorf("unexpected scoped filter: %v", scoped)
+	}
+	if filter["tenant_id"] != "globex" || filter["deleted_at"] != nil {
+		t.Error("expected the caller's filter to be left unchanged")
+	}
+}
+
+func TestWithUpdatedAt(t *testing.T) {
+	update := bson.M{"$set": bson.M{"total": 5}, "$inc": bson.M{"version": 1}}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	result, err := withUpdatedAt(update, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	set := result["$set"].(bson.M)
+	if set["total"] != 5 || set["updated_at"] != now || result["$inc"] == nil {
+		t.Errorf("unexpected update: %v", result)
+	}
+	if _, ok := update["$set"].(bson.M)["updated_at"]; ok {
+		t.Error("expected the caller's update to be left unchanged")
+	}
+}
+
+func TestWithUpdatedAt_SetForms(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		set  interface{}
+		want bson.M
+		err  error
+	}{
+		{"no set", nil, bson.M{"updated_at": now}, nil},
+		{"bson.M", bson.M{"total": 5}, bson.M{"total": 5, "updated_at": now}, nil},
+		{"map", map[string]interface{}{"total": 5}, bson.M{"total": 5, "updated_at": now}, nil},
+		{"bson.D", bson.D{{Key: "total", Value: 5}}, bson.M{"total": 5, "updated_at": now}, nil},
+		{"struct", &testOrder{Total: 5}, nil, ErrUnsupportedUpdate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := bson.M{}
+			if tt.set != nil {
+				update["$set"] = tt.set
+			}
+			result, err := withUpdatedAt(update, now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if tt.err == nil && !reflect.DeepEqual(result["$set"], tt.want) {
+				t.Errorf("expected $set %v, got %v", tt.want, result["$set"])
+			}
+		})
+	}
+}
+
+type testCustomer struct {
+	BaseModel `bson:",inline"`
+	Phone     string `bson:"phone" secure:"encrypt"`
+	PhoneIdx  string `bson:"phone_bidx" secure:"blind_index=Phone"`
+}
+
+func TestRepository_EncryptedFields(t *testing.T) {
+	ctx := context.Background()
+	provider, _ := encryption.NewStaticKeyProvider("k1", map[string][]byte{"k1": make([]byte, 32)})
+	encryptor, err := encryption.NewEncryptor(provider, encryption.Config{IndexKey: make([]byte, 32)})
+	if err != nil {
+		t.Fatalf("failed to create encryptor: %v", err)
+	}
+	repo := NewRepository(TypedRepositoryConfig[testCustomer]{
+		Collection: lazyClient(t, "mongodb://localhost:27017", "app").Collection("customers"),
+		Encryptor:  encryptor,
+	})
+
+	update, err := withUpdatedAt(bson.M{"$set": bson.M{"phone": "+84901234567"}, "$setOnInsert": bson.M{"phone": "+84900000000"}}, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.encryptUpdate(ctx, update); err != nil {
+		t.Fatalf("failed to encrypt update: %v", err)
+	}
+	set := update["$set"].(bson.M)
+	if !encryption.IsEncrypted(set["phone"].(string)) {
+		t.Errorf("expected encrypted phone, got %v", set["phone"])
+	}
+	if phone := update["$setOnInsert"].(bson.M)["phone"].(string); !encryption.IsEncrypted(phone) {
+		t.Errorf("expected encrypted $setOnInsert phone, got %v", phone)
+	}
+	if err := repo.encryptUpdate(ctx, bson.M{"$push": bson.M{"phone": "+84901234567"}}); !errors.Is(err, encryption.ErrUnsupportedField) {
+		t.Errorf("expected updates writing plaintext to be rejected, got %v", err)
+	}
+
+	filter := NewQueryBuilder().WhereBlindIndex("phone_bidx", "+84901234567", encryptor).Build()
+	if filter["phone_bidx"] == "" || filter["phone_bidx"] != set["phone_bidx"] {
+		t.Errorf("expected blind index filter to match the update, got %v and %v", filter, set)
+	}
+}
+
+func TestRotationUpdate(t *testing.T) {
+	id := primitive.NewObjectID()
+	before := bson.D{{Key: "_id", Value: id}, {Key: "name", Value: "Bob"}, {Key: "phone", Value: "enc:v1:old"}}
+	after := bson.D{{Key: "_id", Value: id}, {Key: "name", Value: "Bob"}, {Key: "phone", Value: "enc:v1:new"}}
+
+	filter, set := rotationUpdate(before, after)
+	if len(filter) != 2 || filter["_id"] != id || filter["phone"] != "enc:v1:old" {
+		t.Errorf("expected the filter to match the old ciphertext, got %v", filter)
+	}
+	if len(set) != 1 || set["phone"] != "enc:v1:new" {
+		t.Errorf("expected only the rotated field to be set, got %v", set)
+	}
+}
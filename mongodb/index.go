@@ -0,0 +1,278 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexSpec declares an index of a collection
+type IndexSpec struct {
+	Name    string // default: generated from the keys like MongoDB does (e.g. "email_1")
+	Keys    bson.D // use the value "text" for text index fields
+	Unique  bool
+	Sparse  bool
+	TTL     time.Duration // expire documents this long after the (date) key value
+	Partial bson.M        // partialFilterExpression
+	Weights bson.M        // text index field weights
+}
+
+// TenantIndex declares an index on tenant_id followed by the given ascending fields
+func TenantIndex(fields ...string) IndexSpec {
+	keys := bson.D{{Key: "tenant_id", Value: 1}}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	return IndexSpec{Keys: keys}
+}
+
+// TextIndex declares a text index on the fields
+func TextIndex(fields ...string) IndexSpec {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+	}
+	return IndexSpec{Keys: keys}
+}
+
+// IndexName returns the index name, generated from the keys when not set
+func (s IndexSpec) IndexName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	parts := make([]string, 0, len(s.Keys)*2)
+	for _, key := range s.Keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// Model returns the index model used to create the index
+func (s IndexSpec) Model() mongo.IndexModel {
+	opts := options.Index().SetName(s.IndexName())
+	if s.Unique {
+		opts.SetUnique(true)
+	}
+	if s.Sparse {
+		opts.SetSparse(true)
+	}
+	if s.TTL > 0 {
+		opts.SetExpireAfterSeconds(int32(s.TTL / time.Second))
+	}
+	if len(s.Partial) > 0 {
+		opts.SetPartialFilterExpression(s.Partial)
+	}
+	if len(s.Weights) > 0 {
+		opts.SetWeights(s.Weights)
+	}
+	return mongo.IndexModel{Keys: s.Keys, Options: opts}
+}
+
+func (s IndexSpec) isText() bool {
+	for _, key := range s.Keys {
+		if key.Value == "text" {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexAction is a change needed to match the declared indexes
+type IndexAction string
+
+const (
+	IndexCreate  IndexAction = "create"
+	IndexRebuild IndexAction = "rebuild" // drop and create with the declared definition
+	IndexDrop    IndexAction = "drop"
+)
+
+// IndexChange is a planned index change
+type IndexChange struct {
+	Collection string
+	Action     IndexAction
+	Name       string
+	Spec       *IndexSpec // nil for drops
+	Reason     string
+}
+
+// existingIndex is the layout of listIndexes results
+type existingIndex struct {
+	Name                    string `bson:"name"`
+	Key                     bson.D `bson:"key"`
+	Unique                  bool   `bson:"unique"`
+	Sparse                  bool   `bson:"sparse"`
+	ExpireAfterSeconds      *int64 `bson:"expireAfterSeconds"`
+	PartialFilterExpression bson.M `bson:"partialFilterExpression"`
+	Weights                 bson.M `bson:"weights"`
+}
+
+func listIndexes(ctx context.Context, collection *mongo.Collection) ([]existingIndex, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes of %s: %w", collection.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var indexes []existingIndex
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, fmt.Errorf("failed to decode indexes of %s: %w", collection.Name(), err)
+	}
+	return indexes, nil
+}
+
+// diffIndexes compares declared and existing indexes by name. Undeclared
+// indexes (except _id_) are dropped only when prune is set.
+func diffIndexes(collection string, declared []IndexSpec, existing []existingIndex, prune bool) []IndexChange {
+	byName := make(map[string]existingIndex, len(existing))
+	for _, index := range existing {
+		byName[index.Name] = index
+	}
+
+	var changes []IndexChange
+	names := make(map[string]bool, len(declared))
+	for i := range declared {
+		spec := declared[i]
+		name := spec.IndexName()
+		names[name] = true
+
+		current, ok := byName[name]
+		if !ok {
+			changes = append(changes, IndexChange{Collection: collection, Action: IndexCreate, Name: name, Spec: &spec, Reason: "missing"})
+			continue
+		}
+		if reason := indexDifference(spec, current); reason != "" {
+			changes = append(changes, IndexChange{Collection: collection, Action: IndexRebuild, Name: name, Spec: &spec, Reason: reason})
+		}
+	}
+
+	if prune {
+		var undeclared []string
+		for _, index := range existing {
+			if index.Name != "_id_" && !names[index.Name] {
+				undeclared = append(undeclared, index.Name)
+			}
+		}
+		sort.Strings(undeclared)
+		for _, name := range undeclared {
+			changes = append(changes, IndexChange{Collection: collection, Action: IndexDrop, Name: name, Reason: "not declared"})
+		}
+	}
+	return changes
+}
+
+// indexDifference describes how an existing index differs from its spec, or returns ""
+func indexDifference(spec IndexSpec, current existingIndex) string {
+	if spec.isText() {
+		if !sameTextFields(spec, current) {
+			return "text fields differ"
+		}
+	} else if !sameKeys(spec.Keys, current.Key) {
+		return "keys differ"
+	}
+
+	if spec.Unique != current.Unique {
+		return "unique differs"
+	}
+	if spec.Sparse != current.Sparse {
+		return "sparse differs"
+	}
+
+	var expire int64 = -1
+	if current.ExpireAfterSeconds != nil {
+		expire = *current.ExpireAfterSeconds
+	}
+	want := int64(-1)
+	if spec.TTL > 0 {
+		want = int64(spec.TTL / time.Second)
+	}
+	if expire != want {
+		return "ttl differs"
+	}
+
+	if !reflect.DeepEqual(normalizeDocument(spec.Partial), normalizeDocument(current.PartialFilterExpression)) {
+		return "partial filter differs"
+	}
+	return ""
+}
+
+func sameKeys(declared, existing bson.D) bool {
+	if len(declared) != len(existing) {
+		return false
+	}
+	for i := range declared {
+		if declared[i].Key != existing[i].Key || !sameKeyValue(declared[i].Value, existing[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameKeyValue(a, b interface{}) bool {
+	if x, ok := keyNumber(a); ok {
+		y, ok := keyNumber(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func keyNumber(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// sameTextFields compares the fields and weights of text indexes. MongoDB
+// stores text fields as weights, with a default weight of 1.
+func sameTextFields(spec IndexSpec, current existingIndex) bool {
+	want := make(map[string]float64)
+	for _, key := range spec.Keys {
+		if key.Value == "text" {
+			want[key.Key] = 1
+		}
+	}
+	for field, weight := range spec.Weights {
+		if w, ok := keyNumber(weight); ok {
+			want[field] = w
+		}
+	}
+
+	if len(want) != len(current.Weights) {
+		return false
+	}
+	for field, weight := range current.Weights {
+		w, ok := keyNumber(weight)
+		if !ok || want[field] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeDocument round-trips a document through BSON so that value types
+// match documents read from the server; empty documents become nil
+func normalizeDocument(doc bson.M) bson.M {
+	if len(doc) == 0 {
+		return nil
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return doc
+	}
+	var normalized bson.M
+	if err := bson.Unmarshal(data, &normalized); err != nil {
+		return doc
+	}
+	return normalized
+}
@@ -0,0 +1,347 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrMigrationLocked is returned when another instance holds the migration lock
+	ErrMigrationLocked = errors.New("migrations are locked by another instance")
+	// ErrIrreversibleMigration is returned when rolling back a migration without Down
+	ErrIrreversibleMigration = errors.New("migration cannot be rolled back")
+)
+
+// Migration is a versioned schema or data migration
+type Migration struct {
+	Version     int64 // unique and ordered, e.g. 20240301120000
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error // optional
+}
+
+// AppliedMigration is the record of an applied migration
+type AppliedMigration struct {
+	Version     int64     `bson:"_id" json:"version"`
+	Description string    `bson:"description" json:"description"`
+	AppliedAt   time.Time `bson:"applied_at" json:"applied_at"`
+}
+
+// MigrationPlan lists what Up would do
+type MigrationPlan struct {
+	Migrations []Migration
+	Indexes    []IndexChange
+}
+
+// Empty reports whether there is nothing to do
+func (p *MigrationPlan) Empty() bool {
+	return len(p.Migrations) == 0 && len(p.Indexes) == 0
+}
+
+// String returns a human-readable plan, e.g. for dry runs
+func (p *MigrationPlan) String() string {
+	if p.Empty() {
+		return "nothing to migrate"
+	}
+	var b strings.Builder
+	for _, migration := range p.Migrations {
+		fmt.Fprintf(&b, "migrate %d: %s\n", migration.Version, migration.Description)
+	}
+	for _, change := range p.Indexes {
+		fmt.Fprintf(&b, "%s index %s.%s (%s)\n", change.Action, change.Collection, change.Name, change.Reason)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// MigratorConfig configures a Migrator
+type MigratorConfig struct {
+	Collection     string        // applied migrations (default: "schema_migrations")
+	LockCollection string        // default: "schema_migrations_lock"
+	LockTTL        time.Duration // lock expiry, must exceed the longest run (default: 10 minutes)
+	LockTimeout    time.Duration // how long to wait for the lock (default: 1 minute)
+	Prune          bool          // drop indexes that are not declared
+}
+
+// Migrator applies versioned migrations and declarative indexes to a
+// database. Runs are serialized across instances by a lock document.
+type Migrator struct {
+	db         *mongo.Database
+	config     MigratorConfig
+	migrations map[int64]Migration
+	indexes    map[string][]IndexSpec
+	owner      string
+	now        func() time.Time
+}
+
+// NewMigrator creates a migrator for the database
+func NewMigrator(db *mongo.Database, config MigratorConfig) *Migrator {
+	if config.Collection == "" {
+		config.Collection = "schema_migrations"
+	}
+	if config.LockCollection == "" {
+		config.LockCollection = "schema_migrations_lock"
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = 10 * time.Minute
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = time.Minute
+	}
+
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+
+	return &Migrator{
+		db:         db,
+		config:     config,
+		migrations: make(map[int64]Migration),
+		indexes:    make(map[string][]IndexSpec),
+		owner:      hostname + "-" + hex.EncodeToString(suffix),
+		now:        time.Now,
+	}
+}
+
+// Register adds migrations
+func (m *Migrator) Register(migrations ...Migration) error {
+	for _, migration := range migrations {
+		if migration.Up == nil {
+			return fmt.Errorf("migration %d has no Up function", migration.Version)
+		}
+		if _, exists := m.migrations[migration.Version]; exists {
+			return fmt.Errorf("migration %d is registered twice", migration.Version)
+		}
+		m.migrations[migration.Version] = migration
+	}
+	return nil
+}
+
+// DeclareIndexes declares the indexes of a collection; declaring again replaces them
+func (m *Migrator) DeclareIndexes(collection string, specs ...IndexSpec) {
+	m.indexes[collection] = specs
+}
+
+// Applied returns the applied migrations, oldest first
+func (m *Migrator) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	cursor, err := m.db.Collection(m.config.Collection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var applied []AppliedMigration
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+	sort.Slice(applied, func(i, j int) bool { return applied[i].Version < applied[j].Version })
+	return applied, nil
+}
+
+// Plan returns the pending migrations and the index changes without applying
+// them (dry run). Index changes are computed against the current indexes, so
+// indexes created by pending migrations are not taken into account.
+func (m *Migrator) Plan(ctx context.Context) (*MigrationPlan, error) {
+	pending, err := m.pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := m.indexChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &MigrationPlan{Migrations: pending, Indexes: indexes}, nil
+}
+
+// Up applies the pending migrations in version order, then creates, rebuilds
+// and (with Prune) drops indexes to match the declarations. It returns what
+// was applied; on failure the migrations applied so far stay recorded.
+func (m *Migrator) Up(ctx context.Context) (*MigrationPlan, error) {
+	release, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	pending, err := m.pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MigrationPlan{}
+	records := m.db.Collection(m.config.Collection)
+	for _, migration := range pending {
+		if err := migration.Up(ctx, m.db); err != nil {
+			return plan, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+		record := AppliedMigration{Version: migration.Version, Description: migration.Description, AppliedAt: m.now().UTC()}
+		if _, err := records.InsertOne(ctx, record); err != nil {
+			return plan, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		plan.Migrations = append(plan.Migrations, migration)
+	}
+
+	changes, err := m.indexChanges(ctx)
+	if err != nil {
+		return plan, err
+	}
+	for _, change := range changes {
+		if err := m.applyIndexChange(ctx, change); err != nil {
+			return plan, err
+		}
+		plan.Indexes = append(plan.Indexes, change)
+	}
+	return plan, nil
+}
+
+// Down rolls back the applied migrations with a version greater than
+// version, newest first. Declared indexes are not changed.
+func (m *Migrator) Down(ctx context.Context, version int64) ([]Migration, error) {
+	release, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledBack []Migration
+	records := m.db.Collection(m.config.Collection)
+	for i := len(applied) - 1; i >= 0 && applied[i].Version > version; i-- {
+		migration, ok := m.migrations[applied[i].Version]
+		if !ok {
+			return rolledBack, fmt.Errorf("applied migration %d is not registered", applied[i].Version)
+		}
+		if migration.Down == nil {
+			return rolledBack, fmt.Errorf("%w: %d (%s)", ErrIrreversibleMigration, migration.Version, migration.Description)
+		}
+		if err := migration.Down(ctx, m.db); err != nil {
+			return rolledBack, fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+		if _, err := records.DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+			return rolledBack, fmt.Errorf("failed to remove migration record %d: %w", migration.Version, err)
+		}
+		rolledBack = append(rolledBack, migration)
+	}
+	return rolledBack, nil
+}
+
+// pending returns the registered migrations that are not applied, in version order
+func (m *Migrator) pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int64]bool, len(applied))
+	for _, record := range applied {
+		done[record.Version] = true
+	}
+	return pendingMigrations(m.migrations, done), nil
+}
+
+func pendingMigrations(migrations map[int64]Migration, applied map[int64]bool) []Migration {
+	var pending []Migration
+	for version, migration := range migrations {
+		if !applied[version] {
+			pending = append(pending, migration)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+	return pending
+}
+
+// indexChanges diffs the declared indexes of every collection against the existing ones
+func (m *Migrator) indexChanges(ctx context.Context) ([]IndexChange, error) {
+	collections := make([]string, 0, len(m.indexes))
+	for name := range m.indexes {
+		collections = append(collections, name)
+	}
+	sort.Strings(collections)
+
+	var changes []IndexChange
+	for _, name := range collections {
+		existing, err := listIndexes(ctx, m.db.Collection(name))
+		if err != nil && !isNamespaceNotFound(err) {
+			return nil, err
+		}
+		changes = append(changes, diffIndexes(name, m.indexes[name], existing, m.config.Prune)...)
+	}
+	return changes, nil
+}
+
+func (m *Migrator) applyIndexChange(ctx context.Context, change IndexChange) error {
+	indexes := m.db.Collection(change.Collection).Indexes()
+	if change.Action == IndexRebuild || change.Action == IndexDrop {
+		if _, err := indexes.DropOne(ctx, change.Name); err != nil {
+			return fmt.Errorf("failed to drop index %s.%s: %w", change.Collection, change.Name, err)
+		}
+	}
+	if change.Action == IndexCreate || change.Action == IndexRebuild {
+		if _, err := indexes.CreateOne(ctx, change.Spec.Model()); err != nil {
+			return fmt.Errorf("failed to create index %s.%s: %w", change.Collection, change.Name, err)
+		}
+	}
+	return nil
+}
+
+// lock acquires the migration lock, waiting up to LockTimeout, and returns its release function
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	locks := m.db.Collection(m.config.LockCollection)
+	deadline := m.now().Add(m.config.LockTimeout)
+
+	for {
+		now := m.now()
+		_, err := locks.InsertOne(ctx, bson.M{"_id": "migrations", "owner": m.owner, "expires_at": now.Add(m.config.LockTTL)})
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		// Take over an expired lock
+		result, err := locks.UpdateOne(ctx,
+			bson.M{"_id": "migrations", "expires_at": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": m.owner, "expires_at": now.Add(m.config.LockTTL)}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if result.ModifiedCount > 0 {
+			break
+		}
+
+		if !now.Before(deadline) {
+			return nil, ErrMigrationLocked
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	return func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		locks.DeleteOne(releaseCtx, bson.M{"_id": "migrations", "owner": m.owner})
+	}, nil
+}
+
+// isNamespaceNotFound reports whether listing indexes failed because the collection does not exist
+func isNamespaceNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == 26
+}
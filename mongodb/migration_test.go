@@ -0,0 +1,131 @@
+package mongodb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestIndexSpec_IndexName(t *testing.T) {
+	tests := []struct {
+		spec IndexSpec
+		want string
+	}{
+		{IndexSpec{Keys: bson.D{{Key: "email", Value: 1}}}, "email_1"},
+		{TenantIndex("created_at"), "tenant_id_1_created_at_1"},
+		{IndexSpec{Keys: bson.D{{Key: "a", Value: 1}, {Key: "b", Value: -1}}}, "a_1_b_-1"},
+		{TextIndex("title", "body"), "title_text_body_text"},
+		{IndexSpec{Name: "custom", Keys: bson.D{{Key: "a", Value: 1}}}, "custom"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.spec.IndexName(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestDiffIndexes(t *testing.T) {
+	ttl := int64(3600)
+	existing := []existingIndex{
+		{Name: "_id_", Key: bson.D{{Key: "_id", Value: int32(1)}}},
+		{Name: "email_1", Key: bson.D{{Key: "email", Value: int32(1)}}, Unique: true},
+		{Name: "expires_at_1", Key: bson.D{{Key: "expires_at", Value: int32(1)}}, ExpireAfterSeconds: &ttl},
+		{Name: "status_1", Key: bson.D{{Key: "status", Value: float64(1)}}, PartialFilterExpression: bson.M{"active": true}},
+		{Name: "title_text", Key: bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}}, Weights: bson.M{"title": int32(1)}},
+		{Name: "legacy_1", Key: bson.D{{Key: "legacy", Value: int32(1)}}},
+	}
+
+	tests := []struct {
+		name     string
+		declared []IndexSpec
+		prune    bool
+		want     []IndexAction
+		reasons  []string
+	}{
+		{
+			name: "in sync",
+			declared: []IndexSpec{
+				{Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+				{Keys: bson.D{{Key: "expires_at", Value: 1}}, TTL: time.Hour},
+				{Keys: bson.D{{Key: "status", Value: 1}}, Partial: bson.M{"active": true}},
+				TextIndex("title"),
+			},
+		},
+		{
+			name:     "missing index",
+			declared: []IndexSpec{TenantIndex("email")},
+			want:     []IndexAction{IndexCreate},
+			reasons:  []string{"missing"},
+		},
+		{
+			name: "changed definitions",
+			declared: []IndexSpec{
+				{Keys: bson.D{{Key: "email", Value: 1}}},
+				{Keys: bson.D{{Key: "expires_at", Value: 1}}, TTL: 2 * time.Hour},
+				{Keys: bson.D{{Key: "status", Value: 1}}, Partial: bson.M{"active": false}},
+				{Name: "title_text", Keys: bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}}},
+			},
+			want:    []IndexAction{IndexRebuild, IndexRebuild, IndexRebuild, IndexRebuild},
+			reasons: []string{"unique differs", "ttl differs", "partial filter differs", "text fields differ"},
+		},
+		{
+			name:     "prune undeclared",
+			declared: []IndexSpec{{Keys: bson.D{{Key: "email", Value: 1}}, Unique: true}},
+			prune:    true,
+			want:     []IndexAction{IndexDrop, IndexDrop, IndexDrop, IndexDrop},
+			reasons:  []string{"not declared", "not declared", "not declared", "not declared"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := diffIndexes("users", tt.declared, existing, tt.prune)
+			var actions []IndexAction
+			var reasons []string
+			for _, change := range changes {
+				actions = append(actions, change.Action)
+				reasons = append(reasons, change.Reason)
+			}
+			if !reflect.DeepEqual(actions, tt.want) || !reflect.DeepEqual(reasons, tt.reasons) {
+				t.Errorf("expected %v %v, got %v %v", tt.want, tt.reasons, actions, reasons)
+			}
+		})
+	}
+}
+
+func TestMigrator_RegisterAndPending(t *testing.T) {
+	up := func(ctx context.Context, db *mongo.Database) error { return nil }
+	migrator := NewMigrator(nil, MigratorConfig{})
+
+	if err := migrator.Register(
+		Migration{Version: 3, Description: "backfill", Up: up},
+		Migration{Version: 1, Description: "create users", Up: up},
+		Migration{Version: 2, Description: "split names", Up: up},
+	); err != nil {
+		t.Fatalf("failed to register migrations: %v", err)
+	}
+	if err := migrator.Register(Migration{Version: 2, Up: up}); err == nil {
+		t.Error("expected error for duplicate version")
+	}
+	if err := migrator.Register(Migration{Version: 4}); err == nil {
+		t.Error("expected error for migration without Up")
+	}
+
+	pending := pendingMigrations(migrator.migrations, map[int64]bool{2: true})
+	plan := &MigrationPlan{
+		Migrations: pending,
+		Indexes:    []IndexChange{{Collection: "users", Action: IndexCreate, Name: "email_1", Reason: "missing"}},
+	}
+	want := "migrate 1: create users\nmigrate 3: backfill\ncreate index users.email_1 (missing)"
+	if got := plan.String(); got != want {
+		t.Errorf("expected plan:\n%s\ngot:\n%s", want, got)
+	}
+	if !(&MigrationPlan{}).Empty() {
+		t.Error("expected empty plan")
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -32,8 +32,9 @@ type PaginationResult struct {
 // CursorPagination holds parameters for cursor-based pagination
 type CursorPagination struct {
 	Limit  int64
-	Cursor string
+	Cursor string // NextCursor or PrevCursor of a previous result
 	Sort   bson.D
+	Secret []byte // cursor signing key (default: the key set with SetCursorSecret)
 }
 
 // CursorResult contains cursor-based pagination results
@@ -181,86 +182,146 @@ func (cp *CursorPagination) WithSort(sort bson.D) *CursorPagination {
 	return cp
 }
 
-// PaginateWithCursor performs cursor-based pagination on a collection
-// Note: This implementation uses _id field as the cursor. For ObjectID types,
-// the cursor will be the hex string representation.
+// WithSecret sets the key used to sign and verify cursors
+func (cp *CursorPagination) WithSecret(secret []byte) *CursorPagination {
+	cp.Secret = secret
+	return cp
+}
+
+// PaginateWithCursor performs cursor-based pagination on a collection.
+// Cursors are opaque, signed tokens holding the sort values of the boundary
+// document, so any multi-key sort is supported; _id is appended as a
+// tie-breaker when missing. Passing a PrevCursor pages backwards. Tampered
+// cursors and cursors issued for another sort return ErrInvalidCursor. Results
+// are decoded into results (a pointer to a slice) when given, otherwise Data
+// is a []bson.M.
 func PaginateWithCursor(ctx context.Context, collection *mongo.Collection, filter bson.M, params *CursorPagination, results interface{}) (*CursorResult, error) {
 	if params == nil {
 		return nil, errors.New("cursor pagination params cannot be nil")
 	}
+	if params.Limit < 1 {
+		return nil, errors.New("limit must be greater than 0")
+	}
 
-	// Build filter with cursor if provided
+	secret := params.Secret
+	if len(secret) == 0 {
+		secret = defaultCursorSecret()
+	}
+	sort := normalizeCursorSort(params.Sort)
+
+	direction := cursorNext
 	queryFilter := filter
 	if params.Cursor != "" {
-		// Try to parse as ObjectID first, fall back to string comparison
-		var cursorValue interface{} = params.Cursor
-		if objectID, err := primitive.ObjectIDFromHex(params.Cursor); err == nil {
-			cursorValue = objectID
+		payload, err := decodeCursor(secret, params.Cursor, sort)
+		if err != nil {
+			return nil, err
 		}
+		direction = payload.Direction
 
-		cursorFilter := bson.M{"_id": bson.M{"$gt": cursorValue}}
-		if filter != nil {
-			queryFilter = bson.M{
-				"$and": []bson.M{
-					filter,
-					cursorFilter,
-				},
-			}
+		seekSort := sort
+		if direction == cursorPrev {
+			seekSort = reverseSort(sort)
+		}
+		cursorFilter := seekCondition(seekSort, payload.Values)
+		if len(filter) > 0 {
+			queryFilter = bson.M{"$and": bson.A{filter, cursorFilter}}
 		} else {
 			queryFilter = cursorFilter
 		}
 	}
 
+	// Backward pages are read in reverse order and flipped afterwards
+	querySort := sort
+	if direction == cursorPrev {
+		querySort = reverseSort(sort)
+	}
+
 	// Fetch one more than limit to check if there are more results
 	findOptions := options.Find().
-		SetLimit(params.Limit + 1)
-
-	if len(params.Sort) > 0 {
-		findOptions.SetSort(params.Sort)
-	} else {
-		// Default sort by _id for cursor pagination
-		findOptions.SetSort(bson.D{{Key: "_id", Value: 1}})
-	}
+		SetLimit(params.Limit + 1).
+		SetSort(querySort)
 
-	// Execute query
 	cursor, err := collection.Find(ctx, queryFilter, findOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute find query: %w", err)
 	}
 	defer cursor.Close(ctx)
 
-	// Decode results into a temporary slice
-	var tempResults []bson.M
-	if err := cursor.All(ctx, &tempResults); err != nil {
+	var docs []bson.Raw
+	if err := cursor.All(ctx, &docs); err != nil {
 		return nil, fmt.Errorf("failed to decode results: %w", err)
 	}
 
-	// Check if there are more results
-	hasNext := len(tempResults) > int(params.Limit)
-	if hasNext {
-		tempResults = tempResults[:params.Limit]
-	}
-
-	// Get next cursor from last result - properly encode based on type
-	var nextCursor string
-	if len(tempResults) > 0 && hasNext {
-		lastDoc := tempResults[len(tempResults)-1]
-		if id, ok := lastDoc["_id"]; ok {
-			// Handle ObjectID type specially to get consistent hex representation
-			if objectID, ok := id.(primitive.ObjectID); ok {
-				nextCursor = objectID.Hex()
-			} else {
-				nextCursor = fmt.Sprintf("%v", id)
+	more := len(docs) > int(params.Limit)
+	if more {
+		docs = docs[:params.Limit]
+	}
+	if direction == cursorPrev {
+		for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+			docs[i], docs[j] = docs[j], docs[i]
+		}
+	}
+
+	result := &CursorResult{
+		HasNext: more,
+		HasPrev: params.Cursor != "",
+	}
+	if direction == cursorPrev {
+		result.HasNext = true
+		result.HasPrev = more
+	}
+
+	if len(docs) > 0 {
+		keys := make([]string, len(sort))
+		for i, field := range sort {
+			keys[i] = field.Key
+		}
+		if result.HasNext {
+			values := sortValues(docs[len(docs)-1], sort)
+			if result.NextCursor, err = encodeCursor(secret, cursorPayload{Direction: cursorNext, Keys: keys, Values: values}); err != nil {
+				return nil, err
+			}
+		}
+		if result.HasPrev {
+			values := sortValues(docs[0], sort)
+			if result.PrevCursor, err = encodeCursor(secret, cursorPayload{Direction: cursorPrev, Keys: keys, Values: values}); err != nil {
+				return nil, err
 			}
 		}
 	}
 
-	return &CursorResult{
-		Data:       tempResults,
-		NextCursor: nextCursor,
-		HasNext:    hasNext,
-		HasPrev:    params.Cursor != "",
-	}, nil
+	if results == nil {
+		data := make([]bson.M, len(docs))
+		for i, doc := range docs {
+			if err := bson.Unmarshal(doc, &data[i]); err != nil {
+				return nil, fmt.Errorf("failed to decode results: %w", err)
+			}
+		}
+		result.Data = data
+		return result, nil
+	}
+	if err := decodeRawInto(docs, results); err != nil {
+		return nil, err
+	}
+	result.Data = results
+	return result, nil
+}
+
+// decodeRawInto decodes documents into results, which must be a pointer to a slice
+func decodeRawInto(docs []bson.Raw, results interface{}) error {
+	rv := reflect.ValueOf(results)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("results must be a pointer to a slice")
+	}
+	slice := rv.Elem()
+	items := reflect.MakeSlice(slice.Type(), len(docs), len(docs))
+	for i, doc := range docs {
+		if err := bson.Unmarshal(doc, items.Index(i).Addr().Interface()); err != nil {
+			return fmt.Errorf("failed to decode results: %w", err)
+		}
+	}
+	slice.Set(items)
+	return nil
 }
 
 // PaginateFast performs fast pagination without counting total documents
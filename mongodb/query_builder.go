@@ -1,15 +1,26 @@
 package mongodb
 
 import (
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// QueryBuilder provides a fluent interface for building MongoDB queries
+// QueryBuilder provides a fluent interface for building MongoDB queries.
+// Operator conditions on the same field are merged, e.g. WhereGreaterThan
+// followed by WhereLessThan produces {"$gt": a, "$lt": b}, while Where and the
+// other equality conditions replace earlier conditions on the field.
 type QueryBuilder struct {
-	filter bson.M
+	filter     bson.M
+	sort       bson.D
+	projection bson.M
+	limit      int64
+	skip       int64
+	schema     *Schema
+	err        error
 }
 
 // NewQueryBuilder creates a new QueryBuilder
@@ -20,108 +31,162 @@ func NewQueryBuilder() *QueryBuilder {
 	}
 }
 
+// NewTypedQueryBuilder creates a QueryBuilder that validates field names
+// against the bson fields of T; unknown fields are reported by Err
+func NewTypedQueryBuilder[T any]() *QueryBuilder {
+	qb := NewQueryBuilder()
+	qb.schema = SchemaOf[T]()
+	return qb
+}
+
+// Group creates an empty builder with the same schema, for nested OrGroup and
+// AndGroup conditions
+func (qb *QueryBuilder) Group() *QueryBuilder {
+	group := NewQueryBuilder()
+	group.schema = qb.schema
+	return group
+}
+
+// Err returns the first invalid field name used with a typed builder
+func (qb *QueryBuilder) Err() error {
+	return qb.err
+}
+
 // Where adds an equality condition
 func (qb *QueryBuilder) Where(field string, value interface{}) *QueryBuilder {
-	qb.filter[field] = value
+	qb.filter[qb.field(field)] = value
+	return qb
+}
+
+// BlindIndexer computes blind indexes of encrypted fields, e.g. *encryption.Encryptor
+type BlindIndexer interface {
+	BlindIndex(field, value string) string
+}
+
+// WhereBlindIndex adds an equality condition on an encrypted field through
+// its blind index field (e.g. "phone_bidx")
+func (qb *QueryBuilder) WhereBlindIndex(field, value string, indexer BlindIndexer) *QueryBuilder {
+	qb.filter[qb.field(field)] = indexer.BlindIndex(field, value)
 	return qb
 }
 
 // WhereIn adds an $in condition
 func (qb *QueryBuilder) WhereIn(field string, values interface{}) *QueryBuilder {
-	qb.filter[field] = bson.M{"$in": values}
-	return qb
+	return qb.merge(field, bson.M{"$in": values})
 }
 
 // WhereNotIn adds a $nin condition
 func (qb *QueryBuilder) WhereNotIn(field string, values interface{}) *QueryBuilder {
-	qb.filter[field] = bson.M{"$nin": values}
-	return qb
+	return qb.merge(field, bson.M{"$nin": values})
+}
+
+// WhereNotEqual adds a $ne condition
+func (qb *QueryBuilder) WhereNotEqual(field string, value interface{}) *QueryBuilder {
+	return qb.merge(field, bson.M{"$ne": value})
 }
 
 // WhereGreaterThan adds a $gt condition
 func (qb *QueryBuilder) WhereGreaterThan(field string, value interface{}) *QueryBuilder {
-	qb.filter[field] = bson.M{"$gt": value}
-	return qb
+	return qb.merge(field, bson.M{"$gt": value})
 }
 
 // WhereGreaterThanOrEqual adds a $gte condition
 func (qb *QueryBuilder) WhereGreaterThanOrEqual(field string, value interface{}) *QueryBuilder {
-	qb.filter[field] = bson.M{"$gte": value}
-	return qb
+	return qb.merge(field, bson.M{"$gte": value})
 }
 
 // WhereLessThan adds a $lt condition
 func (qb *QueryBuilder) WhereLessThan(field string, value interface{}) *QueryBuilder {
-	qb.filter[field] = bson.M{"$lt": value}
-	return qb
+	return qb.merge(field, bson.M{"$lt": value})
 }
 
 // WhereLessThanOrEqual adds a $lte condition
 func (qb *QueryBuilder) WhereLessThanOrEqual(field string, value interface{}) *QueryBuilder {
-	qb.filter[field] = bson.M{"$lte": value}
-	return qb
+	return qb.merge(field, bson.M{"$lte": value})
 }
 
 // WhereBetween adds a range condition ($gte and $lte)
 func (qb *QueryBuilder) WhereBetween(field string, min, max interface{}) *QueryBuilder {
-	qb.filter[field] = bson.M{
+	return qb.merge(field, bson.M{
 		"$gte": min,
 		"$lte": max,
-	}
-	return qb
+	})
 }
 
 // WhereRegex adds a regex pattern matching condition
 func (qb *QueryBuilder) WhereRegex(field string, pattern string, options string) *QueryBuilder {
-	qb.filter[field] = bson.M{
+	return qb.merge(field, bson.M{
 		"$regex":   pattern,
 		"$options": options,
-	}
-	return qb
+	})
 }
 
 // WhereExists checks if a field exists
 func (qb *QueryBuilder) WhereExists(field string, exists bool) *QueryBuilder {
-	qb.filter[field] = bson.M{"$exists": exists}
-	return qb
+	return qb.merge(field, bson.M{"$exists": exists})
 }
 
 // WhereNull checks if a field is null
 func (qb *QueryBuilder) WhereNull(field string) *QueryBuilder {
-	qb.filter[field] = nil
+	qb.filter[qb.field(field)] = nil
 	return qb
 }
 
 // WhereNotNull checks if a field is not null
 func (qb *QueryBuilder) WhereNotNull(field string) *QueryBuilder {
-	qb.filter[field] = bson.M{"$ne": nil}
-	return qb
+	return qb.merge(field, bson.M{"$ne": nil})
 }
 
 // WhereArrayContains checks if an array contains a value
 func (qb *QueryBuilder) WhereArrayContains(field string, value interface{}) *QueryBuilder {
-	qb.filter[field] = bson.M{"$elemMatch": bson.M{"$eq": value}}
-	return qb
+	return qb.merge(field, bson.M{"$elemMatch": bson.M{"$eq": value}})
 }
 
 // WhereArraySize checks the size of an array
 func (qb *QueryBuilder) WhereArraySize(field string, size int) *QueryBuilder {
-	qb.filter[field] = bson.M{"$size": size}
-	return qb
+	return qb.merge(field, bson.M{"$size": size})
 }
 
-// Or adds an $or condition with multiple sub-conditions
+// Or adds an $or condition with multiple sub-conditions. A second Or is
+// combined with the first through $and, so both must match.
 func (qb *QueryBuilder) Or(conditions ...bson.M) *QueryBuilder {
+	if _, exists := qb.filter["$or"]; exists {
+		return qb.And(bson.M{"$or": conditions})
+	}
 	qb.filter["$or"] = conditions
 	return qb
 }
 
-// And adds an $and condition with multiple sub-conditions
+// And adds an $and condition with multiple sub-conditions, appending to
+// earlier And conditions
 func (qb *QueryBuilder) And(conditions ...bson.M) *QueryBuilder {
-	qb.filter["$and"] = conditions
+	existing, _ := qb.filter["$and"].([]bson.M)
+	qb.filter["$and"] = append(existing[:len(existing):len(existing)], conditions...)
 	return qb
 }
 
+// OrGroup adds an $or condition built from nested builders, e.g.
+// qb.OrGroup(qb.Group().Where("role", "admin"), qb.Group().Where("owner_id", id))
+func (qb *QueryBuilder) OrGroup(groups ...*QueryBuilder) *QueryBuilder {
+	return qb.Or(qb.groupFilters(groups)...)
+}
+
+// AndGroup adds an $and condition built from nested builders
+func (qb *QueryBuilder) AndGroup(groups ...*QueryBuilder) *QueryBuilder {
+	return qb.And(qb.groupFilters(groups)...)
+}
+
+func (qb *QueryBuilder) groupFilters(groups []*QueryBuilder) []bson.M {
+	conditions := make([]bson.M, 0, len(groups))
+	for _, group := range groups {
+		if group.err != nil && qb.err == nil {
+			qb.err = group.err
+		}
+		conditions = append(conditions, group.filter)
+	}
+	return conditions
+}
+
 // WhereObjectID adds an ObjectID equality condition
 // If the id string is invalid, the field will be set to match nothing (empty ObjectID)
 func (qb *QueryBuilder) WhereObjectID(field string, id string) *QueryBuilder {
@@ -130,7 +195,7 @@ func (qb *QueryBuilder) WhereObjectID(field string, id string) *QueryBuilder {
 		// Use zero ObjectID which will not match any real document
 		objectID = primitive.NilObjectID
 	}
-	qb.filter[field] = objectID
+	qb.filter[qb.field(field)] = objectID
 	return qb
 }
 
@@ -138,23 +203,20 @@ func (qb *QueryBuilder) WhereObjectID(field string, id string) *QueryBuilder {
 func (qb *QueryBuilder) WhereDate(field string, date time.Time) *QueryBuilder {
 	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 	endOfDay := startOfDay.Add(24 * time.Hour)
-	qb.filter[field] = bson.M{
+	return qb.merge(field, bson.M{
 		"$gte": startOfDay,
 		"$lt":  endOfDay,
-	}
-	return qb
+	})
 }
 
 // WhereDateAfter adds a condition for dates after a given date
 func (qb *QueryBuilder) WhereDateAfter(field string, date time.Time) *QueryBuilder {
-	qb.filter[field] = bson.M{"$gt": date}
-	return qb
+	return qb.merge(field, bson.M{"$gt": date})
 }
 
 // WhereDateBefore adds a condition for dates before a given date
 func (qb *QueryBuilder) WhereDateBefore(field string, date time.Time) *QueryBuilder {
-	qb.filter[field] = bson.M{"$lt": date}
-	return qb
+	return qb.merge(field, bson.M{"$lt": date})
 }
 
 // WhereTextSearch adds a full-text search condition
@@ -163,6 +225,52 @@ func (qb *QueryBuilder) WhereTextSearch(text string) *QueryBuilder {
 	return qb
 }
 
+// Sort adds a sort key; direction is 1 for ascending and -1 for descending.
+// Keys are applied in the order they were added.
+func (qb *QueryBuilder) Sort(field string, direction int) *QueryBuilder {
+	field = qb.field(field)
+	for i := range qb.sort {
+		if qb.sort[i].Key == field {
+			qb.sort[i].Value = direction
+			return qb
+		}
+	}
+	qb.sort = append(qb.sort, bson.E{Key: field, Value: direction})
+	return qb
+}
+
+// Select limits the returned fields to the given ones
+func (qb *QueryBuilder) Select(fields ...string) *QueryBuilder {
+	return qb.project(fields, 1)
+}
+
+// Exclude omits the given fields from the returned documents
+func (qb *QueryBuilder) Exclude(fields ...string) *QueryBuilder {
+	return qb.project(fields, 0)
+}
+
+func (qb *QueryBuilder) project(fields []string, include int) *QueryBuilder {
+	if qb.projection == nil {
+		qb.projection = make(bson.M, len(fields))
+	}
+	for _, field := range fields {
+		qb.projection[qb.field(field)] = include
+	}
+	return qb
+}
+
+// Limit sets the maximum number of documents to return
+func (qb *QueryBuilder) Limit(limit int64) *QueryBuilder {
+	qb.limit = limit
+	return qb
+}
+
+// Skip sets the number of documents to skip
+func (qb *QueryBuilder) Skip(skip int64) *QueryBuilder {
+	qb.skip = skip
+	return qb
+}
+
 // Build returns the final filter
 func (qb *QueryBuilder) Build() bson.M {
 	return qb.filter
@@ -174,6 +282,39 @@ func (qb *QueryBuilder) BuildWithTenant(tenantID string) bson.M {
 	return qb.filter
 }
 
+// FindOptions returns the sort, projection, limit and skip as find options
+func (qb *QueryBuilder) FindOptions() *options.FindOptions {
+	opts := options.Find()
+	if len(qb.sort) > 0 {
+		opts.SetSort(qb.sort)
+	}
+	if len(qb.projection) > 0 {
+		opts.SetProjection(qb.projection)
+	}
+	if qb.limit > 0 {
+		opts.SetLimit(qb.limit)
+	}
+	if qb.skip > 0 {
+		opts.SetSkip(qb.skip)
+	}
+	return opts
+}
+
+// FindOneOptions returns the sort, projection and skip as find-one options
+func (qb *QueryBuilder) FindOneOptions() *options.FindOneOptions {
+	opts := options.FindOne()
+	if len(qb.sort) > 0 {
+		opts.SetSort(qb.sort)
+	}
+	if len(qb.projection) > 0 {
+		opts.SetProjection(qb.projection)
+	}
+	if qb.skip > 0 {
+		opts.SetSkip(qb.skip)
+	}
+	return opts
+}
+
 // Clone creates a copy of the QueryBuilder for reusability
 // Performance: Pre-allocate with same capacity as source
 func (qb *QueryBuilder) Clone() *QueryBuilder {
@@ -181,21 +322,86 @@ func (qb *QueryBuilder) Clone() *QueryBuilder {
 	for k, v := range qb.filter {
 		newFilter[k] = v
 	}
-	return &QueryBuilder{
+	clone := &QueryBuilder{
 		filter: newFilter,
+		limit:  qb.limit,
+		skip:   qb.skip,
+		schema: qb.schema,
+		err:    qb.err,
+	}
+	if qb.sort != nil {
+		clone.sort = append(bson.D(nil), qb.sort...)
+	}
+	if qb.projection != nil {
+		clone.projection = make(bson.M, len(qb.projection))
+		for k, v := range qb.projection {
+			clone.projection[k] = v
+		}
 	}
+	return clone
 }
 
-// Reset clears all conditions
+// Reset clears all conditions and options
 // Performance: Reuse underlying map storage
 func (qb *QueryBuilder) Reset() *QueryBuilder {
 	// Clear map but keep allocated capacity
 	for k := range qb.filter {
 		delete(qb.filter, k)
 	}
+	qb.sort = qb.sort[:0]
+	qb.projection = nil
+	qb.limit = 0
+	qb.skip = 0
+	qb.err = nil
 	return qb
 }
 
+// field validates a field name against the schema of a typed builder
+func (qb *QueryBuilder) field(field string) string {
+	if qb.schema != nil && qb.err == nil {
+		qb.err = qb.schema.Validate(field)
+	}
+	return field
+}
+
+// merge adds operator conditions to a field, keeping other operators already
+// set on it. An earlier equality condition is kept as $eq. Operator documents
+// are copied rather than modified, so maps shared with clones stay unchanged.
+func (qb *QueryBuilder) merge(field string, operators bson.M) *QueryBuilder {
+	field = qb.field(field)
+	existing, exists := qb.filter[field]
+	if !exists {
+		qb.filter[field] = operators
+		return qb
+	}
+
+	merged := make(bson.M, len(operators)+2)
+	if current, ok := existing.(bson.M); ok && isOperatorDocument(current) {
+		for k, v := range current {
+			merged[k] = v
+		}
+	} else {
+		merged["$eq"] = existing
+	}
+	for k, v := range operators {
+		merged[k] = v
+	}
+	qb.filter[field] = merged
+	return qb
+}
+
+func isOperatorDocument(doc bson.M) bool {
+	if len(doc) == 0 {
+		return false
+	}
+	for k := range doc {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+	return true
+}
+
 // AggregationBuilder provides a fluent interface for building aggregation pipelines
 type AggregationBuilder struct {
 	pipeline []bson.M
@@ -0,0 +1,207 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type testAddress struct {
+	City    string `bson:"city"`
+	Country string
+}
+
+type testProfile struct {
+	BaseModel `bson:",inline"`
+	Name      string            `bson:"name"`
+	Status    string            `bson:"status"`
+	Verified  bool              `bson:"verified"`
+	Age       int               `bson:"age"`
+	Tags      []string          `bson:"tags"`
+	Address   *testAddress      `bson:"address"`
+	Previous  []testAddress     `bson:"previous"`
+	Meta      map[string]string `bson:"meta"`
+	Internal  string            `bson:"-"`
+}
+
+func TestQueryBuilder_MergesOperators(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*QueryBuilder) *QueryBuilder
+		expected bson.M
+	}{
+		{
+			name: "range on one field",
+			setup: func(qb *QueryBuilder) *QueryBuilder {
+				return qb.WhereGreaterThan("age", 18).WhereLessThan("age", 65)
+			},
+			expected: bson.M{"age": bson.M{"$gt": 18, "$lt": 65}},
+		},
+		{
+			name: "later operator replaces the same operator",
+			setup: func(qb *QueryBuilder) *QueryBuilder {
+				return qb.WhereGreaterThan("age", 18).WhereGreaterThan("age", 21)
+			},
+			expected: bson.M{"age": bson.M{"$gt": 21}},
+		},
+		{
+			name: "equality kept as $eq",
+			setup: func(qb *QueryBuilder) *QueryBuilder {
+				return qb.Where("status", "active").WhereExists("status", true)
+			},
+			expected: bson.M{"status": bson.M{"$eq": "active", "$exists": true}},
+		},
+		{
+			name: "equality replaces operators",
+			setup: func(qb *QueryBuilder) *QueryBuilder {
+				return qb.WhereIn("status", []string{"a", "b"}).Where("status", "c")
+			},
+			expected: bson.M{"status": "c"},
+		},
+		{
+			name: "second Or is combined with $and",
+			setup: func(qb *QueryBuilder) *QueryBuilder {
+				return qb.Or(bson.M{"a": 1}, bson.M{"b": 1}).Or(bson.M{"c": 1}, bson.M{"d": 1})
+			},
+			expected: bson.M{
+				"$or":  []bson.M{{"a": 1}, {"b": 1}},
+				"$and": []bson.M{{"$or": []bson.M{{"c": 1}, {"d": 1}}}},
+			},
+		},
+		{
+			name: "nested groups",
+			setup: func(qb *QueryBuilder) *QueryBuilder {
+				return qb.Where("tenant_id", "acme").OrGroup(
+					qb.Group().Where("role", "admin"),
+					qb.Group().Where("owner_id", "u1").WhereNotEqual("status", "draft"),
+				)
+			},
+			expected: bson.M{
+				"tenant_id": "acme",
+				"$or": []bson.M{
+					{"role": "admin"},
+					{"owner_id": "u1", "status": bson.M{"$ne": "draft"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.setup(NewQueryBuilder()).Build()
+			if !reflect.DeepEqual(filter, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, filter)
+			}
+		})
+	}
+}
+
+func TestQueryBuilder_CloneDoesNotShareConditions(t *testing.T) {
+	base := NewQueryBuilder().WhereGreaterThan("age", 18).And(bson.M{"a": 1}).Sort("age", -1)
+	clone := base.Clone().WhereLessThan("age", 65).And(bson.M{"b": 1}).Sort("name", 1)
+
+	if len(base.Build()["age"].(bson.M)) != 1 || len(base.Build()["$and"].([]bson.M)) != 1 || len(base.sort) != 1 {
+		t.Errorf("expected the base builder to be unchanged, got %v sorted by %v", base.Build(), base.sort)
+	}
+	if len(clone.Build()["age"].(bson.M)) != 2 || len(clone.Build()["$and"].([]bson.M)) != 2 {
+		t.Errorf("unexpected clone filter: %v", clone.Build())
+	}
+}
+
+func TestQueryBuilder_FindOptions(t *testing.T) {
+	qb := NewQueryBuilder().
+		Sort("created_at", -1).
+		Sort("_id", 1).
+		Sort("created_at", 1).
+		Select("name", "age").
+		Limit(20).
+		Skip(40)
+
+	opts := qb.FindOptions()
+	expectedSort := bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}
+	if !reflect.DeepEqual(opts.Sort, expectedSort) {
+		t.Errorf("expected sort %v, got %v", expectedSort, opts.Sort)
+	}
+	if !reflect.DeepEqual(opts.Projection, bson.M{"name": 1, "age": 1}) {
+		t.Errorf("unexpected projection: %v", opts.Projection)
+	}
+	if *opts.Limit != 20 || *opts.Skip != 40 {
+		t.Errorf("unexpected limit and skip: %d, %d", *opts.Limit, *opts.Skip)
+	}
+
+	qb.Reset()
+	opts = qb.FindOptions()
+	if opts.Sort != nil || opts.Projection != nil || opts.Limit != nil || opts.Skip != nil {
+		t.Errorf("expected empty options after reset, got %+v", opts)
+	}
+}
+
+func TestTypedQueryBuilder_ValidatesFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*QueryBuilder)
+		valid bool
+	}{
+		{"top-level field", func(qb *QueryBuilder) { qb.Where("name", "x") }, true},
+		{"inline base model field", func(qb *QueryBuilder) { qb.WhereDateAfter("created_at", time.Now()) }, true},
+		{"_id", func(qb *QueryBuilder) { qb.Sort("_id", 1) }, true},
+		{"nested pointer field", func(qb *QueryBuilder) { qb.Where("address.city", "Hanoi") }, true},
+		{"untagged field uses lowercase name", func(qb *QueryBuilder) { qb.Where("address.country", "VN") }, true},
+		{"array of documents", func(qb *QueryBuilder) { qb.Where("previous.city", "Hue") }, true},
+		{"array position", func(qb *QueryBuilder) { qb.Where("previous.0.city", "Hue") }, true},
+		{"array of scalars", func(qb *QueryBuilder) { qb.WhereArrayContains("tags", "go") }, true},
+		{"map keys", func(qb *QueryBuilder) { qb.Where("meta.source", "import") }, true},
+		{"Go field name", func(qb *QueryBuilder) { qb.Where("Name", "x") }, false},
+		{"skipped field", func(qb *QueryBuilder) { qb.Where("internal", "x") }, false},
+		{"unknown nested field", func(qb *QueryBuilder) { qb.Where("address.zip", "x") }, false},
+		{"path into scalar", func(qb *QueryBuilder) { qb.Where("name.first", "x") }, false},
+		{"unknown projection", func(qb *QueryBuilder) { qb.Select("name", "email") }, false},
+		{"unknown field in group", func(qb *QueryBuilder) { qb.OrGroup(qb.Group().Where("emial", "x")) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := NewTypedQueryBuilder[testProfile]()
+			tt.setup(qb)
+			err := qb.Err()
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrUnknownField) {
+				t.Errorf("expected ErrUnknownField, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSchema_Field(t *testing.T) {
+	schema := SchemaOf[testProfile]()
+	tests := []struct {
+		goPath   string
+		expected string
+		valid    bool
+	}{
+		{"Name", "name", true},
+		{"ID", "_id", true},
+		{"Address.City", "address.city", true},
+		{"Previous.Country", "previous.country", true},
+		{"Address.Zip", "", false},
+		{"Internal", "", false},
+		{"Name.First", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goPath, func(t *testing.T) {
+			path, err := schema.Field(tt.goPath)
+			if tt.valid && (err != nil || path != tt.expected) {
+				t.Errorf("expected %q, got %q (%v)", tt.expected, path, err)
+			}
+			if !tt.valid && !errors.Is(err, ErrUnknownField) {
+				t.Errorf("expected ErrUnknownField, got %q (%v)", path, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "github.com/vhvplatform/go-shared/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FieldType is the type query-string values of a field are converted to
+type FieldType int
+
+const (
+	FieldString FieldType = iota
+	FieldInt
+	FieldFloat
+	FieldBool
+	FieldTime // RFC 3339 or YYYY-MM-DD
+	FieldObjectID
+)
+
+// Query-string operators, used as field[op]=value
+const (
+	OpEq     = "eq"
+	OpNe     = "ne"
+	OpGt     = "gt"
+	OpGte    = "gte"
+	OpLt     = "lt"
+	OpLte    = "lte"
+	OpIn     = "in"  // comma-separated values
+	OpNin    = "nin" // comma-separated values
+	OpExists = "exists"
+	OpLike   = "like" // case-insensitive substring match
+)
+
+// Reserved query-string parameters
+const (
+	ParamSort     = "sort"   // e.g. -created_at,name
+	ParamFields   = "fields" // e.g. name,email
+	ParamSearch   = "q"      // full-text search
+	ParamPage     = "page"
+	ParamPageSize = "page_size"
+	ParamLimit    = "limit" // cursor pagination page size
+	ParamCursor   = "cursor"
+)
+
+var defaultOperators = map[FieldType][]string{
+	FieldString:   {OpEq, OpNe, OpIn, OpNin, OpLike, OpExists},
+	FieldInt:      {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNin, OpExists},
+	FieldFloat:    {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNin, OpExists},
+	FieldBool:     {OpEq, OpNe, OpExists},
+	FieldTime:     {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpExists},
+	FieldObjectID: {OpEq, OpNe, OpIn, OpNin, OpExists},
+}
+
+// QueryField whitelists a query-string parameter
+type QueryField struct {
+	Type       FieldType
+	Column     string   // bson path (default: the parameter name)
+	Operators  []string // allowed operators (default: all operators supported by the type)
+	Sortable   bool
+	Selectable bool // may be listed in ?fields=
+}
+
+// QueryParserConfig configures a QueryParser
+type QueryParserConfig struct {
+	Fields          map[string]QueryField // keyed by query-string parameter name
+	TextSearch      bool                  // allow ?q= full-text search (needs a text index)
+	DefaultSort     string                // e.g. "-created_at"
+	DefaultPageSize int64                 // default: 20
+	MaxInValues     int                   // default: 100
+	CursorSecret    []byte                // cursor signing key (default: the key set with SetCursorSecret)
+	AllowUnknown    bool                  // ignore parameters that are not whitelisted instead of rejecting them
+	Schema          *Schema               // validates columns against a model, e.g. SchemaOf[User]()
+}
+
+// QueryParser converts a whitelisted query-string grammar into a QueryBuilder
+// and pagination parameters:
+//
+//	?status=active&total[gte]=100&tags[in]=a,b&q=refund&sort=-created_at&fields=number,total&page=2
+type QueryParser struct {
+	fields          map[string]QueryField
+	textSearch      bool
+	defaultSort     bson.D
+	defaultPageSize int64
+	maxInValues     int
+	cursorSecret    []byte
+	allowUnknown    bool
+}
+
+// NewQueryParser creates a query parser, checking operators and columns
+func NewQueryParser(config QueryParserConfig) (*QueryParser, error) {
+	if config.DefaultPageSize <= 0 {
+		config.DefaultPageSize = 20
+	}
+	if config.DefaultPageSize > MaxPageSize {
+		return nil, fmt.Errorf("default page size cannot exceed %d", MaxPageSize)
+	}
+	if config.MaxInValues <= 0 {
+		config.MaxInValues = 100
+	}
+
+	fields := make(map[string]QueryField, len(config.Fields))
+	for name, field := range config.Fields {
+		if field.Column == "" {
+			field.Column = name
+		}
+		supported, ok := defaultOperators[field.Type]
+		if !ok {
+			return nil, fmt.Errorf("field %q has an unknown type", name)
+		}
+		if field.Operators == nil {
+			field.Operators = supported
+		}
+		for _, op := range field.Operators {
+			if !containsString(supported, op) {
+				return nil, fmt.Errorf("operator %q is not supported for field %q", op, name)
+			}
+		}
+		if config.Schema != nil {
+			if err := config.Schema.Validate(field.Column); err != nil {
+				return nil, err
+			}
+		}
+		fields[name] = field
+	}
+
+	p := &QueryParser{
+		fields:          fields,
+		textSearch:      config.TextSearch,
+		defaultPageSize: config.DefaultPageSize,
+		maxInValues:     config.MaxInValues,
+		cursorSecret:    config.CursorSecret,
+		allowUnknown:    config.AllowUnknown,
+	}
+	if config.DefaultSort != "" {
+		order, errs := p.parseSort(config.DefaultSort)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("invalid default sort: %s", errs[0].Message)
+		}
+		p.defaultSort = order
+	}
+	return p, nil
+}
+
+// ParsedQuery is the result of parsing a query string
+type ParsedQuery struct {
+	Builder  *QueryBuilder // filter, sort and projection
+	Sort     bson.D
+	Page     int64
+	PageSize int64 // page_size, or limit for cursor pagination
+	Cursor   string
+
+	cursorSecret []byte
+}
+
+// Pagination returns offset pagination parameters
+func (q *ParsedQuery) Pagination() *PaginationParams {
+	return &PaginationParams{Page: q.Page, PageSize: q.PageSize, Sort: q.Sort}
+}
+
+// CursorPagination returns cursor pagination parameters
+func (q *ParsedQuery) CursorPagination() *CursorPagination {
+	return &CursorPagination{Limit: q.PageSize, Cursor: q.Cursor, Sort: q.Sort, Secret: q.cursorSecret}
+}
+
+// QueryParamError describes an invalid query-string parameter
+type QueryParamError struct {
+	Field   string `json:"field"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// QueryError lists the invalid parameters of a query string. It converts to
+// a 400 errors.Validation, so handlers can render it with response.AppError.
+type QueryError struct {
+	Errors []QueryParamError `json:"errors"`
+}
+
+// Error implements the error interface
+func (e *QueryError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// AppError converts the error to an HTTP 400 validation error
+func (e *QueryError) AppError() *apperrors.AppError {
+	return apperrors.Validation("Invalid query parameters").WithDetails(map[string]interface{}{
+		"errors": e.Errors,
+	})
+}
+
+// Parse converts query-string values, e.g. c.Request.URL.Query(), into a
+// query. All invalid parameters are reported together in a *QueryError.
+func (p *QueryParser) Parse(values url.Values) (*ParsedQuery, error) {
+	qb := NewQueryBuilder()
+	query := &ParsedQuery{Builder: qb, Sort: p.defaultSort, Page: 1, PageSize: p.defaultPageSize, cursorSecret: p.cursorSecret}
+	var errs []QueryParamError
+
+	// Sorted keys keep error order and merged conditions deterministic
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if len(values[key]) > 1 {
+			errs = append(errs, QueryParamError{Field: key, Message: fmt.Sprintf("%s must be given once", key)})
+			continue
+		}
+		value := values.Get(key)
+
+		switch key {
+		case ParamSort:
+			order, sortErrs := p.parseSort(value)
+			errs = append(errs, sortErrs...)
+			query.Sort = order
+		case ParamFields:
+			errs = append(errs, p.parseFields(qb, value)...)
+		case ParamSearch:
+			if !p.textSearch {
+				errs = append(errs, QueryParamError{Field: key, Message: "text search is not supported"})
+			} else if value != "" {
+				qb.WhereTextSearch(value)
+			}
+		case ParamPage:
+			page, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || page < 1 {
+				errs = append(errs, QueryParamError{Field: key, Value: value, Message: "page must be a positive integer"})
+				continue
+			}
+			query.Page = page
+		case ParamPageSize, ParamLimit:
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 1 || size > MaxPageSize {
+				errs = append(errs, QueryParamError{Field: key, Value: value, Message: fmt.Sprintf("%s must be between 1 and %d", key, MaxPageSize)})
+				continue
+			}
+			query.PageSize = size
+		case ParamCursor:
+			query.Cursor = value
+		default:
+			if err := p.parseFilter(qb, key, value); err != nil {
+				errs = append(errs, *err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, &QueryError{Errors: errs}
+	}
+	for _, e := range query.Sort {
+		qb.Sort(e.Key, e.Value.(int))
+	}
+	return query, nil
+}
+
+func (p *QueryParser) parseFilter(qb *QueryBuilder, key, value string) *QueryParamError {
+	name, op := key, OpEq
+	if open := strings.IndexByte(key, '['); open > 0 && strings.HasSuffix(key, "]") {
+		name, op = key[:open], key[open+1:len(key)-1]
+	}
+
+	field, ok := p.fields[name]
+	if !ok {
+		if p.allowUnknown {
+			return nil
+		}
+		return &QueryParamError{Field: key, Message: fmt.Sprintf("filtering by %s is not supported", name)}
+	}
+	if !containsString(field.Operators, op) {
+		return &QueryParamError{Field: key, Message: fmt.Sprintf("operator %q is not supported for %s", op, name)}
+	}
+
+	switch op {
+	case OpIn, OpNin:
+		parts := strings.Split(value, ",")
+		if len(parts) > p.maxInValues {
+			return &QueryParamError{Field: key, Message: fmt.Sprintf("%s accepts at most %d values", key, p.maxInValues)}
+		}
+		list := make(bson.A, 0, len(parts))
+		for _, part := range parts {
+			v, err := coerceQueryValue(field.Type, strings.TrimSpace(part))
+			if err != nil {
+				return &QueryParamError{Field: key, Value: part, Message: fmt.Sprintf("%s %s", name, err)}
+			}
+			list = append(list, v)
+		}
+		if op == OpIn {
+			qb.WhereIn(field.Column, list)
+		} else {
+			qb.WhereNotIn(field.Column, list)
+		}
+		return nil
+	case OpExists:
+		exists, err := strconv.ParseBool(value)
+		if err != nil {
+			return &QueryParamError{Field: key, Value: value, Message: fmt.Sprintf("%s must be true or false", key)}
+		}
+		qb.WhereExists(field.Column, exists)
+		return nil
+	case OpLike:
+		qb.WhereRegex(field.Column, regexp.QuoteMeta(value), "i")
+		return nil
+	}
+
+	v, err := coerceQueryValue(field.Type, value)
+	if err != nil {
+		return &QueryParamError{Field: key, Value: value, Message: fmt.Sprintf("%s %s", name, err)}
+	}
+	switch op {
+	case OpEq:
+		qb.Where(field.Column, v)
+	case OpNe:
+		qb.WhereNotEqual(field.Column, v)
+	case OpGt:
+		qb.WhereGreaterThan(field.Column, v)
+	case OpGte:
+		qb.WhereGreaterThanOrEqual(field.Column, v)
+	case OpLt:
+		qb.WhereLessThan(field.Column, v)
+	case OpLte:
+		qb.WhereLessThanOrEqual(field.Column, v)
+	}
+	return nil
+}
+
+func (p *QueryParser) parseSort(value string) (bson.D, []QueryParamError) {
+	var order bson.D
+	var errs []QueryParamError
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		direction := 1
+		if strings.HasPrefix(part, "-") {
+			direction = -1
+			part = part[1:]
+		} else {
+			part = strings.TrimPrefix(part, "+")
+		}
+		field, ok := p.fields[part]
+		if !ok || !field.Sortable {
+			errs = append(errs, QueryParamError{Field: ParamSort, Value: part, Message: fmt.Sprintf("sorting by %s is not supported", part)})
+			continue
+		}
+		order = append(order, bson.E{Key: field.Column, Value: direction})
+	}
+	return order, errs
+}
+
+func (p *QueryParser) parseFields(qb *QueryBuilder, value string) []QueryParamError {
+	var errs []QueryParamError
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, ok := p.fields[part]
+		if !ok || !field.Selectable {
+			errs = append(errs, QueryParamError{Field: ParamFields, Value: part, Message: fmt.Sprintf("field %s cannot be selected", part)})
+			continue
+		}
+		qb.Select(field.Column)
+	}
+	return errs
+}
+
+// coerceQueryValue converts a query-string value to the field type; error
+// messages follow the field name, e.g. "total must be an integer"
+func coerceQueryValue(fieldType FieldType, value string) (interface{}, error) {
+	switch fieldType {
+	case FieldInt:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return v, nil
+	case FieldFloat:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return v, nil
+	case FieldBool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return v, nil
+	case FieldTime:
+		if v, err := time.Parse(time.RFC3339, value); err == nil {
+			return v, nil
+		}
+		v, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, errors.New("must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		return v, nil
+	case FieldObjectID:
+		v, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, errors.New("must be a valid ID")
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
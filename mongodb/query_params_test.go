@@ -0,0 +1,138 @@
+package mongodb
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	apperrors "github.com/vhvplatform/go-shared/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func newTestQueryParser(t *testing.T) *QueryParser {
+	t.Helper()
+	parser, err := NewQueryParser(QueryParserConfig{
+		Fields: map[string]QueryField{
+			"name":       {Sortable: true, Selectable: true},
+			"status":     {Operators: []string{OpEq, OpIn}},
+			"age":        {Type: FieldInt, Sortable: true, Selectable: true},
+			"city":       {Column: "address.city", Selectable: true},
+			"created_at": {Type: FieldTime, Sortable: true},
+			"verified":   {Type: FieldBool},
+		},
+		TextSearch:  true,
+		DefaultSort: "-created_at",
+		Schema:      SchemaOf[testProfile](),
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	return parser
+}
+
+func TestQueryParser_Parse(t *testing.T) {
+	parser := newTestQueryParser(t)
+	tests := []struct {
+		name     string
+		query    string
+		expected bson.M
+	}{
+		{"equality", "name=alice", bson.M{"name": "alice"}},
+		{"range merged", "age[gte]=18&age[lt]=65", bson.M{"age": bson.M{"$gte": int64(18), "$lt": int64(65)}}},
+		{"in list", "status[in]=active,pending", bson.M{"status": bson.M{"$in": bson.A{"active", "pending"}}}},
+		{"column mapping", "city=Hanoi", bson.M{"address.city": "Hanoi"}},
+		{"like is escaped", "name[like]=a.b", bson.M{"name": bson.M{"$regex": `a\.b`, "$options": "i"}}},
+		{"bool", "verified=true", bson.M{"verified": true}},
+		{"exists", "age[exists]=false", bson.M{"age": bson.M{"$exists": false}}},
+		{"date", "created_at[gte]=2026-01-02", bson.M{"created_at": bson.M{"$gte": time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)}}},
+		{"text search", "q=refund", bson.M{"$text": bson.M{"$search": "refund"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			query, err := parser.Parse(values)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if filter := query.Builder.Build(); !reflect.DeepEqual(filter, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, filter)
+			}
+		})
+	}
+}
+
+func TestQueryParser_Pagination(t *testing.T) {
+	parser := newTestQueryParser(t)
+
+	values, _ := url.ParseQuery("sort=name,-age&fields=name,city&page=3&page_size=50")
+	query, err := parser.Parse(values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	params := query.Pagination()
+	expectedSort := bson.D{{Key: "name", Value: 1}, {Key: "age", Value: -1}}
+	if params.Page != 3 || params.PageSize != 50 || !reflect.DeepEqual(params.Sort, expectedSort) {
+		t.Errorf("unexpected pagination: %+v", params)
+	}
+	if opts := query.Builder.FindOptions(); !reflect.DeepEqual(opts.Projection, bson.M{"name": 1, "address.city": 1}) {
+		t.Errorf("unexpected projection: %v", opts.Projection)
+	}
+
+	values, _ = url.ParseQuery("limit=10&cursor=abc")
+	query, err = parser.Parse(values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cursor := query.CursorPagination()
+	if cursor.Limit != 10 || cursor.Cursor != "abc" || !reflect.DeepEqual(cursor.Sort, bson.D{{Key: "created_at", Value: -1}}) {
+		t.Errorf("expected default sort and cursor, got %+v", cursor)
+	}
+}
+
+func TestQueryParser_Errors(t *testing.T) {
+	parser := newTestQueryParser(t)
+
+	values, _ := url.ParseQuery("age=old&status[ne]=x&email=a&sort=city&fields=created_at&page=0&page_size=500&name=a&name=b")
+	_, err := parser.Parse(values)
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("expected QueryError, got %v", err)
+	}
+
+	fields := make([]string, len(queryErr.Errors))
+	for i, e := range queryErr.Errors {
+		fields[i] = e.Field
+	}
+	expected := []string{"age", "email", "fields", "name", "page", "page_size", "sort", "status[ne]"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected errors for %v, got %v", expected, queryErr.Errors)
+	}
+
+	appErr := apperrors.FromError(err)
+	if appErr.StatusCode != http.StatusBadRequest || appErr.Code != apperrors.ErrCodeValidation || appErr.Details["errors"] == nil {
+		t.Errorf("unexpected application error: %+v", appErr)
+	}
+}
+
+func TestNewQueryParser_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config QueryParserConfig
+	}{
+		{"unsupported operator", QueryParserConfig{Fields: map[string]QueryField{"verified": {Type: FieldBool, Operators: []string{OpGt}}}}},
+		{"unknown column", QueryParserConfig{Fields: map[string]QueryField{"email": {}}, Schema: SchemaOf[testProfile]()}},
+		{"unsortable default sort", QueryParserConfig{Fields: map[string]QueryField{"name": {}}, DefaultSort: "name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewQueryParser(tt.config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
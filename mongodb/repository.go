@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/vhvplatform/go-shared/audit"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -18,6 +19,14 @@ type BaseModel struct {
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
 	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	// Version is maintained by repositories with versioning enabled (optimistic concurrency)
+	Version int64 `bson:"version,omitempty" json:"version,omitempty"`
+}
+
+// Versioned interface for models that support optimistic concurrency
+type Versioned interface {
+	GetVersion() int64
+	SetVersion(version int64)
 }
 
 // SoftDeletable interface for models that support soft delete
@@ -41,6 +50,8 @@ type BaseRepository struct {
 	collection   *mongo.Collection
 	client       *Client
 	softDelete   bool
+	versioning   bool
+	auditor      *audit.Auditor
 	queryBuilder *QueryBuilder
 }
 
@@ -49,6 +60,10 @@ type RepositoryConfig struct {
 	Collection *mongo.Collection
 	Client     *Client
 	SoftDelete bool // Enable soft delete functionality
+	Versioning bool // Enable the version field for optimistic concurrency
+	// Auditor records the changes made through the repository. When recording
+	// fails after a write, the write result is returned together with the error.
+	Auditor *audit.Auditor
 }
 
 // NewBaseRepository creates a new base repository
@@ -57,6 +72,8 @@ func NewBaseRepository(config RepositoryConfig) *BaseRepository {
 		collection:   config.Collection,
 		client:       config.Client,
 		softDelete:   config.SoftDelete,
+		versioning:   config.Versioning,
+		auditor:      config.Auditor,
 		queryBuilder: NewQueryBuilder(),
 	}
 }
@@ -101,7 +118,12 @@ func (r *BaseRepository) Create(ctx context.Context, document interface{}) (*mon
 		doc["updated_at"] = now
 	}
 
-	return r.collection.InsertOne(ctx, document)
+	r.initVersion(document)
+	result, err := r.collection.InsertOne(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+	return result, auditInsert(ctx, r.auditor, r.collection, result.InsertedID)
 }
 
 // CreateMany inserts multiple documents
@@ -120,9 +142,14 @@ func (r *BaseRepository) CreateMany(ctx context.Context, documents []interface{}
 			doc["created_at"] = now
 			doc["updated_at"] = now
 		}
+		r.initVersion(document)
 	}
 
-	return r.collection.InsertMany(ctx, documents)
+	result, err := r.collection.InsertMany(ctx, documents)
+	if err != nil {
+		return result, err
+	}
+	return result, auditInsert(ctx, r.auditor, r.collection, result.InsertedIDs...)
 }
 
 // FindByID finds a document by ID
@@ -167,9 +194,44 @@ func (r *BaseRepository) Update(ctx context.Context, filter bson.M, update inter
 		} else {
 			updateDoc["$set"] = bson.M{"updated_at": time.Now()}
 		}
+		if err := r.incVersion(updateDoc); err != nil {
+			return nil, err
+		}
+	}
+
+	return auditedUpdateOne(ctx, r.auditor, r.collection, filter, update, audit.OperationUpdate, opts...)
+}
+
+// UpdateWithVersion updates a single document only if its version equals
+// version (compare-and-swap) and increments the version. It returns a
+// *VersionConflictError when the document exists with another version and
+// mongo.ErrNoDocuments when it does not exist.
+func (r *BaseRepository) UpdateWithVersion(ctx context.Context, filter bson.M, version int64, update bson.M) (*mongo.UpdateResult, error) {
+	filter = r.addSoftDeleteFilter(filter)
+	casFilter := copyFilter(filter)
+	casFilter["version"] = versionCondition(version)
+
+	update, err := withUpdatedAt(update, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := incVersion(update); err != nil {
+		return nil, err
+	}
+
+	result, err := auditedUpdateOne(ctx, r.auditor, r.collection, casFilter, update, audit.OperationUpdate)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, versionMismatch(ctx, r.collection, filter, version)
 	}
+	return result, nil
+}
 
-	return r.collection.UpdateOne(ctx, filter, update, opts...)
+// UpdateByIDWithVersion updates a document by ID if its version equals version
+func (r *BaseRepository) UpdateByIDWithVersion(ctx context.Context, id primitive.ObjectID, version int64, update bson.M) (*mongo.UpdateResult, error) {
+	return r.UpdateWithVersion(ctx, bson.M{"_id": id}, version, update)
 }
 
 // UpdateByID updates a document by ID
@@ -190,9 +252,14 @@ func (r *BaseRepository) UpdateMany(ctx context.Context, filter bson.M, update i
 		} else {
 			updateDoc["$set"] = bson.M{"updated_at": time.Now()}
 		}
+		if err := r.incVersion(updateDoc); err != nil {
+			return nil, err
+		}
 	}
 
-	return r.collection.UpdateMany(ctx, filter, update, opts...)
+	return auditedMany(ctx, r.auditor, r.collection, filter, audit.OperationUpdate, func(filter bson.M) (*mongo.UpdateResult, error) {
+		return r.collection.UpdateMany(ctx, filter, update, opts...)
+	})
 }
 
 // Delete deletes a single document (soft delete if enabled)
@@ -208,7 +275,7 @@ func (r *BaseRepository) Delete(ctx context.Context, filter bson.M, opts ...*opt
 				"updated_at": now,
 			},
 		}
-		result, err := r.collection.UpdateOne(ctx, filter, update)
+		result, err := auditedUpdateOne(ctx, r.auditor, r.collection, filter, update, audit.OperationSoftDelete)
 		if err != nil {
 			return nil, err
 		}
@@ -216,7 +283,7 @@ func (r *BaseRepository) Delete(ctx context.Context, filter bson.M, opts ...*opt
 	}
 
 	// Hard delete
-	return r.collection.DeleteOne(ctx, filter, opts...)
+	return auditedDeleteOne(ctx, r.auditor, r.collection, filter, opts...)
 }
 
 // DeleteByID deletes a document by ID (soft delete if enabled)
@@ -237,7 +304,9 @@ func (r *BaseRepository) DeleteMany(ctx context.Context, filter bson.M, opts ...
 				"updated_at": now,
 			},
 		}
-		result, err := r.collection.UpdateMany(ctx, filter, update)
+		result, err := auditedMany(ctx, r.auditor, r.collection, filter, audit.OperationSoftDelete, func(filter bson.M) (*mongo.UpdateResult, error) {
+			return r.collection.UpdateMany(ctx, filter, update)
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -245,13 +314,15 @@ func (r *BaseRepository) DeleteMany(ctx context.Context, filter bson.M, opts ...
 	}
 
 	// Hard delete
-	return r.collection.DeleteMany(ctx, filter, opts...)
+	return auditedMany(ctx, r.auditor, r.collection, filter, audit.OperationDelete, func(filter bson.M) (*mongo.DeleteResult, error) {
+		return r.collection.DeleteMany(ctx, filter, opts...)
+	})
 }
 
 // HardDelete permanently deletes a document (bypasses soft delete)
 func (r *BaseRepository) HardDelete(ctx context.Context, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
 	// Remove soft delete filter to allow deleting soft-deleted documents
-	return r.collection.DeleteOne(ctx, filter, opts...)
+	return auditedDeleteOne(ctx, r.auditor, r.collection, filter, opts...)
 }
 
 // HardDeleteByID permanently deletes a document by ID
@@ -273,7 +344,7 @@ func (r *BaseRepository) Restore(ctx context.Context, filter bson.M) (*mongo.Upd
 		"$set":   bson.M{"updated_at": time.Now()},
 	}
 
-	return r.collection.UpdateOne(ctx, filter, update)
+	return auditedUpdateOne(ctx, r.auditor, r.collection, filter, update, audit.OperationRestore)
 }
 
 // RestoreByID restores a soft-deleted document by ID
@@ -451,6 +522,26 @@ func (r *BaseRepository) EnsureIndexes(ctx context.Context, configs []IndexConfi
 
 // Helper methods for timestamp management
 
+// GetID returns the document ID
+func (bm *BaseModel) GetID() primitive.ObjectID {
+	return bm.ID
+}
+
+// SetID sets the document ID
+func (bm *BaseModel) SetID(id primitive.ObjectID) {
+	bm.ID = id
+}
+
+// GetVersion returns the document version
+func (bm *BaseModel) GetVersion() int64 {
+	return bm.Version
+}
+
+// SetVersion sets the document version
+func (bm *BaseModel) SetVersion(version int64) {
+	bm.Version = version
+}
+
 // SetCreatedAt sets the created_at timestamp on BaseModel
 func (bm *BaseModel) SetCreatedAt(t time.Time) {
 	bm.CreatedAt = t
@@ -460,3 +551,24 @@ func (bm *BaseModel) SetCreatedAt(t time.Time) {
 func (bm *BaseModel) SetUpdatedAt(t time.Time) {
 	bm.UpdatedAt = t
 }
+
+// initVersion sets the initial version of a new document when versioning is enabled
+func (r *BaseRepository) initVersion(document interface{}) {
+	if !r.versioning {
+		return
+	}
+	if model, ok := document.(Versioned); ok {
+		model.SetVersion(1)
+	}
+	if doc, ok := document.(bson.M); ok {
+		doc["version"] = int64(1)
+	}
+}
+
+// incVersion increments the version in an update when versioning is enabled
+func (r *BaseRepository) incVersion(update bson.M) error {
+	if !r.versioning {
+		return nil
+	}
+	return incVersion(update)
+}
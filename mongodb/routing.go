@@ -0,0 +1,325 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrPlacementNotFound is returned by a PlacementStore when a tenant has no explicit placement
+	ErrPlacementNotFound = errors.New("tenant placement not found")
+	// ErrInvalidPlacement is returned when a placement cannot be routed
+	ErrInvalidPlacement = errors.New("invalid tenant placement")
+)
+
+// IsolationStrategy describes how a tenant's data is physically isolated
+type IsolationStrategy string
+
+const (
+	// IsolationShared stores the tenant in shared collections, isolated by the tenant_id filter
+	IsolationShared IsolationStrategy = "shared"
+	// IsolationCollection stores the tenant in dedicated collections of the shared database
+	IsolationCollection IsolationStrategy = "collection"
+	// IsolationDatabase stores the tenant in a dedicated database of the shared cluster
+	IsolationDatabase IsolationStrategy = "database"
+	// IsolationCluster stores the tenant in a database on a dedicated cluster
+	IsolationCluster IsolationStrategy = "cluster"
+)
+
+// TenantPlacement maps a tenant to its isolation strategy
+type TenantPlacement struct {
+	TenantID string            `bson:"_id" json:"tenant_id"`
+	Strategy IsolationStrategy `bson:"strategy" json:"strategy"`
+	// Database is the dedicated database name (database and cluster strategies)
+	Database string `bson:"database,omitempty" json:"database,omitempty"`
+	// CollectionPrefix prefixes collection names (collection strategy, default: "{tenant}_")
+	CollectionPrefix string `bson:"collection_prefix,omitempty" json:"collection_prefix,omitempty"`
+	// URI is the connection string of the dedicated cluster (cluster strategy)
+	URI string `bson:"uri,omitempty" json:"-"`
+}
+
+// PlacementStore loads tenant placements
+type PlacementStore interface {
+	// Get returns the placement of a tenant or ErrPlacementNotFound
+	Get(ctx context.Context, tenantID string) (*TenantPlacement, error)
+}
+
+// MongoPlacementStore stores tenant placements in MongoDB
+type MongoPlacementStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPlacementStore creates a MongoDB-backed placement store
+func NewMongoPlacementStore(collection *mongo.Collection) *MongoPlacementStore {
+	return &MongoPlacementStore{collection: collection}
+}
+
+// Get returns the placement of a tenant
+func (s *MongoPlacementStore) Get(ctx context.Context, tenantID string) (*TenantPlacement, error) {
+	var placement TenantPlacement
+	if err := s.collection.FindOne(ctx, bson.M{"_id": tenantID}).Decode(&placement); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrPlacementNotFound
+		}
+		return nil, err
+	}
+	return &placement, nil
+}
+
+// Save creates or replaces the placement of a tenant
+func (s *MongoPlacementStore) Save(ctx context.Context, placement *TenantPlacement) error {
+	_, err := s.collection.ReplaceOne(ctx,
+		bson.M{"_id": placement.TenantID},
+		placement,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// StaticPlacements is a PlacementStore backed by a fixed map, e.g. from configuration
+type StaticPlacements map[string]TenantPlacement
+
+// Get returns the placement of a tenant
+func (m StaticPlacements) Get(ctx context.Context, tenantID string) (*TenantPlacement, error) {
+	placement, ok := m[tenantID]
+	if !ok {
+		return nil, ErrPlacementNotFound
+	}
+	placement.TenantID = tenantID
+	return &placement, nil
+}
+
+// TenantRouterConfig configures a TenantRouter
+type TenantRouterConfig struct {
+	Default        IsolationStrategy // strategy for tenants without a placement (default: IsolationShared)
+	DatabasePrefix string            // dedicated database name prefix when the placement has none (default: "tenant_")
+	PlacementTTL   time.Duration     // default: 5 minutes
+	// Cluster is the connection template for dedicated clusters; URI and Database are taken from the placement
+	Cluster Config
+}
+
+// TenantRouter resolves the collection holding a tenant's data according to
+// the tenant's placement, so repositories work unchanged whether a tenant is
+// shared, or has dedicated collections, databases or clusters. Connections to
+// dedicated clusters are pooled per URI and kept until Close.
+type TenantRouter struct {
+	client     *Client
+	placements PlacementStore
+	config     TenantRouterConfig
+	connect    func(ctx context.Context, cfg Config) (*Client, error)
+	now        func() time.Time
+
+	mu       sync.RWMutex
+	cache    map[string]placementCacheEntry
+	clusters map[string]*clusterConn
+}
+
+type placementCacheEntry struct {
+	placement *TenantPlacement
+	expiresAt time.Time
+}
+
+type clusterConn struct {
+	ready  chan struct{}
+	client *Client
+	err    error
+}
+
+// NewTenantRouter creates a tenant router over the shared client
+func NewTenantRouter(client *Client, placements PlacementStore, config TenantRouterConfig) *TenantRouter {
+	if config.Default == "" {
+		config.Default = IsolationShared
+	}
+	if config.DatabasePrefix == "" {
+		config.DatabasePrefix = "tenant_"
+	}
+	if config.PlacementTTL <= 0 {
+		config.PlacementTTL = 5 * time.Minute
+	}
+	return &TenantRouter{
+		client:     client,
+		placements: placements,
+		config:     config,
+		connect:    NewClient,
+		now:        time.Now,
+		cache:      make(map[string]placementCacheEntry),
+		clusters:   make(map[string]*clusterConn),
+	}
+}
+
+// Collection returns the named collection for the tenant in the context
+func (r *TenantRouter) Collection(ctx context.Context, name string) (*mongo.Collection, error) {
+	tenantID, err := pkgctx.GetTenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.TenantCollection(ctx, tenantID, name)
+}
+
+// Repository returns a tenant-scoped repository over the routed collection for
+// the tenant in the context. The tenant_id filter is kept for every strategy
+// as a second line of isolation.
+func (r *TenantRouter) Repository(ctx context.Context, name string) (*TenantRepository, error) {
+	tenantID, err := pkgctx.GetTenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := r.TenantCollection(ctx, tenantID, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewTenantRepository(collection, tenantID), nil
+}
+
+// TenantCollection returns the named collection for a tenant
+func (r *TenantRouter) TenantCollection(ctx context.Context, tenantID, name string) (*mongo.Collection, error) {
+	placement, err := r.Placement(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch placement.Strategy {
+	case IsolationShared:
+		return r.client.Collection(name), nil
+	case IsolationCollection:
+		prefix := placement.CollectionPrefix
+		if prefix == "" {
+			prefix = tenantID + "_"
+		}
+		return r.client.Collection(prefix + name), nil
+	case IsolationDatabase:
+		return r.client.Client.Database(r.databaseName(placement)).Collection(name), nil
+	case IsolationCluster:
+		client, err := r.clusterClient(ctx, placement.URI)
+		if err != nil {
+			return nil, err
+		}
+		return client.Client.Database(r.databaseName(placement)).Collection(name), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown strategy %q for tenant %s", ErrInvalidPlacement, placement.Strategy, tenantID)
+	}
+}
+
+// Placement returns the (cached) placement of a tenant, falling back to the default strategy
+func (r *TenantRouter) Placement(ctx context.Context, tenantID string) (*TenantPlacement, error) {
+	if !isValidTenantName(tenantID) {
+		return nil, fmt.Errorf("%w: tenant ID %q cannot be used in namespace names", ErrInvalidPlacement, tenantID)
+	}
+
+	now := r.now()
+	r.mu.RLock()
+	entry, ok := r.cache[tenantID]
+	r.mu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.placement, nil
+	}
+
+	placement, err := r.placements.Get(ctx, tenantID)
+	if errors.Is(err, ErrPlacementNotFound) {
+		placement, err = &TenantPlacement{TenantID: tenantID, Strategy: r.config.Default}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant placement: %w", err)
+	}
+	if placement.Strategy == IsolationCluster && placement.URI == "" {
+		return nil, fmt.Errorf("%w: cluster placement of tenant %s has no URI", ErrInvalidPlacement, tenantID)
+	}
+
+	r.mu.Lock()
+	r.cache[tenantID] = placementCacheEntry{placement: placement, expiresAt: now.Add(r.config.PlacementTTL)}
+	r.mu.Unlock()
+	return placement, nil
+}
+
+// Invalidate drops cached placements, e.g. after migrating a tenant; with no
+// tenant IDs the whole cache is cleared
+func (r *TenantRouter) Invalidate(tenantIDs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(tenantIDs) == 0 {
+		r.cache = make(map[string]placementCacheEntry)
+		return
+	}
+	for _, tenantID := range tenantIDs {
+		delete(r.cache, tenantID)
+	}
+}
+
+// Close disconnects all dedicated cluster clients. The shared client is owned by the caller.
+func (r *TenantRouter) Close(ctx context.Context) error {
+	r.mu.Lock()
+	clusters := r.clusters
+	r.clusters = make(map[string]*clusterConn)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, conn := range clusters {
+		<-conn.ready
+		if conn.client != nil {
+			if err := conn.client.Close(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *TenantRouter) databaseName(placement *TenantPlacement) string {
+	if placement.Database != "" {
+		return placement.Database
+	}
+	return r.config.DatabasePrefix + placement.TenantID
+}
+
+// clusterClient returns the pooled client for a cluster URI, connecting at most once concurrently
+func (r *TenantRouter) clusterClient(ctx context.Context, uri string) (*Client, error) {
+	r.mu.Lock()
+	conn, ok := r.clusters[uri]
+	if !ok {
+		conn = &clusterConn{ready: make(chan struct{})}
+		r.clusters[uri] = conn
+	}
+	r.mu.Unlock()
+
+	if ok {
+		select {
+		case <-conn.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if conn.err != nil {
+			return nil, conn.err
+		}
+		return conn.client, nil
+	}
+
+	cfg := r.config.Cluster
+	cfg.URI = uri
+	conn.client, conn.err = r.connect(ctx, cfg)
+	if conn.err != nil {
+		// Forget failed connections so the next request retries
+		r.mu.Lock()
+		if r.clusters[uri] == conn {
+			delete(r.clusters, uri)
+		}
+		r.mu.Unlock()
+		conn.err = fmt.Errorf("failed to connect to tenant cluster: %w", conn.err)
+	}
+	close(conn.ready)
+	return conn.client, conn.err
+}
+
+// isValidTenantName reports whether a tenant ID can be embedded in database and collection names
+func isValidTenantName(tenantID string) bool {
+	return tenantID != "" && !strings.ContainsAny(tenantID, "/\\. \"$*<>:|?\x00")
+}
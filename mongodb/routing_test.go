@@ -0,0 +1,78 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pkgctx "github.com/vhvplatform/go-shared/context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lazyClient returns a client that does not connect until an operation runs
+func lazyClient(t *testing.T, uri, database string) *Client {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return &Client{Client: client, database: database}
+}
+
+func TestTenantRouter_TenantCollection(t *testing.T) {
+	placements := StaticPlacements{
+		"acme":     {Strategy: IsolationCollection},
+		"globex":   {Strategy: IsolationDatabase},
+		"initech":  {Strategy: IsolationCluster, URI: "mongodb://initech.internal:27017", Database: "initech"},
+		"umbrella": {Strategy: IsolationCluster},
+	}
+	router := NewTenantRouter(lazyClient(t, "mongodb://localhost:27017", "app"), placements, TenantRouterConfig{})
+
+	connects := 0
+	router.connect = func(ctx context.Context, cfg Config) (*Client, error) {
+		connects++
+		return lazyClient(t, cfg.URI, ""), nil
+	}
+
+	tests := []struct {
+		tenantID   string
+		database   string
+		collection string
+		err        error
+	}{
+		{"hooli", "app", "orders", nil},
+		{"acme", "app", "acme_orders", nil},
+		{"globex", "tenant_globex", "orders", nil},
+		{"initech", "initech", "orders", nil},
+		{"umbrella", "", "", ErrInvalidPlacement},
+		{"../admin", "", "", ErrInvalidPlacement},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tenantID, func(t *testing.T) {
+			ctx := pkgctx.WithTenantID(context.Background(), tt.tenantID)
+			collection, err := router.Collection(ctx, "orders")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if collection.Database().Name() != tt.database || collection.Name() != tt.collection {
+				t.Errorf("expected %s.%s, got %s.%s", tt.database, tt.collection, collection.Database().Name(), collection.Name())
+			}
+		})
+	}
+
+	// Cluster connections are pooled per URI
+	router.TenantCollection(context.Background(), "initech", "invoices")
+	if connects != 1 {
+		t.Errorf("expected a single cluster connection, got %d", connects)
+	}
+
+	if _, err := router.Repository(context.Background(), "orders"); err == nil {
+		t.Error("expected an error without a tenant in the context")
+	}
+}
@@ -0,0 +1,193 @@
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrUnknownField is returned when a field name does not match the model's bson fields
+var ErrUnknownField = errors.New("unknown field")
+
+// Schema describes the bson fields of a model type so that field names used
+// in queries can be validated and Go field names mapped to bson paths
+type Schema struct {
+	typ    reflect.Type
+	fields map[string]schemaField // keyed by bson name
+	byGo   map[string]string      // Go field name to bson name
+}
+
+type schemaField struct {
+	typ reflect.Type
+}
+
+var schemaCache sync.Map // reflect.Type -> *Schema
+
+// SchemaOf returns the schema of the model type T
+func SchemaOf[T any]() *Schema {
+	var zero T
+	return schemaOf(reflect.TypeOf(&zero).Elem())
+}
+
+func schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if cached, ok := schemaCache.Load(t); ok {
+		return cached.(*Schema)
+	}
+	s := &Schema{typ: t, fields: make(map[string]schemaField), byGo: make(map[string]string)}
+	if t.Kind() == reflect.Struct {
+		s.collect(t)
+	}
+	cached, _ := schemaCache.LoadOrStore(t, s)
+	return cached.(*Schema)
+}
+
+func (s *Schema) collect(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		name, inline, skip := bsonFieldName(sf)
+		if skip {
+			continue
+		}
+		if inline {
+			ft := sf.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.collect(ft)
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		s.fields[name] = schemaField{typ: sf.Type}
+		s.byGo[sf.Name] = name
+	}
+}
+
+// bsonFieldName returns the field's bson key following the driver's rules:
+// the tag name, or the lowercased Go name when the tag has none
+func bsonFieldName(sf reflect.StructField) (name string, inline, skip bool) {
+	tag := sf.Tag.Get("bson")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			return "", true, false
+		}
+	}
+	if parts[0] != "" {
+		return parts[0], false, false
+	}
+	return strings.ToLower(sf.Name), false, false
+}
+
+// Validate checks that a dotted bson path exists in the model. Array
+// positions ("items.0.sku") are accepted, and paths into maps or untyped
+// values cannot be checked beyond the first unknown level.
+func (s *Schema) Validate(path string) error {
+	if path == "_id" || s.typ.Kind() != reflect.Struct {
+		return nil
+	}
+	schema := s
+	segments := strings.Split(path, ".")
+	for i := 0; i < len(segments); i++ {
+		field, ok := schema.fields[segments[i]]
+		if !ok {
+			return fmt.Errorf("%w %q in %s", ErrUnknownField, path, s.typ.Name())
+		}
+		t := field.typ
+		for {
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8 {
+				if i+1 < len(segments) && isArrayIndex(segments[i+1]) {
+					i++
+				}
+				t = t.Elem()
+				continue
+			}
+			break
+		}
+		if i+1 == len(segments) {
+			return nil
+		}
+		if !isDocumentType(t) {
+			if t.Kind() == reflect.Map || t.Kind() == reflect.Interface {
+				return nil
+			}
+			return fmt.Errorf("%w %q in %s", ErrUnknownField, path, s.typ.Name())
+		}
+		schema = schemaOf(t)
+	}
+	return nil
+}
+
+// Field maps a Go field path such as "Address.City" to its bson path
+// ("address.city")
+func (s *Schema) Field(goPath string) (string, error) {
+	schema := s
+	segments := strings.Split(goPath, ".")
+	paths := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		name, ok := schema.byGo[segment]
+		if !ok {
+			return "", fmt.Errorf("%w %q in %s", ErrUnknownField, goPath, s.typ.Name())
+		}
+		paths = append(paths, name)
+		if i+1 == len(segments) {
+			break
+		}
+		t := schema.fields[name].typ
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		if !isDocumentType(t) {
+			return "", fmt.Errorf("%w %q in %s", ErrUnknownField, goPath, s.typ.Name())
+		}
+		schema = schemaOf(t)
+	}
+	return strings.Join(paths, "."), nil
+}
+
+// MustField is like Field but panics on unknown fields; it is intended for
+// package-level field name declarations
+func (s *Schema) MustField(goPath string) string {
+	path, err := s.Field(goPath)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	primitiveTypesPkg = reflect.TypeOf(primitive.ObjectID{}).PkgPath()
+)
+
+// isDocumentType reports whether values of t are stored as embedded documents
+// whose fields can be addressed with dotted paths
+func isDocumentType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && t.PkgPath() != primitiveTypesPkg
+}
+
+func isArrayIndex(segment string) bool {
+	_, err := strconv.Atoi(segment)
+	return err == nil || segment == "$" || strings.HasPrefix(segment, "$[")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/vhvplatform/go-shared/audit"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -19,6 +20,7 @@ type TenantAware interface {
 type TenantRepository struct {
 	collection *mongo.Collection
 	tenantID   string
+	auditor    *audit.Auditor
 }
 
 // NewTenantRepository creates a new tenant-scoped repository
@@ -29,6 +31,14 @@ func NewTenantRepository(collection *mongo.Collection, tenantID string) *TenantR
 	}
 }
 
+// WithAuditor records the changes made through the repository with the
+// auditor. When recording fails after a write, the write result is returned
+// together with the error.
+func (tr *TenantRepository) WithAuditor(auditor *audit.Auditor) *TenantRepository {
+	tr.auditor = auditor
+	return tr
+}
+
 // addTenantFilter adds tenant_id to the filter
 func (tr *TenantRepository) addTenantFilter(filter bson.M) bson.M {
 	if filter == nil {
@@ -70,7 +80,11 @@ func (tr *TenantRepository) InsertOne(ctx context.Context, document interface{},
 		doc["tenant_id"] = tr.tenantID
 	}
 
-	return tr.collection.InsertOne(ctx, document, opts...)
+	result, err := tr.collection.InsertOne(ctx, document, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return result, auditInsert(ctx, tr.auditor, tr.collection, result.InsertedID)
 }
 
 // InsertMany inserts multiple documents with tenant_id
@@ -89,31 +103,39 @@ func (tr *TenantRepository) InsertMany(ctx context.Context, documents []interfac
 		}
 	}
 
-	return tr.collection.InsertMany(ctx, documents, opts...)
+	result, err := tr.collection.InsertMany(ctx, documents, opts...)
+	if err != nil {
+		return result, err
+	}
+	return result, auditInsert(ctx, tr.auditor, tr.collection, result.InsertedIDs...)
 }
 
 // UpdateOne updates a single document with tenant isolation
 func (tr *TenantRepository) UpdateOne(ctx context.Context, filter bson.M, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
 	filter = tr.addTenantFilter(filter)
-	return tr.collection.UpdateOne(ctx, filter, update, opts...)
+	return auditedUpdateOne(ctx, tr.auditor, tr.collection, filter, update, audit.OperationUpdate, opts...)
 }
 
 // UpdateMany updates multiple documents with tenant isolation
 func (tr *TenantRepository) UpdateMany(ctx context.Context, filter bson.M, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
 	filter = tr.addTenantFilter(filter)
-	return tr.collection.UpdateMany(ctx, filter, update, opts...)
+	return auditedMany(ctx, tr.auditor, tr.collection, filter, audit.OperationUpdate, func(filter bson.M) (*mongo.UpdateResult, error) {
+		return tr.collection.UpdateMany(ctx, filter, update, opts...)
+	})
 }
 
 // DeleteOne deletes a single document with tenant isolation
 func (tr *TenantRepository) DeleteOne(ctx context.Context, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
 	filter = tr.addTenantFilter(filter)
-	return tr.collection.DeleteOne(ctx, filter, opts...)
+	return auditedDeleteOne(ctx, tr.auditor, tr.collection, filter, opts...)
 }
 
 // DeleteMany deletes multiple documents with tenant isolation
 func (tr *TenantRepository) DeleteMany(ctx context.Context, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
 	filter = tr.addTenantFilter(filter)
-	return tr.collection.DeleteMany(ctx, filter, opts...)
+	return auditedMany(ctx, tr.auditor, tr.collection, filter, audit.OperationDelete, func(filter bson.M) (*mongo.DeleteResult, error) {
+		return tr.collection.DeleteMany(ctx, filter, opts...)
+	})
 }
 
 // CountDocuments counts documents with tenant isolation
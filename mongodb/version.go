@@ -0,0 +1,73 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apperrors "github.com/vhvplatform/go-shared/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrVersionConflict is returned when a compare-and-swap update finds a
+// different document version than expected
+var ErrVersionConflict = errors.New("version conflict")
+
+// VersionConflictError describes a failed compare-and-swap update. It matches
+// ErrVersionConflict with errors.Is and converts to a 409 errors.Conflict.
+type VersionConflictError struct {
+	Expected int64
+	Actual   int64
+}
+
+// Error implements the error interface
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict: expected version %d, found %d", e.Expected, e.Actual)
+}
+
+// Unwrap returns ErrVersionConflict
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// AppError converts the conflict to an HTTP 409 application error
+func (e *VersionConflictError) AppError() *apperrors.AppError {
+	return apperrors.Conflict("The resource was modified by another request").WithDetails(map[string]interface{}{
+		"expected_version": e.Expected,
+		"current_version":  e.Actual,
+	})
+}
+
+// versionCondition matches documents at the given version; documents created
+// before versioning was enabled have no version field and count as version 0
+func versionCondition(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// incVersion adds a version increment to an update, keeping the caller's
+// $inc fields whatever document form they use
+func incVersion(update bson.M) error {
+	inc, err := operatorFields(update, "$inc")
+	if err != nil {
+		return err
+	}
+	inc["version"] = int64(1)
+	update["$inc"] = inc
+	return nil
+}
+
+// versionMismatch explains why a compare-and-swap update matched nothing
+func versionMismatch(ctx context.Context, collection *mongo.Collection, filter bson.M, expected int64) error {
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+	err := collection.FindOne(ctx, filter).Decode(&current)
+	if err != nil {
+		return err
+	}
+	return &VersionConflictError{Expected: expected, Actual: current.Version}
+}
@@ -0,0 +1,57 @@
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	apperrors "github.com/vhvplatform/go-shared/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestVersionConflictError(t *testing.T) {
+	err := fmt.Errorf("update order: %w", &VersionConflictError{Expected: 2, Actual: 3})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Error("expected error to match ErrVersionConflict")
+	}
+
+	appErr := apperrors.FromError(err)
+	if appErr.StatusCode != http.StatusConflict || appErr.Details["current_version"] != int64(3) {
+		t.Errorf("expected 409 conflict with version details, got %+v", appErr)
+	}
+}
+
+func TestIncVersion(t *testing.T) {
+	original := bson.M{"$inc": bson.M{"views": 1}}
+	update := bson.M{"$inc": original["$inc"]}
+	if err := incVersion(update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inc := update["$inc"].(bson.M)
+	if inc["version"] != int64(1) || inc["views"] != 1 {
+		t.Errorf("unexpected increments: %v", inc)
+	}
+	if _, ok := original["$inc"].(bson.M)["version"]; ok {
+		t.Error("expected the original $inc document to be left unchanged")
+	}
+
+	for _, inc := range []interface{}{map[string]interface{}{"views": 1}, bson.D{{Key: "views", Value: 1}}} {
+		update := bson.M{"$inc": inc}
+		if err := incVersion(update); err != nil || !reflect.DeepEqual(update["$inc"], bson.M{"views": 1, "version": int64(1)}) {
+			t.Errorf("expected the caller's counters to be kept for %T, got %v (%v)", inc, update["$inc"], err)
+		}
+	}
+	if err := incVersion(bson.M{"$inc": struct{ Views int }{1}}); !errors.Is(err, ErrUnsupportedUpdate) {
+		t.Errorf("expected ErrUnsupportedUpdate, got %v", err)
+	}
+
+	if _, ok := versionCondition(0).(bson.M); !ok {
+		t.Error("expected version 0 to also match unversioned documents")
+	}
+	if versionCondition(4) != int64(4) {
+		t.Error("expected an exact match for other versions")
+	}
+}
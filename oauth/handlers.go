@@ -0,0 +1,123 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/response"
+)
+
+// SuccessHandler is called after a successful callback to issue the application session/JWT
+type SuccessHandler func(c *gin.Context, result *Result)
+
+// stateCookiePrefix names the cookie binding a login's state to the browser
+// that started it, per provider
+const stateCookiePrefix = "oauth_state_"
+
+// LoginHandler redirects to the provider named by the ":provider" route parameter.
+// An optional "redirect_to" query parameter (relative paths only) is carried
+// through the state and returned in Result.State.RedirectTo. A short-lived
+// HttpOnly cookie holding a hash of the state binds the login to the browser,
+// so a callback started by someone else is rejected (login CSRF).
+//
+// Example: router.GET("/auth/:provider/login", client.LoginHandler())
+func (c *Client) LoginHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		redirectTo := ctx.Query("redirect_to")
+		if !isSafeRedirect(redirectTo) {
+			response.BadRequest(ctx, "Invalid redirect_to")
+			return
+		}
+
+		provider := ctx.Param("provider")
+		authURL, err := c.AuthCodeURL(ctx.Request.Context(), provider, redirectTo, ctx.GetString("tenant_id"))
+		if err != nil {
+			if errors.Is(err, ErrUnknownProvider) {
+				response.NotFound(ctx, "Unknown login provider")
+				return
+			}
+			response.InternalServerError(ctx, "Failed to start login")
+			return
+		}
+		parsed, err := url.Parse(authURL)
+		if err != nil {
+			response.InternalServerError(ctx, "Failed to start login")
+			return
+		}
+
+		setStateCookie(ctx, provider, hashState(parsed.Query().Get("state")), int(c.stateTTL.Seconds()))
+		ctx.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// CallbackHandler completes the flow for the ":provider" route parameter and
+// hands the result to onSuccess. The state must match the cookie set by
+// LoginHandler; the cookie is cleared afterwards.
+//
+// Example: router.GET("/auth/:provider/callback", client.CallbackHandler(issueSession))
+func (c *Client) CallbackHandler(onSuccess SuccessHandler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if errCode := ctx.Query("error"); errCode != "" {
+			response.Error(ctx, http.StatusUnauthorized, "OAUTH_DENIED", "Login was cancelled or denied by the provider")
+			return
+		}
+
+		code := ctx.Query("code")
+		if code == "" {
+			response.BadRequest(ctx, "Missing authorization code")
+			return
+		}
+
+		provider := ctx.Param("provider")
+		state := ctx.Query("state")
+		cookie, _ := ctx.Cookie(stateCookiePrefix + provider)
+		setStateCookie(ctx, provider, "", -1)
+		if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(hashState(state))) != 1 {
+			response.Error(ctx, http.StatusBadRequest, "INVALID_STATE", "Invalid or expired login state")
+			return
+		}
+
+		result, err := c.Exchange(ctx.Request.Context(), provider, code, state)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrUnknownProvider):
+				response.NotFound(ctx, "Unknown login provider")
+			case errors.Is(err, ErrInvalidState), errors.Is(err, ErrProviderMismatch):
+				response.Error(ctx, http.StatusBadRequest, "INVALID_STATE", "Invalid or expired login state")
+			case errors.Is(err, ErrInvalidIDToken), errors.Is(err, ErrMissingIDToken):
+				response.Unauthorized(ctx, "Invalid identity token")
+			default:
+				response.Error(ctx, http.StatusBadGateway, "OAUTH_EXCHANGE_FAILED", "Failed to complete login with provider")
+			}
+			return
+		}
+
+		onSuccess(ctx, result)
+	}
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// setStateCookie sets (maxAge > 0) or clears (maxAge < 0) the state cookie
+func setStateCookie(ctx *gin.Context, provider, value string, maxAge int) {
+	secure := ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(stateCookiePrefix+provider, value, maxAge, "/", "", secure, true)
+}
+
+// isSafeRedirect only allows same-origin relative paths to prevent open redirects
+func isSafeRedirect(target string) bool {
+	if target == "" {
+		return true
+	}
+	return strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") && !strings.Contains(target, "\\")
+}
@@ -0,0 +1,190 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vhvplatform/go-shared/auth"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrUnknownProvider is returned when the requested provider is not registered
+	ErrUnknownProvider = errors.New("unknown oauth provider")
+	// ErrInvalidState is returned when the state is missing, expired or already used
+	ErrInvalidState = errors.New("invalid or expired oauth state")
+	// ErrProviderMismatch is returned when a callback arrives for a different provider than the one that started the flow
+	ErrProviderMismatch = errors.New("oauth provider mismatch")
+	// ErrInvalidIDToken is returned when an ID token fails validation
+	ErrInvalidIDToken = errors.New("invalid id token")
+	// ErrMissingIDToken is returned when an OIDC provider does not return an ID token
+	ErrMissingIDToken = errors.New("id token missing from token response")
+)
+
+// Config configures the OAuth client
+type Config struct {
+	StateTTL   time.Duration // default: 10 minutes
+	HTTPClient *http.Client  // default: http.DefaultClient
+}
+
+// Result is the outcome of a successful login
+type Result struct {
+	Profile *Profile
+	Token   *oauth2.Token
+	State   *AuthState
+}
+
+// Client runs authorization code flows (with PKCE) against the registered providers
+type Client struct {
+	providers  map[string]*Provider
+	verifiers  map[string]*IDTokenVerifier
+	stateStore StateStore
+	stateTTL   time.Duration
+	httpClient *http.Client
+}
+
+// NewClient creates an OAuth client for the given providers
+func NewClient(providers []*Provider, stateStore StateStore, config Config) *Client {
+	if config.StateTTL <= 0 {
+		config.StateTTL = 10 * time.Minute
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+
+	c := &Client{
+		providers:  make(map[string]*Provider, len(providers)),
+		verifiers:  make(map[string]*IDTokenVerifier),
+		stateStore: stateStore,
+		stateTTL:   config.StateTTL,
+		httpClient: config.HTTPClient,
+	}
+	for _, p := range providers {
+		c.providers[p.Name] = p
+		if p.IsOIDC() {
+			c.verifiers[p.Name] = NewIDTokenVerifier(p.Issuer, p.ClientID, NewKeySet(p.JWKSURL, config.HTTPClient))
+		}
+	}
+	return c
+}
+
+// Provider returns the registered provider with the given name
+func (c *Client) Provider(name string) (*Provider, error) {
+	p, ok := c.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// AuthCodeURL starts a login: it stores a fresh state, nonce and PKCE verifier
+// and returns the provider URL to redirect the user to.
+func (c *Client) AuthCodeURL(ctx context.Context, providerName, redirectTo, tenantID string) (string, error) {
+	provider, err := c.Provider(providerName)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	data := &AuthState{
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RedirectTo:   redirectTo,
+		TenantID:     tenantID,
+		CreatedAt:    time.Now(),
+	}
+	if err := c.stateStore.Save(ctx, state, data, c.stateTTL); err != nil {
+		return "", fmt.Errorf("failed to save state: %w", err)
+	}
+
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if provider.IsOIDC() {
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+	return provider.oauth2Config().AuthCodeURL(state, opts...), nil
+}
+
+// Exchange completes a login: it consumes the state, exchanges the code,
+// validates the ID token (for OIDC providers) and loads the user profile.
+func (c *Client) Exchange(ctx context.Context, providerName, code, state string) (*Result, error) {
+	provider, err := c.Provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if state == "" {
+		return nil, ErrInvalidState
+	}
+
+	data, err := c.stateStore.Consume(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if data.Provider != provider.Name {
+		return nil, ErrProviderMismatch
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.httpClient)
+	token, err := provider.oauth2Config().Exchange(ctx, code, oauth2.VerifierOption(data.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	var idClaims *IDTokenClaims
+	if verifier, ok := c.verifiers[provider.Name]; ok {
+		rawIDToken, _ := token.Extra("id_token").(string)
+		if rawIDToken == "" {
+			return nil, ErrMissingIDToken
+		}
+		idClaims, err = verifier.Verify(ctx, rawIDToken, data.Nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	profile, err := c.fetchProfile(ctx, provider, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ID token subject is authoritative; never trust a userinfo response for a different user
+	if idClaims != nil {
+		if profile.Subject != "" && profile.Subject != idClaims.Subject {
+			return nil, fmt.Errorf("%w: userinfo subject does not match id token", ErrInvalidIDToken)
+		}
+		profile.Subject = idClaims.Subject
+		if profile.Email == "" {
+			profile.Email = idClaims.Email
+			profile.EmailVerified = idClaims.EmailVerified
+		}
+	}
+
+	return &Result{
+		Profile: profile,
+		Token:   token,
+		State:   data,
+	}, nil
+}
+
+func (c *Client) fetchProfile(ctx context.Context, provider *Provider, token *oauth2.Token) (*Profile, error) {
+	client := provider.oauth2Config().Client(ctx, token)
+	if provider.FetchProfile != nil {
+		return provider.FetchProfile(ctx, client, token)
+	}
+	if provider.UserInfoURL == "" {
+		return &Profile{Provider: provider.Name}, nil
+	}
+	return fetchUserInfo(ctx, client, provider)
+}
@@ -0,0 +1,291 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeOIDCProvider is a minimal OpenID Connect provider for tests
+type fakeOIDCProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]fakeAuthRequest
+
+	// tamper hooks
+	issuerOverride string
+	nonceOverride  string
+}
+
+type fakeAuthRequest struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeOIDCProvider(t *testing.T, clientID string) *fakeOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	p := &fakeOIDCProvider{key: key, clientID: clientID, codes: make(map[string]fakeAuthRequest)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/userinfo", p.userinfo)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize simulates the user approving the request behind authURL and returns the callback query
+func (p *fakeOIDCProvider) authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid auth URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("expected PKCE S256 challenge in auth URL: %s", authURL)
+	}
+	if q.Get("nonce") == "" {
+		t.Fatalf("expected nonce in auth URL: %s", authURL)
+	}
+
+	p.mu.Lock()
+	p.codes["code-123"] = fakeAuthRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	p.mu.Unlock()
+
+	return url.Values{"code": {"code-123"}, "state": {q.Get("state")}}
+}
+
+func (p *fakeOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	base := p.server.URL
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 base,
+		"authorization_endpoint": base + "/authorize",
+		"token_endpoint":         base + "/token",
+		"userinfo_endpoint":      base + "/userinfo",
+		"jwks_uri":               base + "/jwks",
+	})
+}
+
+func (p *fakeOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	p.mu.Lock()
+	req, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	issuer := p.server.URL
+	if p.issuerOverride != "" {
+		issuer = p.issuerOverride
+	}
+	nonce := req.nonce
+	if p.nonceOverride != "" {
+		nonce = p.nonceOverride
+	}
+
+	claims := IDTokenClaims{
+		Nonce:         nonce,
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   "user-42",
+			Audience:  jwt.ClaimStrings{p.clientID},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	idToken, _ := token.SignedString(p.key)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access-123",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (p *fakeOIDCProvider) userinfo(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer access-123" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sub":            "user-42",
+		"email":          "Alice@Example.com",
+		"email_verified": true,
+		"name":           "Alice",
+	})
+}
+
+func newTestClient(t *testing.T, fake *fakeOIDCProvider) *Client {
+	t.Helper()
+
+	provider, err := DiscoverProvider(context.Background(), nil, "fake", fake.server.URL, fake.clientID, "secret", "http://app/callback")
+	if err != nil {
+		t.Fatalf("failed to discover provider: %v", err)
+	}
+	return NewClient([]*Provider{provider}, NewMemoryStateStore(), Config{})
+}
+
+func TestClient_AuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeOIDCProvider(t, "client-1")
+	client := newTestClient(t, fake)
+
+	authURL, err := client.AuthCodeURL(ctx, "fake", "/dashboard", "tenant-1")
+	if err != nil {
+		t.Fatalf("failed to build auth URL: %v", err)
+	}
+	callback := fake.authorize(t, authURL)
+
+	result, err := client.Exchange(ctx, "fake", callback.Get("code"), callback.Get("state"))
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+
+	if result.Profile.Subject != "user-42" || result.Profile.Name != "Alice" || !result.Profile.EmailVerified {
+		t.Errorf("unexpected profile: %+v", result.Profile)
+	}
+	if result.State.RedirectTo != "/dashboard" {
+		t.Errorf("expected redirect to be carried through state, got %q", result.State.RedirectTo)
+	}
+
+	user := result.Profile.ToUserInfo(result.State.TenantID)
+	if user.ID != "fake:user-42" || user.Email != "alice@example.com" || user.TenantID != "tenant-1" {
+		t.Errorf("unexpected user info: %+v", user)
+	}
+
+	// State is single-use
+	if _, err := client.Exchange(ctx, "fake", callback.Get("code"), callback.Get("state")); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState on replay, got %v", err)
+	}
+}
+
+func TestClient_RejectsInvalidIDTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(p *fakeOIDCProvider)
+	}{
+		{"wrong nonce", func(p *fakeOIDCProvider) { p.nonceOverride = "other" }},
+		{"wrong issuer", func(p *fakeOIDCProvider) { p.issuerOverride = "https://evil.example.com" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := newFakeOIDCProvider(t, "client-1")
+			client := newTestClient(t, fake)
+			tt.tamper(fake)
+
+			authURL, _ := client.AuthCodeURL(ctx, "fake", "", "")
+			callback := fake.authorize(t, authURL)
+
+			if _, err := client.Exchange(ctx, "fake", callback.Get("code"), callback.Get("state")); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fake := newFakeOIDCProvider(t, "client-1")
+	client := newTestClient(t, fake)
+
+	router := gin.New()
+	router.GET("/auth/:provider/login", client.LoginHandler())
+	router.GET("/auth/:provider/callback", client.CallbackHandler(func(c *gin.Context, result *Result) {
+		c.String(http.StatusOK, result.Profile.ExternalID())
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/fake/login?redirect_to=//evil.com", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected open redirect to be rejected, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/fake/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected redirect, got %d", w.Code)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("expected an HttpOnly SameSite=Lax state cookie, got %+v", cookies)
+	}
+	callback := fake.authorize(t, w.Header().Get("Location"))
+
+	// A callback from another browser (no state cookie) is rejected
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/fake/callback?"+callback.Encode(), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected callback without state cookie to be rejected, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/auth/fake/callback?"+callback.Encode(), nil)
+	req.AddCookie(cookies[0])
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "fake:user-42" {
+		t.Errorf("unexpected callback response: %d %s", w.Code, w.Body.String())
+	}
+	if cleared := w.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("expected the state cookie to be cleared, got %+v", cleared)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/unknown/login", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown provider, got %d", w.Code)
+	}
+}
@@ -0,0 +1,133 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/vhvplatform/go-shared/auth"
+	"golang.org/x/oauth2"
+)
+
+// Profile is a provider-independent user profile
+type Profile struct {
+	Provider      string                 `json:"provider"`
+	Subject       string                 `json:"subject"`
+	Email         string                 `json:"email"`
+	EmailVerified bool                   `json:"email_verified"`
+	Name          string                 `json:"name,omitempty"`
+	AvatarURL     string                 `json:"avatar_url,omitempty"`
+	Raw           map[string]interface{} `json:"raw,omitempty"`
+}
+
+// ExternalID returns a globally unique identifier of the form "provider:subject"
+func (p *Profile) ExternalID() string {
+	return p.Provider + ":" + p.Subject
+}
+
+// ToUserInfo converts the profile into an auth.UserInfo for the given tenant.
+// The ID is the provider-qualified external ID; callers that map external
+// identities to local users should replace it with the local user ID.
+func (p *Profile) ToUserInfo(tenantID string) *auth.UserInfo {
+	return &auth.UserInfo{
+		ID:       p.ExternalID(),
+		Email:    auth.NormalizeIdentifier(p.Email, "email"),
+		TenantID: tenantID,
+	}
+}
+
+// profileFromClaims maps standard OIDC claims into a profile
+func profileFromClaims(provider string, claims map[string]interface{}) *Profile {
+	profile := &Profile{
+		Provider: provider,
+		Raw:      claims,
+	}
+	profile.Subject, _ = claims["sub"].(string)
+	profile.Email, _ = claims["email"].(string)
+	profile.Name, _ = claims["name"].(string)
+	profile.AvatarURL, _ = claims["picture"].(string)
+
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		profile.EmailVerified = verified
+	case string:
+		profile.EmailVerified = verified == "true"
+	}
+	return profile
+}
+
+// fetchUserInfo fetches the OIDC userinfo endpoint
+func fetchUserInfo(ctx context.Context, client *http.Client, provider *Provider) (*Profile, error) {
+	var claims map[string]interface{}
+	if err := getJSON(ctx, client, provider.UserInfoURL, &claims); err != nil {
+		return nil, fmt.Errorf("failed to fetch userinfo: %w", err)
+	}
+	return profileFromClaims(provider.Name, claims), nil
+}
+
+// fetchGitHubProfile loads the GitHub user and, when needed, the primary verified email
+func fetchGitHubProfile(ctx context.Context, client *http.Client, token *oauth2.Token) (*Profile, error) {
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(ctx, client, "https://api.github.com/user", &user); err != nil {
+		return nil, fmt.Errorf("failed to fetch GitHub user: %w", err)
+	}
+
+	profile := &Profile{
+		Provider:  ProviderGitHub,
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Email:     user.Email,
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+		Raw: map[string]interface{}{
+			"login": user.Login,
+		},
+	}
+	if profile.Name == "" {
+		profile.Name = user.Login
+	}
+
+	// The public profile email is optional and unverified; prefer the primary verified email
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, client, "https://api.github.com/user/emails", &emails); err == nil {
+		for _, e := range emails {
+			if e.Primary && e.Verified {
+				profile.Email = e.Email
+				profile.EmailVerified = true
+				break
+			}
+		}
+	}
+
+	return profile, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
@@ -0,0 +1,156 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vhvplatform/go-shared/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+// Provider names for the built-in providers
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+// ProfileFetcher loads the user profile after a successful token exchange
+type ProfileFetcher func(ctx context.Context, client *http.Client, token *oauth2.Token) (*Profile, error)
+
+// Provider describes an OAuth2 / OpenID Connect provider
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+
+	// OIDC settings; when Issuer is set the ID token is validated against JWKSURL
+	Issuer  string
+	JWKSURL string
+
+	// FetchProfile overrides the default OIDC userinfo mapping (e.g. for GitHub)
+	FetchProfile ProfileFetcher
+}
+
+// IsOIDC reports whether the provider issues ID tokens
+func (p *Provider) IsOIDC() bool {
+	return p.Issuer != ""
+}
+
+// oauth2Config returns the x/oauth2 configuration for the provider
+func (p *Provider) oauth2Config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  p.RedirectURL,
+		Scopes:       p.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.AuthURL,
+			TokenURL: p.TokenURL,
+		},
+	}
+}
+
+// GoogleProvider returns the Google OpenID Connect provider
+func GoogleProvider(clientID, clientSecret, redirectURL string) *Provider {
+	return &Provider{
+		Name:         ProviderGoogle,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		AuthURL:      endpoints.Google.AuthURL,
+		TokenURL:     endpoints.Google.TokenURL,
+		UserInfoURL:  "https://openidconnect.googleapis.com/v1/userinfo",
+		Issuer:       "https://accounts.google.com",
+		JWKSURL:      "https://www.googleapis.com/oauth2/v3/certs",
+	}
+}
+
+// GitHubProvider returns the GitHub OAuth2 provider (GitHub does not support OIDC for user login)
+func GitHubProvider(clientID, clientSecret, redirectURL string) *Provider {
+	return &Provider{
+		Name:         ProviderGitHub,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"read:user", "user:email"},
+		AuthURL:      endpoints.GitHub.AuthURL,
+		TokenURL:     endpoints.GitHub.TokenURL,
+		UserInfoURL:  "https://api.github.com/user",
+		FetchProfile: fetchGitHubProfile,
+	}
+}
+
+// ProvidersFromConfig builds the providers that have a client ID configured
+func ProvidersFromConfig(cfg *config.OAuthConfig) []*Provider {
+	var providers []*Provider
+	if cfg.GoogleClientID != "" {
+		providers = append(providers, GoogleProvider(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL))
+	}
+	if cfg.GitHubClientID != "" {
+		providers = append(providers, GitHubProvider(cfg.GitHubClientID, cfg.GitHubClientSecret, cfg.GitHubRedirectURL))
+	}
+	return providers
+}
+
+// discoveryDocument is the subset of the OIDC discovery document we use
+type discoveryDocument struct {
+	Issuer      string `json:"issuer"`
+	AuthURL     string `json:"authorization_endpoint"`
+	TokenURL    string `json:"token_endpoint"`
+	UserInfoURL string `json:"userinfo_endpoint"`
+	JWKSURL     string `json:"jwks_uri"`
+}
+
+// DiscoverProvider builds a provider from the issuer's OpenID Connect discovery document
+func DiscoverProvider(ctx context.Context, httpClient *http.Client, name, issuer, clientID, clientSecret, redirectURL string) (*Provider, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery document request failed with status %d", resp.StatusCode)
+	}
+
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode discovery document: %w", err)
+	}
+	if doc.Issuer != issuer {
+		return nil, fmt.Errorf("issuer mismatch: expected %s, got %s", issuer, doc.Issuer)
+	}
+
+	return &Provider{
+		Name:         name,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		AuthURL:      doc.AuthURL,
+		TokenURL:     doc.TokenURL,
+		UserInfoURL:  doc.UserInfoURL,
+		Issuer:       doc.Issuer,
+		JWKSURL:      doc.JWKSURL,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/vhvplatform/go-shared/redis"
+)
+
+// AuthState is the data stored between the redirect and the callback
+type AuthState struct {
+	Provider     string    `json:"provider"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	RedirectTo   string    `json:"redirect_to,omitempty"`
+	TenantID     string    `json:"tenant_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// StateStore stores pending authorization states. Consume must be single-use.
+type StateStore interface {
+	Save(ctx context.Context, state string, data *AuthState, ttl time.Duration) error
+	Consume(ctx context.Context, state string) (*AuthState, error)
+}
+
+// RedisStateStore stores authorization states in Redis
+type RedisStateStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisStateStore creates a Redis-backed state store
+func NewRedisStateStore(client *redis.Client, keyPrefix string) *RedisStateStore {
+	if keyPrefix == "" {
+		keyPrefix = "oauth:state"
+	}
+	return &RedisStateStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// Save stores the state with the given TTL
+func (s *RedisStateStore) Save(ctx context.Context, state string, data *AuthState, ttl time.Duration) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return s.client.Set(ctx, s.key(state), payload, ttl)
+}
+
+// Consume atomically reads and deletes the state
+func (s *RedisStateStore) Consume(ctx context.Context, state string) (*AuthState, error) {
+	payload, err := s.client.GetDel(ctx, s.key(state)).Bytes()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, ErrInvalidState
+		}
+		return nil, err
+	}
+
+	var data AuthState
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	return &data, nil
+}
+
+func (s *RedisStateStore) key(state string) string {
+	return s.keyPrefix + ":" + state
+}
+
+// MemoryStateStore stores authorization states in memory (tests and single-instance setups)
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]memoryState
+}
+
+type memoryState struct {
+	data      *AuthState
+	expiresAt time.Time
+}
+
+// NewMemoryStateStore creates an in-memory state store
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{
+		states: make(map[string]memoryState),
+	}
+}
+
+// Save stores the state with the given TTL
+func (s *MemoryStateStore) Save(ctx context.Context, state string, data *AuthState, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[state] = memoryState{data: data, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Consume reads and deletes the state
+func (s *MemoryStateStore) Consume(ctx context.Context, state string) (*AuthState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.states[state]
+	delete(s.states, state)
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, ErrInvalidState
+	}
+	return entry.data, nil
+}
@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"strings"
+
+	"github.com/vhvplatform/go-shared/serviceauth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// internalTokenKey is the gRPC metadata key of the service token
+var internalTokenKey = strings.ToLower(serviceauth.HeaderName)
+
+// ServiceAuthUnaryInterceptor authenticates calling services by the
+// x-internal-token metadata or the verified mTLS peer certificate
+func ServiceAuthUnaryInterceptor(authenticator *serviceauth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateService(ctx, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ServiceAuthStreamInterceptor is the streaming counterpart of ServiceAuthUnaryInterceptor
+func ServiceAuthStreamInterceptor(authenticator *serviceauth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateService(ss.Context(), authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &identityServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// ServiceTokenUnaryClientInterceptor attaches a service token for the target audience to outgoing calls
+func ServiceTokenUnaryClientInterceptor(issuer *serviceauth.Issuer, audience string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		token, err := issuer.Token(audience)
+		if err != nil {
+			return status.Error(codes.Internal, "failed to issue service token")
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, internalTokenKey, token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// ServiceTokenStreamClientInterceptor is the streaming counterpart of ServiceTokenUnaryClientInterceptor
+func ServiceTokenStreamClientInterceptor(issuer *serviceauth.Issuer, audience string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		token, err := issuer.Token(audience)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to issue service token")
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, internalTokenKey, token)
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func authenticateService(ctx context.Context, authenticator *serviceauth.Authenticator, fullMethod string) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(internalTokenKey); len(values) > 0 {
+			token = values[0]
+		}
+	}
+
+	var chains [][]*x509.Certificate
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			chains = tlsInfo.State.VerifiedChains
+		}
+	}
+
+	identity, err := authenticator.Authenticate(token, chains, fullMethod)
+	if err != nil {
+		if errors.Is(err, serviceauth.ErrNotAllowed) {
+			return nil, status.Error(codes.PermissionDenied, "service not allowed to call this method")
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid service credentials")
+	}
+	return serviceauth.WithIdentity(ctx, identity), nil
+}
+
+// identityServerStream overrides the stream context to carry the service identity
+type identityServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,89 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/vhvplatform/go-shared/errors"
+)
+
+// ErrInvalidIfMatch is returned when the If-Match header is not a version entity tag
+var ErrInvalidIfMatch = errors.New("invalid If-Match header")
+
+// ETag formats a resource version as a strong entity tag, e.g. "3"
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetETag sets the ETag header to the resource version
+func SetETag(c *gin.Context, version int64) {
+	c.Header("ETag", ETag(version))
+}
+
+// IfMatch returns the version in the If-Match header. ok is false when the
+// header is absent or "*"; lists of several tags are rejected.
+func IfMatch(c *gin.Context) (version int64, ok bool, err error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false, ErrInvalidIfMatch
+	}
+	version, err = strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version < 0 {
+		return 0, false, ErrInvalidIfMatch
+	}
+	return version, true, nil
+}
+
+// RequireIfMatch returns the version in the If-Match header. When the header
+// is missing (428) or invalid (400) it sends the error response and returns
+// false; the handler should then return.
+func RequireIfMatch(c *gin.Context) (int64, bool) {
+	version, ok, err := IfMatch(c)
+	if err != nil {
+		BadRequest(c, "If-Match must be a quoted resource version")
+		return 0, false
+	}
+	if !ok {
+		PreconditionRequired(c, "If-Match header is required")
+		return 0, false
+	}
+	return version, true
+}
+
+// PreconditionFailed sends a precondition failed error
+func PreconditionFailed(c *gin.Context, message string) {
+	Error(c, http.StatusPreconditionFailed, "PRECONDITION_FAILED", message)
+}
+
+// PreconditionRequired sends a precondition required error
+func PreconditionRequired(c *gin.Context, message string) {
+	Error(c, http.StatusPreconditionRequired, "PRECONDITION_REQUIRED", message)
+}
+
+// AppError sends the error response for err converted with errors.FromError,
+// e.g. 409 Conflict for a mongodb version conflict. Errors without an
+// application error mapping are sent as a generic internal error so their
+// messages are not exposed.
+func AppError(c *gin.Context, err error) {
+	var appErr *apperrors.AppError
+	var converter interface{ AppError() *apperrors.AppError }
+	if !errors.As(err, &appErr) && !errors.As(err, &converter) {
+		InternalServerError(c, "Internal server error")
+		return
+	}
+
+	appErr = apperrors.FromError(err)
+	if len(appErr.Details) > 0 {
+		ErrorWithDetails(c, appErr.StatusCode, string(appErr.Code), appErr.Message, appErr.Details)
+		return
+	}
+	Error(c, appErr.StatusCode, string(appErr.Code), appErr.Message)
+}
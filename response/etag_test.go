@@ -0,0 +1,77 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/vhvplatform/go-shared/errors"
+)
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		header  string
+		version int64
+		ok      bool
+		err     error
+	}{
+		{"", 0, false, nil},
+		{"*", 0, false, nil},
+		{`"3"`, 3, true, nil},
+		{`W/"7"`, 7, true, nil},
+		{`3`, 0, false, ErrInvalidIfMatch},
+		{`"3", "4"`, 0, false, ErrInvalidIfMatch},
+		{`"-1"`, 0, false, ErrInvalidIfMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+			c.Request.Header.Set("If-Match", tt.header)
+
+			version, ok, err := IfMatch(c)
+			if version != tt.version || ok != tt.ok || err != tt.err {
+				t.Errorf("expected (%d, %v, %v), got (%d, %v, %v)", tt.version, tt.ok, tt.err, version, ok, err)
+			}
+		})
+	}
+
+	if ETag(3) != `"3"` {
+		t.Errorf("unexpected ETag: %s", ETag(3))
+	}
+}
+
+func TestAppError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"app error", fmt.Errorf("wrapped: %w", apperrors.Conflict("Stale")), http.StatusConflict, "CONFLICT", "Stale"},
+		{"plain error", fmt.Errorf("dial tcp 10.0.0.1: refused"), http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			AppError(c, tt.err)
+
+			var resp Response
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if w.Code != tt.status || resp.Error.Code != tt.code || resp.Error.Message != tt.message {
+				t.Errorf("expected %d %s %q, got %d %+v", tt.status, tt.code, tt.message, w.Code, resp.Error)
+			}
+		})
+	}
+}
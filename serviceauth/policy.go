@@ -0,0 +1,68 @@
+package serviceauth
+
+import (
+	"strings"
+	"sync"
+)
+
+// Policy maps calling services to the routes they may call.
+//
+// Routes are HTTP routes as "METHOD /path" or gRPC full method names such as
+// "/orders.v1.OrderService/GetOrder". Patterns may omit the HTTP method to
+// match any method and may end in "*" to match any suffix, e.g.
+// "GET /internal/users/*" or "/orders.v1.OrderService/*". The service name "*"
+// applies a pattern to every authenticated service.
+type Policy struct {
+	mu    sync.RWMutex
+	rules map[string][]string
+}
+
+// NewPolicy creates an empty policy (which denies everything)
+func NewPolicy() *Policy {
+	return &Policy{
+		rules: make(map[string][]string),
+	}
+}
+
+// Allow permits the service to call routes matching the patterns
+func (p *Policy) Allow(service string, patterns ...string) *Policy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rules[service] = append(p.rules[service], patterns...)
+	return p
+}
+
+// IsAllowed reports whether the service may call the route
+func (p *Policy) IsAllowed(service, route string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, key := range []string{service, "*"} {
+		for _, pattern := range p.rules[key] {
+			if matchRoute(pattern, route) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HTTPRoute formats an HTTP method and path as a policy route
+func HTTPRoute(method, path string) string {
+	return method + " " + path
+}
+
+func matchRoute(pattern, route string) bool {
+	// A pattern without a method matches the path of any HTTP route
+	if !strings.Contains(pattern, " ") {
+		if _, path, ok := strings.Cut(route, " "); ok {
+			route = path
+		}
+	}
+
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(route, prefix)
+	}
+	return pattern == route
+}
@@ -0,0 +1,126 @@
+package serviceauth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"strings"
+)
+
+// HeaderName is the HTTP header (and lower-cased gRPC metadata key) carrying service tokens
+const HeaderName = "X-Internal-Token"
+
+// Authentication methods reported in Identity.Method
+const (
+	MethodToken = "token"
+	MethodMTLS  = "mtls"
+)
+
+var (
+	// ErrInvalidToken is returned when a service token fails validation
+	ErrInvalidToken = errors.New("invalid service token")
+	// ErrTokenExpired is returned when a service token has expired
+	ErrTokenExpired = errors.New("service token expired")
+	// ErrUnauthenticated is returned when neither a token nor a trusted client certificate is present
+	ErrUnauthenticated = errors.New("service credentials required")
+	// ErrNotAllowed is returned when the policy does not allow the caller to call the route
+	ErrNotAllowed = errors.New("service not allowed to call route")
+)
+
+// Identity is an authenticated calling service
+type Identity struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+}
+
+// Config configures the Authenticator
+type Config struct {
+	// Verifier validates service tokens; nil disables token authentication
+	Verifier *Verifier
+	// TrustDomain enables mTLS identities from verified client certificates.
+	// A certificate with URI SAN "spiffe://<TrustDomain>/<service>" or DNS SAN
+	// "<service>.<TrustDomain>" identifies <service>. Empty disables mTLS identities.
+	TrustDomain string
+	// Policy restricts which services may call which routes; nil allows any authenticated service
+	Policy *Policy
+}
+
+// Authenticator verifies service identities from tokens or peer certificates
+type Authenticator struct {
+	verifier    *Verifier
+	trustDomain string
+	policy      *Policy
+}
+
+// NewAuthenticator creates a service authenticator
+func NewAuthenticator(config Config) *Authenticator {
+	return &Authenticator{
+		verifier:    config.Verifier,
+		trustDomain: config.TrustDomain,
+		policy:      config.Policy,
+	}
+}
+
+// Authenticate identifies the caller from the token, falling back to the
+// verified peer certificate chains, and checks the policy for the route
+func (a *Authenticator) Authenticate(token string, verifiedChains [][]*x509.Certificate, route string) (*Identity, error) {
+	identity, err := a.identify(token, verifiedChains)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.policy != nil && !a.policy.IsAllowed(identity.Service, route) {
+		return identity, ErrNotAllowed
+	}
+	return identity, nil
+}
+
+func (a *Authenticator) identify(token string, verifiedChains [][]*x509.Certificate) (*Identity, error) {
+	if token != "" && a.verifier != nil {
+		service, err := a.verifier.Verify(token)
+		if err != nil {
+			return nil, err
+		}
+		return &Identity{Service: service, Method: MethodToken}, nil
+	}
+
+	if a.trustDomain != "" && len(verifiedChains) > 0 && len(verifiedChains[0]) > 0 {
+		if service, ok := ServiceFromCertificate(verifiedChains[0][0], a.trustDomain); ok {
+			return &Identity{Service: service, Method: MethodMTLS}, nil
+		}
+	}
+
+	return nil, ErrUnauthenticated
+}
+
+// ServiceFromCertificate extracts the service name from a certificate's SANs
+func ServiceFromCertificate(cert *x509.Certificate, trustDomain string) (string, bool) {
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" && uri.Host == trustDomain {
+			if service := strings.Trim(uri.Path, "/"); service != "" && !strings.Contains(service, "/") {
+				return service, true
+			}
+		}
+	}
+
+	suffix := "." + trustDomain
+	for _, name := range cert.DNSNames {
+		if service, ok := strings.CutSuffix(name, suffix); ok && service != "" && !strings.Contains(service, ".") {
+			return service, true
+		}
+	}
+	return "", false
+}
+
+type identityKey struct{}
+
+// WithIdentity stores the calling service identity in the context
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the calling service identity, if any
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
@@ -0,0 +1,129 @@
+package serviceauth
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var (
+	billingPublic, billingKey, _ = ed25519.GenerateKey(nil)
+	usersPublic, usersKey, _     = ed25519.GenerateKey(nil)
+	testKeys                     = map[string]ed25519.PublicKey{"billing": billingPublic, "users": usersPublic}
+)
+
+func TestIssuerAndVerifier(t *testing.T) {
+	issuer := NewIssuer("billing", billingKey, time.Minute)
+	token, err := issuer.Token("orders")
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	if cached, _ := issuer.Token("orders"); cached != token {
+		t.Error("expected token to be cached per audience")
+	}
+
+	service, err := NewVerifier("orders", testKeys, 0).Verify(token)
+	if err != nil || service != "billing" {
+		t.Fatalf("expected billing, got %q (%v)", service, err)
+	}
+
+	if _, err := NewVerifier("users", testKeys, 0).Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected audience mismatch to be rejected, got %v", err)
+	}
+	wrongKey := map[string]ed25519.PublicKey{"billing": usersPublic}
+	if _, err := NewVerifier("orders", wrongKey, 0).Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected wrong key to be rejected, got %v", err)
+	}
+	if _, err := NewVerifier("orders", map[string]ed25519.PublicKey{"users": usersPublic}, 0).Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected unknown caller to be rejected, got %v", err)
+	}
+
+	expired := NewVerifier("orders", testKeys, 0)
+	expired.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := expired.Verify(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected ErrTokenExpired, got %v", err)
+	}
+
+	longLived, _ := NewIssuer("billing", billingKey, time.Hour).Token("orders")
+	if _, err := NewVerifier("orders", testKeys, 0).Verify(longLived); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected token above max TTL to be rejected, got %v", err)
+	}
+}
+
+func TestVerifier_RejectsImpersonation(t *testing.T) {
+	// users signs with its own key but claims to be billing
+	forged, err := NewIssuer("billing", usersKey, time.Minute).Token("orders")
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+	if _, err := NewVerifier("orders", testKeys, 0).Verify(forged); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected token signed by users with sub=billing to be rejected, got %v", err)
+	}
+}
+
+func TestServiceFromCertificate(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://mesh.local/billing")
+	tests := []struct {
+		name    string
+		cert    *x509.Certificate
+		service string
+		ok      bool
+	}{
+		{"spiffe URI", &x509.Certificate{URIs: []*url.URL{spiffe}}, "billing", true},
+		{"DNS SAN", &x509.Certificate{DNSNames: []string{"orders.mesh.local"}}, "orders", true},
+		{"other trust domain", &x509.Certificate{DNSNames: []string{"orders.evil.local"}}, "", false},
+		{"nested DNS name", &x509.Certificate{DNSNames: []string{"a.orders.mesh.local"}}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, ok := ServiceFromCertificate(tt.cert, "mesh.local")
+			if service != tt.service || ok != tt.ok {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.service, tt.ok, service, ok)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Policy(t *testing.T) {
+	policy := NewPolicy().
+		Allow("billing", "GET /internal/orders/*", "/orders.v1.OrderService/GetOrder").
+		Allow("*", "/health")
+
+	authenticator := NewAuthenticator(Config{
+		Verifier:    NewVerifier("orders", testKeys, 0),
+		TrustDomain: "mesh.local",
+		Policy:      policy,
+	})
+	token, _ := NewIssuer("billing", billingKey, 0).Token("orders")
+
+	tests := []struct {
+		route string
+		err   error
+	}{
+		{HTTPRoute("GET", "/internal/orders/42"), nil},
+		{HTTPRoute("DELETE", "/internal/orders/42"), ErrNotAllowed},
+		{"/orders.v1.OrderService/GetOrder", nil},
+		{"/orders.v1.OrderService/DeleteOrder", ErrNotAllowed},
+		{HTTPRoute("GET", "/health"), nil},
+	}
+	for _, tt := range tests {
+		if _, err := authenticator.Authenticate(token, nil, tt.route); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.route, tt.err, err)
+		}
+	}
+
+	chains := [][]*x509.Certificate{{{DNSNames: []string{"billing.mesh.local"}}}}
+	identity, err := authenticator.Authenticate("", chains, HTTPRoute("GET", "/internal/orders/1"))
+	if err != nil || identity.Service != "billing" || identity.Method != MethodMTLS {
+		t.Errorf("expected mTLS identity for billing, got %+v (%v)", identity, err)
+	}
+
+	if _, err := authenticator.Authenticate("", nil, "/health"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated, got %v", err)
+	}
+}
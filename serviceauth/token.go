@@ -0,0 +1,155 @@
+package serviceauth
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenIssuer is the issuer claim of every service token
+const tokenIssuer = "service-auth"
+
+// Claims are the claims of a service token. The subject is the calling
+// service and the audience is the service being called.
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+// Issuer signs short-lived service tokens on behalf of one service. Each
+// service signs with its own Ed25519 key, so holding the keys needed to
+// verify callers does not allow minting tokens as them.
+type Issuer struct {
+	service string
+	key     ed25519.PrivateKey
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedToken
+}
+
+type cachedToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// NewIssuer creates an issuer for the named service. Tokens live for ttl
+// (default: 5 minutes) and are cached per audience until close to expiry.
+func NewIssuer(service string, key ed25519.PrivateKey, ttl time.Duration) *Issuer {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &Issuer{
+		service: service,
+		key:     key,
+		ttl:     ttl,
+		now:     time.Now,
+		cache:   make(map[string]cachedToken),
+	}
+}
+
+// Service returns the name of the issuing service
+func (i *Issuer) Service() string {
+	return i.service
+}
+
+// Token returns a token for calling the audience service, reusing a cached
+// token while more than a fifth of its lifetime remains
+func (i *Issuer) Token(audience string) (string, error) {
+	now := i.now()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if cached, ok := i.cache[audience]; ok && cached.expiresAt.Sub(now) > i.ttl/5 {
+		return cached.token, nil
+	}
+
+	token, expiresAt, err := i.issue(audience, now)
+	if err != nil {
+		return "", err
+	}
+	i.cache[audience] = cachedToken{token: token, expiresAt: expiresAt}
+	return token, nil
+}
+
+func (i *Issuer) issue(audience string, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(i.ttl)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   i.service,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(i.key)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign service token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// Verifier validates service tokens addressed to one service
+type Verifier struct {
+	audience string
+	keys     map[string]ed25519.PublicKey
+	maxTTL   time.Duration
+	now      func() time.Time
+}
+
+// NewVerifier creates a verifier that only accepts tokens whose audience is
+// the given service. keys maps each calling service to its public key; a
+// token is only accepted when it is signed by the key of the service named
+// in its subject. Tokens issued with a lifetime longer than maxTTL
+// (default: 15 minutes) are rejected so long-lived tokens cannot circulate.
+func NewVerifier(audience string, keys map[string]ed25519.PublicKey, maxTTL time.Duration) *Verifier {
+	if maxTTL <= 0 {
+		maxTTL = 15 * time.Minute
+	}
+	return &Verifier{
+		audience: audience,
+		keys:     keys,
+		maxTTL:   maxTTL,
+		now:      time.Now,
+	}
+}
+
+// Verify validates the token and returns the calling service name
+func (v *Verifier) Verify(tokenString string) (string, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// The subject is only trusted once the signature of its own key checks out
+		key, ok := v.keys[claims.Subject]
+		if !ok {
+			return nil, fmt.Errorf("unknown calling service %q", claims.Subject)
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(v.now),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return "", ErrTokenExpired
+		}
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Subject == "" || claims.IssuedAt == nil ||
+		claims.ExpiresAt.Sub(claims.IssuedAt.Time) > v.maxTTL {
+		return "", ErrInvalidToken
+	}
+	return claims.Subject, nil
+}
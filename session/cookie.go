@@ -0,0 +1,101 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CookieConfig configures the session cookie
+type CookieConfig struct {
+	Name     string        // default: "session_id"
+	Domain   string        // default: host-only
+	Path     string        // default: "/"
+	Secure   bool          // send over HTTPS only; should be true in production
+	SameSite http.SameSite // default: http.SameSiteLaxMode
+	// HashKey signs the cookie value with HMAC-SHA256 (required, at least 32 bytes)
+	HashKey []byte
+	// BlockKey encrypts the cookie value with AES-GCM when set (16, 24 or 32 bytes)
+	BlockKey []byte
+}
+
+// cookieCodec signs and optionally encrypts session IDs for cookies
+type cookieCodec struct {
+	hashKey []byte
+	aead    cipher.AEAD
+}
+
+func newCookieCodec(hashKey, blockKey []byte) (*cookieCodec, error) {
+	if len(hashKey) < 32 {
+		return nil, fmt.Errorf("session cookie hash key must be at least 32 bytes")
+	}
+
+	codec := &cookieCodec{hashKey: hashKey}
+	if len(blockKey) > 0 {
+		block, err := aes.NewCipher(blockKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session cookie block key: %w", err)
+		}
+		codec.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codec, nil
+}
+
+// encode returns "<payload>.<signature>" where payload is the (encrypted) value
+func (c *cookieCodec) encode(name, value string) (string, error) {
+	payload := []byte(value)
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		// The cookie name is bound as additional data so values cannot be swapped between cookies
+		payload = c.aead.Seal(nonce, nonce, payload, []byte(name))
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + c.sign(name, encoded), nil
+}
+
+// decode verifies and decrypts a cookie value
+func (c *cookieCodec) decode(name, cookie string) (string, error) {
+	encoded, signature, ok := strings.Cut(cookie, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	if !hmac.Equal([]byte(signature), []byte(c.sign(name, encoded))) {
+		return "", ErrInvalidCookie
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+
+	if c.aead != nil {
+		nonceSize := c.aead.NonceSize()
+		if len(payload) < nonceSize {
+			return "", ErrInvalidCookie
+		}
+		payload, err = c.aead.Open(nil, payload[:nonceSize], payload[nonceSize:], []byte(name))
+		if err != nil {
+			return "", ErrInvalidCookie
+		}
+	}
+	return string(payload), nil
+}
+
+func (c *cookieCodec) sign(name, encoded string) string {
+	mac := hmac.New(sha256.New, c.hashKey)
+	mac.Write([]byte(name + "|" + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,303 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vhvplatform/go-shared/auth"
+	pkgctx "github.com/vhvplatform/go-shared/context"
+)
+
+var (
+	// ErrSessionNotFound is returned when a session does not exist
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExpired is returned when a session passed its idle or absolute timeout
+	ErrSessionExpired = errors.New("session expired")
+	// ErrInvalidCookie is returned when a session cookie fails verification
+	ErrInvalidCookie = errors.New("invalid session cookie")
+)
+
+// Session is a server-side login session
+type Session struct {
+	ID          string            `json:"id"`
+	UserID      string            `json:"user_id"`
+	TenantID    string            `json:"tenant_id"`
+	AppID       string            `json:"app_id,omitempty"`
+	Email       string            `json:"email,omitempty"`
+	Roles       []string          `json:"roles,omitempty"`
+	Permissions []string          `json:"permissions,omitempty"`
+	MFAVerified bool              `json:"mfa_verified,omitempty"`
+	UserAgent   string            `json:"user_agent,omitempty"`
+	IPAddress   string            `json:"ip_address,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	LastSeenAt  time.Time         `json:"last_seen_at"`
+	IdleTimeout time.Duration     `json:"idle_timeout"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+}
+
+// RequestContext converts the session into a request context
+func (s *Session) RequestContext() *pkgctx.RequestContext {
+	return &pkgctx.RequestContext{
+		UserID:      s.UserID,
+		TenantID:    s.TenantID,
+		AppID:       s.AppID,
+		Email:       s.Email,
+		Roles:       s.Roles,
+		Permissions: s.Permissions,
+	}
+}
+
+// expiry returns when the session expires if no further activity happens
+func (s *Session) expiry() time.Time {
+	idleExpiry := s.LastSeenAt.Add(s.IdleTimeout)
+	if idleExpiry.Before(s.ExpiresAt) {
+		return idleExpiry
+	}
+	return s.ExpiresAt
+}
+
+// DeviceInfo describes the client that created a session
+type DeviceInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// Config configures the session manager
+type Config struct {
+	Cookie CookieConfig
+	// TouchInterval throttles sliding-expiry writes to at most one per session per interval
+	TouchInterval time.Duration // default: 1 minute
+}
+
+// Manager creates, loads and revokes sessions
+type Manager struct {
+	store         Store
+	cookie        CookieConfig
+	codec         *cookieCodec
+	touchInterval time.Duration
+	now           func() time.Time
+}
+
+// NewManager creates a session manager
+func NewManager(store Store, config Config) (*Manager, error) {
+	codec, err := newCookieCodec(config.Cookie.HashKey, config.Cookie.BlockKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Cookie.Name == "" {
+		config.Cookie.Name = "session_id"
+	}
+	if config.Cookie.Path == "" {
+		config.Cookie.Path = "/"
+	}
+	if config.Cookie.SameSite == 0 {
+		config.Cookie.SameSite = http.SameSiteLaxMode
+	}
+	if config.TouchInterval <= 0 {
+		config.TouchInterval = time.Minute
+	}
+
+	return &Manager{
+		store:         store,
+		cookie:        config.Cookie,
+		codec:         codec,
+		touchInterval: config.TouchInterval,
+		now:           time.Now,
+	}, nil
+}
+
+// Create starts a session for the identity. Idle and absolute timeouts come
+// from the tenant login configuration (defaults apply when it is nil).
+func (m *Manager) Create(ctx context.Context, identity *pkgctx.RequestContext, loginConfig *auth.TenantLoginConfig, device DeviceInfo) (*Session, error) {
+	if loginConfig == nil {
+		loginConfig = &auth.TenantLoginConfig{}
+	}
+
+	id, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	idle := time.Duration(loginConfig.GetSessionTimeoutDuration()) * time.Minute
+	absolute := time.Duration(loginConfig.GetSessionAbsoluteTimeoutMinutes()) * time.Minute
+	if idle > absolute {
+		idle = absolute
+	}
+
+	session := &Session{
+		ID:          id,
+		UserID:      identity.UserID,
+		TenantID:    identity.TenantID,
+		AppID:       identity.AppID,
+		Email:       identity.Email,
+		Roles:       identity.Roles,
+		Permissions: identity.Permissions,
+		UserAgent:   device.UserAgent,
+		IPAddress:   device.IPAddress,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		IdleTimeout: idle,
+		ExpiresAt:   now.Add(absolute),
+	}
+	if err := m.save(ctx, session, now); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Load returns an active session and slides its idle expiry
+func (m *Manager) Load(ctx context.Context, id string) (*Session, error) {
+	session, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	if !now.Before(session.expiry()) {
+		_ = m.store.Delete(ctx, id)
+		return nil, ErrSessionExpired
+	}
+
+	if now.Sub(session.LastSeenAt) >= m.touchInterval {
+		session.LastSeenAt = now
+		if err := m.refresh(ctx, session, now); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// Update persists changes to a session (e.g. Data or MFAVerified) without changing its expiry.
+// It returns ErrSessionNotFound if the session was revoked in the meantime.
+func (m *Manager) Update(ctx context.Context, session *Session) error {
+	return m.refresh(ctx, session, m.now())
+}
+
+// List returns a user's active sessions across devices, most recent first
+func (m *Manager) List(ctx context.Context, userID string) ([]*Session, error) {
+	sessions, err := m.store.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	active := sessions[:0]
+	for _, session := range sessions {
+		if now.Before(session.expiry()) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// Revoke ends a single session
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	return m.store.Delete(ctx, id)
+}
+
+// RevokeAll ends all sessions of a user except exceptID (pass "" to end all)
+// and returns the number of sessions revoked
+func (m *Manager) RevokeAll(ctx context.Context, userID, exceptID string) (int, error) {
+	sessions, err := m.store.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == exceptID {
+			continue
+		}
+		if err := m.store.Delete(ctx, session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// SetCookie writes the signed (and optionally encrypted) session cookie
+func (m *Manager) SetCookie(w http.ResponseWriter, session *Session) error {
+	value, err := m.codec.encode(m.cookie.Name, session.ID)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cookie.Name,
+		Value:    value,
+		Domain:   m.cookie.Domain,
+		Path:     m.cookie.Path,
+		Expires:  session.ExpiresAt,
+		MaxAge:   int(session.ExpiresAt.Sub(m.now()).Seconds()),
+		Secure:   m.cookie.Secure,
+		HttpOnly: true,
+		SameSite: m.cookie.SameSite,
+	})
+	return nil
+}
+
+// ClearCookie removes the session cookie from the client
+func (m *Manager) ClearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cookie.Name,
+		Value:    "",
+		Domain:   m.cookie.Domain,
+		Path:     m.cookie.Path,
+		MaxAge:   -1,
+		Secure:   m.cookie.Secure,
+		HttpOnly: true,
+		SameSite: m.cookie.SameSite,
+	})
+}
+
+// SessionID reads and verifies the session ID from the request cookie
+func (m *Manager) SessionID(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(m.cookie.Name)
+	if err != nil {
+		return "", ErrSessionNotFound
+	}
+	return m.codec.decode(m.cookie.Name, cookie.Value)
+}
+
+// FromRequest loads the session referenced by the request cookie
+func (m *Manager) FromRequest(r *http.Request) (*Session, error) {
+	id, err := m.SessionID(r)
+	if err != nil {
+		return nil, err
+	}
+	return m.Load(r.Context(), id)
+}
+
+func (m *Manager) save(ctx context.Context, session *Session, now time.Time) error {
+	ttl := session.expiry().Sub(now)
+	if ttl <= 0 {
+		return ErrSessionExpired
+	}
+	if err := m.store.Save(ctx, session, ttl); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// refresh persists an existing session without resurrecting it if it was
+// revoked after it was loaded
+func (m *Manager) refresh(ctx context.Context, session *Session, now time.Time) error {
+	ttl := session.expiry().Sub(now)
+	if ttl <= 0 {
+		return ErrSessionExpired
+	}
+	if err := m.store.Refresh(ctx, session, ttl); err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
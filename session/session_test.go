@@ -0,0 +1,167 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vhvplatform/go-shared/auth"
+	pkgctx "github.com/vhvplatform/go-shared/context"
+)
+
+var testHashKey = bytes.Repeat([]byte("h"), 32)
+
+func newTestManager(t *testing.T, blockKey []byte) (*Manager, *time.Time) {
+	t.Helper()
+
+	manager, err := NewManager(NewMemoryStore(), Config{
+		Cookie: CookieConfig{Secure: true, HashKey: testHashKey, BlockKey: blockKey},
+	})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	now := time.Now()
+	manager.now = func() time.Time { return now }
+	return manager, &now
+}
+
+func TestManager_SlidingAndAbsoluteExpiry(t *testing.T) {
+	ctx := context.Background()
+	manager, now := newTestManager(t, nil)
+	loginConfig := &auth.TenantLoginConfig{SessionTimeout: 30, SessionAbsTimeout: 60}
+
+	s, err := manager.Create(ctx, &pkgctx.RequestContext{UserID: "user-1", TenantID: "tenant-1"}, loginConfig, DeviceInfo{})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	// Activity every 20 minutes keeps the session alive past the idle timeout...
+	for i := 0; i < 2; i++ {
+		*now = now.Add(20 * time.Minute)
+		if _, err := manager.Load(ctx, s.ID); err != nil {
+			t.Fatalf("expected session to slide at step %d: %v", i, err)
+		}
+	}
+
+	// ...but not past the absolute timeout
+	*now = now.Add(25 * time.Minute)
+	if _, err := manager.Load(ctx, s.ID); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("expected ErrSessionExpired after absolute timeout, got %v", err)
+	}
+
+	idle, _ := manager.Create(ctx, &pkgctx.RequestContext{UserID: "user-1"}, loginConfig, DeviceInfo{})
+	*now = now.Add(31 * time.Minute)
+	if _, err := manager.Load(ctx, idle.ID); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("expected ErrSessionExpired after idle timeout, got %v", err)
+	}
+}
+
+func TestManager_ListAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	manager, _ := newTestManager(t, nil)
+	identity := &pkgctx.RequestContext{UserID: "user-1", TenantID: "tenant-1"}
+
+	current, _ := manager.Create(ctx, identity, nil, DeviceInfo{UserAgent: "laptop"})
+	manager.Create(ctx, identity, nil, DeviceInfo{UserAgent: "phone"})
+	manager.Create(ctx, identity, nil, DeviceInfo{UserAgent: "tablet"})
+	manager.Create(ctx, &pkgctx.RequestContext{UserID: "user-2"}, nil, DeviceInfo{})
+
+	sessions, err := manager.List(ctx, "user-1")
+	if err != nil || len(sessions) != 3 {
+		t.Fatalf("expected 3 sessions, got %d (%v)", len(sessions), err)
+	}
+
+	revoked, err := manager.RevokeAll(ctx, "user-1", current.ID)
+	if err != nil || revoked != 2 {
+		t.Fatalf("expected 2 sessions revoked, got %d (%v)", revoked, err)
+	}
+
+	sessions, _ = manager.List(ctx, "user-1")
+	if len(sessions) != 1 || sessions[0].ID != current.ID {
+		t.Errorf("expected only the current session to remain, got %d", len(sessions))
+	}
+}
+
+// revokingStore revokes every session right after it is read, as a concurrent
+// Revoke would between Load reading a session and touching it
+type revokingStore struct {
+	*MemoryStore
+}
+
+func (s revokingStore) Get(ctx context.Context, id string) (*Session, error) {
+	session, err := s.MemoryStore.Get(ctx, id)
+	if err == nil {
+		_ = s.MemoryStore.Delete(ctx, id)
+	}
+	return session, err
+}
+
+func TestManager_TouchDoesNotResurrectRevokedSession(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	manager, now := newTestManager(t, nil)
+
+	s, err := manager.Create(ctx, &pkgctx.RequestContext{UserID: "user-1"}, nil, DeviceInfo{})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if err := store.Save(ctx, s, time.Hour); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+	manager.store = revokingStore{store}
+
+	*now = now.Add(2 * time.Minute)
+	if _, err := manager.Load(ctx, s.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound for a session revoked before the touch, got %v", err)
+	}
+	if err := manager.Update(ctx, s); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound when updating a revoked session, got %v", err)
+	}
+	if _, err := store.Get(ctx, s.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected revoked session to stay deleted, got %v", err)
+	}
+}
+
+func TestManager_Cookie(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		blockKey []byte
+	}{
+		{"signed", nil},
+		{"encrypted", bytes.Repeat([]byte("b"), 32)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, _ := newTestManager(t, tt.blockKey)
+			s, _ := manager.Create(context.Background(), &pkgctx.RequestContext{UserID: "user-1"}, nil, DeviceInfo{})
+
+			w := httptest.NewRecorder()
+			if err := manager.SetCookie(w, s); err != nil {
+				t.Fatalf("failed to set cookie: %v", err)
+			}
+			cookie := w.Result().Cookies()[0]
+			if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+				t.Errorf("expected secure cookie attributes, got %+v", cookie)
+			}
+			if tt.blockKey != nil && bytes.Contains([]byte(cookie.Value), []byte(s.ID)) {
+				t.Error("expected encrypted cookie not to contain the session ID")
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.AddCookie(cookie)
+			loaded, err := manager.FromRequest(r)
+			if err != nil || loaded.ID != s.ID {
+				t.Fatalf("expected session from cookie, got %v", err)
+			}
+
+			tampered := httptest.NewRequest(http.MethodGet, "/", nil)
+			tampered.AddCookie(&http.Cookie{Name: cookie.Name, Value: "x" + cookie.Value})
+			if _, err := manager.FromRequest(tampered); !errors.Is(err, ErrInvalidCookie) {
+				t.Errorf("expected ErrInvalidCookie for tampered cookie, got %v", err)
+			}
+		})
+	}
+}
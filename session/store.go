@@ -0,0 +1,272 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/vhvplatform/go-shared/redis"
+)
+
+// Store persists sessions
+type Store interface {
+	// Save creates or replaces a session; the store may drop it after ttl
+	Save(ctx context.Context, session *Session, ttl time.Duration) error
+	// Refresh replaces a session only if it still exists, returning
+	// ErrSessionNotFound once it was deleted or expired
+	Refresh(ctx context.Context, session *Session, ttl time.Duration) error
+	// Get returns a session by ID or ErrSessionNotFound
+	Get(ctx context.Context, id string) (*Session, error)
+	// Delete removes a session
+	Delete(ctx context.Context, id string) error
+	// ListByUser returns the live sessions of a user
+	ListByUser(ctx context.Context, userID string) ([]*Session, error)
+}
+
+// refreshScript replaces a session only if its key still exists and re-adds
+// it to the user index, so a concurrent revoke is never undone
+var refreshScript = goredis.NewScript(`
+if not redis.call("set", KEYS[1], ARGV[1], "PX", ARGV[2], "XX") then
+    return 0
+end
+redis.call("sadd", KEYS[2], ARGV[3])
+redis.call("pexpire", KEYS[2], ARGV[4], "GT")
+redis.call("pexpire", KEYS[2], ARGV[4], "NX")
+return 1
+`)
+
+// RedisStore stores sessions in Redis. Each session is a JSON value with a TTL,
+// and each user has a set of session IDs used to list and revoke sessions.
+type RedisStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisStore creates a Redis-backed session store
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	if keyPrefix == "" {
+		keyPrefix = "session"
+	}
+	return &RedisStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// Save creates or replaces a session
+func (s *RedisStore) Save(ctx context.Context, session *Session, ttl time.Duration) error {
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	userKey := s.userKey(session.UserID)
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, s.sessionKey(session.ID), payload, ttl)
+	pipe.SAdd(ctx, userKey, session.ID)
+	// The index lives as long as the longest-lived session it references
+	// (EXPIRE GT/NX require Redis 7+)
+	pipe.ExpireGT(ctx, userKey, time.Until(session.ExpiresAt))
+	pipe.ExpireNX(ctx, userKey, time.Until(session.ExpiresAt))
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Refresh replaces a session only if it still exists
+func (s *RedisStore) Refresh(ctx context.Context, session *Session, ttl time.Duration) error {
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	refreshed, err := refreshScript.Run(ctx, s.client.Client,
+		[]string{s.sessionKey(session.ID), s.userKey(session.UserID)},
+		payload, ttl.Milliseconds(), session.ID, time.Until(session.ExpiresAt).Milliseconds(),
+	).Int()
+	if err != nil {
+		return err
+	}
+	if refreshed == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// Get returns a session by ID
+func (s *RedisStore) Get(ctx context.Context, id string) (*Session, error) {
+	payload, err := s.client.Get(ctx, s.sessionKey(id))
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal([]byte(payload), &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+// Delete removes a session
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, s.sessionKey(id))
+	pipe.SRem(ctx, s.userKey(session.UserID), id)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ListByUser returns the live sessions of a user, pruning expired IDs from the index
+func (s *RedisStore) ListByUser(ctx context.Context, userID string) ([]*Session, error) {
+	userKey := s.userKey(userID)
+	ids, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.sessionKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*Session
+	var stale []interface{}
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+		var session Session
+		if err := json.Unmarshal([]byte(raw), &session); err != nil {
+			stale = append(stale, ids[i])
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+
+	if len(stale) > 0 {
+		s.client.SRem(ctx, userKey, stale...)
+	}
+
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+func (s *RedisStore) sessionKey(id string) string {
+	return s.keyPrefix + ":" + id
+}
+
+func (s *RedisStore) userKey(userID string) string {
+	return s.keyPrefix + ":user:" + userID
+}
+
+// MemoryStore is an in-memory Store, intended for tests and single-instance deployments
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	session   Session
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]memorySession),
+	}
+}
+
+// Save creates or replaces a session
+func (s *MemoryStore) Save(ctx context.Context, session *Session, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.ID] = memorySession{session: *session, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Refresh replaces a session only if it still exists
+func (s *MemoryStore) Refresh(ctx context.Context, session *Session, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.sessions[session.ID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(s.sessions, session.ID)
+		return ErrSessionNotFound
+	}
+	s.sessions[session.ID] = memorySession{session: *session, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Get returns a session by ID
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.sessions[id]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(s.sessions, id)
+		return nil, ErrSessionNotFound
+	}
+	session := entry.session
+	return &session, nil
+}
+
+// Delete removes a session
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+// ListByUser returns the live sessions of a user
+func (s *MemoryStore) ListByUser(ctx context.Context, userID string) ([]*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []*Session
+	now := time.Now()
+	for _, entry := range s.sessions {
+		if entry.session.UserID == userID && now.Before(entry.expiresAt) {
+			session := entry.session
+			sessions = append(sessions, &session)
+		}
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+// sortSessions orders sessions by most recent activity first
+func sortSessions(sessions []*Session) {
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+}
@@ -0,0 +1,245 @@
+package tenant
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainLookup maps a domain to a tenant ID. Implementations return
+// ErrTenantNotFound when the domain is not mapped. Wildcard mappings are
+// looked up with keys of the form "*.example.com".
+type DomainLookup interface {
+	LookupDomain(ctx context.Context, domain string) (string, error)
+}
+
+// DomainLookupFunc adapts a function to DomainLookup
+type DomainLookupFunc func(ctx context.Context, domain string) (string, error)
+
+// LookupDomain calls f(ctx, domain)
+func (f DomainLookupFunc) LookupDomain(ctx context.Context, domain string) (string, error) {
+	return f(ctx, domain)
+}
+
+// StaticDomains is a DomainLookup backed by a fixed map, e.g. from configuration
+type StaticDomains map[string]string
+
+// LookupDomain returns the tenant mapped to the domain
+func (m StaticDomains) LookupDomain(ctx context.Context, domain string) (string, error) {
+	if tenantID, ok := m[domain]; ok {
+		return tenantID, nil
+	}
+	return "", ErrTenantNotFound
+}
+
+// LookupDomain implements DomainLookup using the registry's custom domains
+func (r *Registry) LookupDomain(ctx context.Context, domain string) (string, error) {
+	tenant, err := r.GetByDomain(ctx, domain)
+	if err != nil {
+		return "", err
+	}
+	return tenant.ID, nil
+}
+
+// DomainMapperConfig configures a DomainMapper
+type DomainMapperConfig struct {
+	CacheTTL    time.Duration // default: 1 minute
+	NegativeTTL time.Duration // cache duration for unmapped domains (default: 30 seconds)
+	// MaxEntries bounds the cached mapped domains; the least recently used
+	// are evicted first (default: 10000)
+	MaxEntries int
+	// MaxNegativeEntries bounds the cached unmapped domains separately, since
+	// any Host header can add one (default: 1000)
+	MaxNegativeEntries int
+}
+
+// DomainMapper resolves domains to tenants through a DomainLookup, trying the
+// exact domain first and then wildcard parents ("*.b.example.com",
+// "*.example.com"), and caches the results in bounded in-memory LRU caches
+type DomainMapper struct {
+	lookup      DomainLookup
+	cacheTTL    time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	mapped   *domainCache
+	unmapped *domainCache
+}
+
+// NewDomainMapper creates a domain mapper
+func NewDomainMapper(lookup DomainLookup, config DomainMapperConfig) *DomainMapper {
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = time.Minute
+	}
+	if config.NegativeTTL <= 0 {
+		config.NegativeTTL = 30 * time.Second
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 10000
+	}
+	if config.MaxNegativeEntries <= 0 {
+		config.MaxNegativeEntries = 1000
+	}
+	return &DomainMapper{
+		lookup:      lookup,
+		cacheTTL:    config.CacheTTL,
+		negativeTTL: config.NegativeTTL,
+		now:         time.Now,
+		mapped:      newDomainCache(config.MaxEntries),
+		unmapped:    newDomainCache(config.MaxNegativeEntries),
+	}
+}
+
+// Resolve returns the tenant ID mapped to the domain
+func (m *DomainMapper) Resolve(ctx context.Context, domain string) (string, error) {
+	domain = normalizeDomain(domain)
+	if domain == "" {
+		return "", ErrInvalidDomain
+	}
+
+	now := m.now()
+	m.mu.Lock()
+	tenantID, ok := m.mapped.get(domain, now)
+	if !ok {
+		_, ok = m.unmapped.get(domain, now)
+	}
+	m.mu.Unlock()
+	if ok {
+		if tenantID == "" {
+			return "", ErrTenantNotFound
+		}
+		return tenantID, nil
+	}
+
+	tenantID, err := m.resolveUncached(ctx, domain)
+	if err != nil && !errors.Is(err, ErrTenantNotFound) {
+		return "", err
+	}
+
+	m.mu.Lock()
+	if tenantID == "" {
+		m.mapped.remove(domain)
+		m.unmapped.add(domain, "", now.Add(m.negativeTTL))
+	} else {
+		m.unmapped.remove(domain)
+		m.mapped.add(domain, tenantID, now.Add(m.cacheTTL))
+	}
+	m.mu.Unlock()
+
+	if tenantID == "" {
+		return "", ErrTenantNotFound
+	}
+	return tenantID, nil
+}
+
+// Invalidate drops cached mappings; with no domains the whole cache is cleared
+func (m *DomainMapper) Invalidate(domains ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(domains) == 0 {
+		m.mapped.clear()
+		m.unmapped.clear()
+		return
+	}
+	for _, domain := range domains {
+		domain = normalizeDomain(domain)
+		m.mapped.remove(domain)
+		m.unmapped.remove(domain)
+	}
+}
+
+// domainCache is a fixed-size LRU cache of domain mappings with expiry. It
+// is not safe for concurrent use.
+type domainCache struct {
+	capacity int
+	order    *list.List // front is most recently used
+	entries  map[string]*list.Element
+}
+
+type domainCacheEntry struct {
+	domain    string
+	tenantID  string
+	expiresAt time.Time
+}
+
+func newDomainCache(capacity int) *domainCache {
+	return &domainCache{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *domainCache) get(domain string, now time.Time) (string, bool) {
+	element, ok := c.entries[domain]
+	if !ok {
+		return "", false
+	}
+	entry := element.Value.(*domainCacheEntry)
+	if !now.Before(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, domain)
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return entry.tenantID, true
+}
+
+func (c *domainCache) add(domain, tenantID string, expiresAt time.Time) {
+	if element, ok := c.entries[domain]; ok {
+		element.Value = &domainCacheEntry{domain: domain, tenantID: tenantID, expiresAt: expiresAt}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[domain] = c.order.PushFront(&domainCacheEntry{domain: domain, tenantID: tenantID, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*domainCacheEntry).domain)
+	}
+}
+
+func (c *domainCache) remove(domain string) {
+	if element, ok := c.entries[domain]; ok {
+		c.order.Remove(element)
+		delete(c.entries, domain)
+	}
+}
+
+func (c *domainCache) clear() {
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+func (m *DomainMapper) resolveUncached(ctx context.Context, domain string) (string, error) {
+	for _, candidate := range domainCandidates(domain) {
+		tenantID, err := m.lookup.LookupDomain(ctx, candidate)
+		if err == nil && tenantID != "" {
+			return tenantID, nil
+		}
+		if err != nil && !errors.Is(err, ErrTenantNotFound) {
+			return "", err
+		}
+	}
+	return "", ErrTenantNotFound
+}
+
+// domainCandidates returns the exact domain followed by its wildcard parents,
+// most specific first. Wildcards never cover a bare top-level domain.
+func domainCandidates(domain string) []string {
+	candidates := []string{domain}
+	rest := domain
+	for {
+		dot := strings.IndexByte(rest, '.')
+		if dot == -1 {
+			break
+		}
+		rest = rest[dot+1:]
+		if !strings.Contains(rest, ".") {
+			break
+		}
+		candidates = append(candidates, "*."+rest)
+	}
+	return candidates
+}
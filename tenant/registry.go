@@ -0,0 +1,386 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/cache"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrTenantNotFound  = errors.New("tenant not found")
+	ErrTenantSuspended = errors.New("tenant is suspended")
+	ErrTenantDeleted   = errors.New("tenant is deleted")
+)
+
+// Status is the lifecycle status of a tenant
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusSuspended Status = "suspended"
+	StatusDeleted   Status = "deleted"
+)
+
+// Tenant is a tenant record in the registry
+type Tenant struct {
+	ID        string            `bson:"_id" json:"id"`
+	Name      string            `bson:"name" json:"name"`
+	Status    Status            `bson:"status" json:"status"`
+	Plan      string            `bson:"plan" json:"plan"`
+	Domains   []string          `bson:"domains,omitempty" json:"domains,omitempty"`
+	Features  map[string]bool   `bson:"features,omitempty" json:"features,omitempty"`
+	Metadata  map[string]string `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time         `bson:"updated_at" json:"updated_at"`
+}
+
+// IsActive reports whether the tenant can serve requests
+func (t *Tenant) IsActive() bool {
+	return t.Status == StatusActive
+}
+
+// HasFeature reports whether a feature flag is enabled for the tenant
+func (t *Tenant) HasFeature(feature string) bool {
+	return t.Features[feature]
+}
+
+// CheckStatus returns an error for tenants that cannot serve requests
+func (t *Tenant) CheckStatus() error {
+	switch t.Status {
+	case StatusActive:
+		return nil
+	case StatusSuspended:
+		return ErrTenantSuspended
+	case StatusDeleted:
+		return ErrTenantDeleted
+	default:
+		return fmt.Errorf("unknown tenant status: %s", t.Status)
+	}
+}
+
+// RegistryStore persists tenant records
+type RegistryStore interface {
+	// Get returns a tenant by ID or ErrTenantNotFound
+	Get(ctx context.Context, id string) (*Tenant, error)
+	// GetByDomain returns the tenant owning a custom domain or ErrTenantNotFound
+	GetByDomain(ctx context.Context, domain string) (*Tenant, error)
+	// Save creates or replaces a tenant
+	Save(ctx context.Context, tenant *Tenant) error
+}
+
+// MongoRegistryStore stores tenants in MongoDB
+type MongoRegistryStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRegistryStore creates a MongoDB-backed registry store
+func NewMongoRegistryStore(collection *mongo.Collection) *MongoRegistryStore {
+	return &MongoRegistryStore{collection: collection}
+}
+
+// EnsureIndexes creates the unique custom domain index
+func (s *MongoRegistryStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "domains", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create domains index: %w", err)
+	}
+	return nil
+}
+
+// Get returns a tenant by ID
+func (s *MongoRegistryStore) Get(ctx context.Context, id string) (*Tenant, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetByDomain returns the tenant owning a custom domain
+func (s *MongoRegistryStore) GetByDomain(ctx context.Context, domain string) (*Tenant, error) {
+	return s.findOne(ctx, bson.M{"domains": normalizeDomain(domain)})
+}
+
+// Save creates or replaces a tenant
+func (s *MongoRegistryStore) Save(ctx context.Context, tenant *Tenant) error {
+	_, err := s.collection.ReplaceOne(ctx,
+		bson.M{"_id": tenant.ID},
+		tenant,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+func (s *MongoRegistryStore) findOne(ctx context.Context, filter bson.M) (*Tenant, error) {
+	var tenant Tenant
+	if err := s.collection.FindOne(ctx, filter).Decode(&tenant); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// MemoryRegistryStore is an in-memory RegistryStore, intended for tests
+type MemoryRegistryStore struct {
+	mu      sync.RWMutex
+	tenants map[string]*Tenant
+}
+
+// NewMemoryRegistryStore creates an in-memory registry store
+func NewMemoryRegistryStore() *MemoryRegistryStore {
+	return &MemoryRegistryStore{
+		tenants: make(map[string]*Tenant),
+	}
+}
+
+// Get returns a tenant by ID
+func (s *MemoryRegistryStore) Get(ctx context.Context, id string) (*Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenant, ok := s.tenants[id]
+	if !ok {
+		return nil, ErrTenantNotFound
+	}
+	copied := *tenant
+	return &copied, nil
+}
+
+// GetByDomain returns the tenant owning a custom domain
+func (s *MemoryRegistryStore) GetByDomain(ctx context.Context, domain string) (*Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	domain = normalizeDomain(domain)
+	for _, tenant := range s.tenants {
+		for _, d := range tenant.Domains {
+			if d == domain {
+				copied := *tenant
+				return &copied, nil
+			}
+		}
+	}
+	return nil, ErrTenantNotFound
+}
+
+// Save creates or replaces a tenant
+func (s *MemoryRegistryStore) Save(ctx context.Context, tenant *Tenant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *tenant
+	s.tenants[tenant.ID] = &copied
+	return nil
+}
+
+// RegistryConfig configures the tenant registry
+type RegistryConfig struct {
+	TTL         time.Duration // default: 5 minutes
+	NegativeTTL time.Duration // cache duration for unknown tenants (default: 30 seconds)
+	KeyPrefix   string        // default: "tenant"
+}
+
+// Registry looks up tenant records through a cache (typically a cache.TieredCache)
+type Registry struct {
+	store       RegistryStore
+	cache       cache.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	keyPrefix   string
+}
+
+// cachedTenant wraps cache entries so unknown tenants can be cached too
+type cachedTenant struct {
+	Tenant  *Tenant `json:"tenant,omitempty"`
+	Missing bool    `json:"missing,omitempty"`
+}
+
+// NewRegistry creates a tenant registry. cache may be nil to disable caching.
+func NewRegistry(store RegistryStore, c cache.Cache, config RegistryConfig) *Registry {
+	if config.TTL <= 0 {
+		config.TTL = 5 * time.Minute
+	}
+	if config.NegativeTTL <= 0 {
+		config.NegativeTTL = 30 * time.Second
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "tenant"
+	}
+	return &Registry{
+		store:       store,
+		cache:       c,
+		ttl:         config.TTL,
+		negativeTTL: config.NegativeTTL,
+		keyPrefix:   config.KeyPrefix,
+	}
+}
+
+// Get returns a tenant by ID
+func (r *Registry) Get(ctx context.Context, id string) (*Tenant, error) {
+	return r.lookup(ctx, r.idKey(id), func() (*Tenant, error) {
+		return r.store.Get(ctx, id)
+	})
+}
+
+// GetByDomain returns the tenant owning a custom domain
+func (r *Registry) GetByDomain(ctx context.Context, domain string) (*Tenant, error) {
+	domain = normalizeDomain(domain)
+	return r.lookup(ctx, r.domainKey(domain), func() (*Tenant, error) {
+		return r.store.GetByDomain(ctx, domain)
+	})
+}
+
+// GetActive returns a tenant by ID, rejecting unknown, suspended and deleted tenants
+func (r *Registry) GetActive(ctx context.Context, id string) (*Tenant, error) {
+	tenant, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.CheckStatus(); err != nil {
+		return tenant, err
+	}
+	return tenant, nil
+}
+
+// Save creates or updates a tenant and invalidates its cache entries
+func (r *Registry) Save(ctx context.Context, tenant *Tenant) error {
+	now := time.Now()
+	if tenant.CreatedAt.IsZero() {
+		tenant.CreatedAt = now
+	}
+	if tenant.Status == "" {
+		tenant.Status = StatusActive
+	}
+	tenant.UpdatedAt = now
+	for i, domain := range tenant.Domains {
+		tenant.Domains[i] = normalizeDomain(domain)
+	}
+
+	// Domains removed by this update must be invalidated as well
+	previous, err := r.store.Get(ctx, tenant.ID)
+	if err != nil && !errors.Is(err, ErrTenantNotFound) {
+		return err
+	}
+
+	if err := r.store.Save(ctx, tenant); err != nil {
+		return fmt.Errorf("failed to save tenant: %w", err)
+	}
+
+	r.invalidate(ctx, tenant)
+	if previous != nil {
+		r.invalidate(ctx, previous)
+	}
+	return nil
+}
+
+// SetStatus changes a tenant's status
+func (r *Registry) SetStatus(ctx context.Context, id string, status Status) error {
+	tenant, err := r.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	tenant.Status = status
+	return r.Save(ctx, tenant)
+}
+
+// Invalidate drops cached entries for a tenant
+func (r *Registry) Invalidate(ctx context.Context, id string) error {
+	tenant, err := r.store.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrTenantNotFound) {
+			r.deleteKey(ctx, r.idKey(id))
+			return nil
+		}
+		return err
+	}
+	r.invalidate(ctx, tenant)
+	return nil
+}
+
+func (r *Registry) lookup(ctx context.Context, key string, load func() (*Tenant, error)) (*Tenant, error) {
+	if r.cache != nil {
+		var entry cachedTenant
+		if err := r.cache.Get(ctx, key, &entry); err == nil {
+			if entry.Missing || entry.Tenant == nil {
+				return nil, ErrTenantNotFound
+			}
+			return entry.Tenant, nil
+		}
+	}
+
+	tenant, err := load()
+	if err != nil {
+		if errors.Is(err, ErrTenantNotFound) && r.cache != nil {
+			_ = r.cache.Set(ctx, key, cachedTenant{Missing: true}, r.negativeTTL)
+		}
+		return nil, err
+	}
+
+	if r.cache != nil {
+		_ = r.cache.Set(ctx, key, cachedTenant{Tenant: tenant}, r.ttl)
+	}
+	return tenant, nil
+}
+
+func (r *Registry) invalidate(ctx context.Context, tenant *Tenant) {
+	r.deleteKey(ctx, r.idKey(tenant.ID))
+	for _, domain := range tenant.Domains {
+		r.deleteKey(ctx, r.domainKey(domain))
+	}
+}
+
+func (r *Registry) deleteKey(ctx context.Context, key string) {
+	if r.cache != nil {
+		_ = r.cache.Delete(ctx, key)
+	}
+}
+
+func (r *Registry) idKey(id string) string {
+	return r.keyPrefix + ":id:" + id
+}
+
+func (r *Registry) domainKey(domain string) string {
+	return r.keyPrefix + ":domain:" + domain
+}
+
+// normalizeDomain lower-cases a host and strips the port
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if i := strings.LastIndexByte(domain, ':'); i != -1 && !strings.Contains(domain[i:], "]") {
+		domain = domain[:i]
+	}
+	return strings.TrimSuffix(domain, ".")
+}
+
+type tenantContextKey struct{}
+
+// WithTenant stores the tenant record in the context
+func WithTenant(ctx context.Context, tenant *Tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// FromContext returns the tenant record attached by the resolver middleware
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(*Tenant)
+	return tenant, ok && tenant != nil
+}
+
+// FromGinContext returns the tenant record attached by the resolver middleware
+func FromGinContext(c *gin.Context) (*Tenant, bool) {
+	value, exists := c.Get("tenant")
+	if !exists {
+		return nil, false
+	}
+	tenant, ok := value.(*Tenant)
+	return tenant, ok && tenant != nil
+}
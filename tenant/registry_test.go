@@ -0,0 +1,144 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/cache"
+)
+
+// mapCache is a minimal cache.Cache for tests
+type mapCache struct {
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{items: make(map[string][]byte)}
+}
+
+func (c *mapCache) Get(ctx context.Context, key string, dest interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.items[key]
+	if !ok {
+		return cache.ErrCacheMiss
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (c *mapCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = data
+	return nil
+}
+
+func (c *mapCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+	return nil
+}
+
+func (c *mapCache) Exists(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.items[key]
+	return ok, nil
+}
+
+func TestRegistry_CachingAndInvalidation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRegistryStore()
+	registry := NewRegistry(store, newMapCache(), RegistryConfig{})
+
+	if err := registry.Save(ctx, &Tenant{ID: "acme", Plan: "pro", Domains: []string{"App.Acme.com"}}); err != nil {
+		t.Fatalf("failed to save tenant: %v", err)
+	}
+
+	tenant, err := registry.GetByDomain(ctx, "app.acme.com:443")
+	if err != nil || tenant.ID != "acme" || !tenant.IsActive() {
+		t.Fatalf("expected active acme tenant by domain, got %+v (%v)", tenant, err)
+	}
+
+	// Unknown tenants are negatively cached
+	if _, err := registry.Get(ctx, "globex"); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("expected ErrTenantNotFound, got %v", err)
+	}
+	store.Save(ctx, &Tenant{ID: "globex", Status: StatusActive})
+	if _, err := registry.Get(ctx, "globex"); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("expected cached miss, got %v", err)
+	}
+
+	// Status changes through the registry invalidate the cache
+	if err := registry.SetStatus(ctx, "acme", StatusSuspended); err != nil {
+		t.Fatalf("failed to suspend tenant: %v", err)
+	}
+	if _, err := registry.GetActive(ctx, "acme"); !errors.Is(err, ErrTenantSuspended) {
+		t.Errorf("expected ErrTenantSuspended, got %v", err)
+	}
+}
+
+func TestResolverMiddleware_WithRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	registry := NewRegistry(NewMemoryRegistryStore(), nil, RegistryConfig{})
+	registry.Save(ctx, &Tenant{ID: "acme", Features: map[string]bool{"sso": true}, Domains: []string{"portal.acme.io"}})
+	registry.Save(ctx, &Tenant{ID: "globex", Status: StatusSuspended})
+
+	resolver := NewResolver(ResolverConfig{
+		Strategies: []ResolutionStrategy{StrategyHeader, StrategyDomain},
+		Registry:   registry,
+	})
+	router := gin.New()
+	router.Use(resolver.Middleware())
+	router.GET("/", func(c *gin.Context) {
+		tenant, ok := FromContext(c.Request.Context())
+		if !ok || !tenant.HasFeature("sso") {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, tenant.ID)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		host   string
+		status int
+	}{
+		{"known tenant", "acme", "", http.StatusOK},
+		{"custom domain", "", "portal.acme.io", http.StatusOK},
+		{"unknown tenant", "initech", "", http.StatusNotFound},
+		{"suspended tenant", "globex", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -7,11 +7,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 	pkgctx "github.com/vhvplatform/go-shared/context"
+	"github.com/vhvplatform/go-shared/jwt"
 )
 
 var (
 	ErrTenantNotResolved = errors.New("tenant could not be resolved")
 	ErrInvalidDomain     = errors.New("invalid domain")
+	ErrTenantConflict    = errors.New("tenant resolution strategies disagree")
 )
 
 // ResolutionStrategy defines how to resolve tenant
@@ -22,13 +24,21 @@ const (
 	StrategySubdomain ResolutionStrategy = "subdomain"
 	StrategyDomain    ResolutionStrategy = "domain"
 	StrategyParam     ResolutionStrategy = "param"
+	StrategyJWT       ResolutionStrategy = "jwt"
+	StrategyPath      ResolutionStrategy = "path"
 )
 
 // Resolver resolves tenant from request
 type Resolver struct {
-	strategies []ResolutionStrategy
-	headerName string
-	paramName  string
+	strategies      []ResolutionStrategy
+	headerName      string
+	paramName       string
+	pathPrefix      string
+	reserved        map[string]struct{}
+	detectConflicts bool
+	registry        *Registry
+	domains         *DomainMapper
+	jwtManager      *jwt.Manager
 }
 
 // ResolverConfig configures tenant resolver
@@ -36,6 +46,21 @@ type ResolverConfig struct {
 	Strategies []ResolutionStrategy
 	HeaderName string // default: "X-Tenant-ID"
 	ParamName  string // default: "tenant_id"
+	// PathPrefix is the prefix of the path strategy, e.g. "/t/" for "/t/{tenant}/..." (default: "/t/")
+	PathPrefix string
+	// ReservedSubdomains are never treated as tenants by the subdomain strategy (default: "www", "api")
+	ReservedSubdomains []string
+	// DetectConflicts evaluates every strategy and fails with ErrTenantConflict when
+	// they resolve different tenants; otherwise the first successful strategy wins
+	DetectConflicts bool
+	// Registry, when set, rejects unknown or inactive tenants and is the default DomainLookup
+	Registry *Registry
+	// DomainLookup maps custom domains to tenants for the domain strategy
+	DomainLookup DomainLookup
+	// DomainMapper configures caching of DomainLookup results
+	DomainMapper DomainMapperConfig
+	// JWTManager validates bearer tokens for the JWT strategy
+	JWTManager *jwt.Manager
 }
 
 // NewResolver creates a new tenant resolver
@@ -49,23 +74,70 @@ func NewResolver(config ResolverConfig) *Resolver {
 	if config.ParamName == "" {
 		config.ParamName = "tenant_id"
 	}
+	if config.PathPrefix == "" {
+		config.PathPrefix = "/t/"
+	}
+	if config.ReservedSubdomains == nil {
+		config.ReservedSubdomains = []string{"www", "api"}
+	}
+	if config.DomainLookup == nil && config.Registry != nil {
+		config.DomainLookup = config.Registry
+	}
 
-	return &Resolver{
-		strategies: config.Strategies,
-		headerName: config.HeaderName,
-		paramName:  config.ParamName,
+	reserved := make(map[string]struct{}, len(config.ReservedSubdomains))
+	for _, subdomain := range config.ReservedSubdomains {
+		reserved[strings.ToLower(subdomain)] = struct{}{}
+	}
+
+	r := &Resolver{
+		strategies:      config.Strategies,
+		headerName:      config.HeaderName,
+		paramName:       config.ParamName,
+		pathPrefix:      "/" + strings.Trim(config.PathPrefix, "/") + "/",
+		reserved:        reserved,
+		detectConflicts: config.DetectConflicts,
+		registry:        config.Registry,
+		jwtManager:      config.JWTManager,
+	}
+	if config.DomainLookup != nil {
+		r.domains = NewDomainMapper(config.DomainLookup, config.DomainMapper)
 	}
+	return r
+}
+
+// Domains returns the domain mapper used by the domain strategy (nil when not configured)
+func (r *Resolver) Domains() *DomainMapper {
+	return r.domains
 }
 
 // Resolve resolves tenant from gin context
 func (r *Resolver) Resolve(c *gin.Context) (string, string, error) {
+	var resolvedID, resolvedDomain string
 	for _, strategy := range r.strategies {
 		tenantID, domain, err := r.resolveByStrategy(c, strategy)
-		if err == nil && tenantID != "" {
+		if err != nil || tenantID == "" {
+			continue
+		}
+		if !r.detectConflicts {
 			return tenantID, domain, nil
 		}
+
+		if resolvedID == "" {
+			resolvedID, resolvedDomain = tenantID, domain
+			continue
+		}
+		if tenantID != resolvedID {
+			return "", "", ErrTenantConflict
+		}
+		if resolvedDomain == "" {
+			resolvedDomain = domain
+		}
+	}
+
+	if resolvedID == "" {
+		return "", "", ErrTenantNotResolved
 	}
-	return "", "", ErrTenantNotResolved
+	return resolvedID, resolvedDomain, nil
 }
 
 func (r *Resolver) resolveByStrategy(c *gin.Context, strategy ResolutionStrategy) (string, string, error) {
@@ -78,6 +150,10 @@ func (r *Resolver) resolveByStrategy(c *gin.Context, strategy ResolutionStrategy
 		return r.resolveFromDomain(c)
 	case StrategyParam:
 		return r.resolveFromParam(c)
+	case StrategyJWT:
+		return r.resolveFromJWT(c)
+	case StrategyPath:
+		return r.resolveFromPath(c)
 	default:
 		return "", "", ErrTenantNotResolved
 	}
@@ -105,8 +181,8 @@ func (r *Resolver) resolveFromSubdomain(c *gin.Context) (string, string, error)
 		return "", "", ErrInvalidDomain
 	}
 
-	subdomain := host[:firstDot]
-	if subdomain == "www" || subdomain == "api" {
+	subdomain := strings.ToLower(host[:firstDot])
+	if _, reserved := r.reserved[subdomain]; reserved {
 		return "", "", ErrTenantNotResolved
 	}
 
@@ -119,9 +195,45 @@ func (r *Resolver) resolveFromDomain(c *gin.Context) (string, string, error) {
 		return "", "", ErrInvalidDomain
 	}
 
-	// TODO: Lookup tenant by custom domain in database
-	// This requires database access, implement in service layer
-	return "", domain, ErrTenantNotResolved
+	if r.domains == nil {
+		return "", domain, ErrTenantNotResolved
+	}
+
+	tenantID, err := r.domains.Resolve(c.Request.Context(), domain)
+	if err != nil {
+		return "", domain, ErrTenantNotResolved
+	}
+	return tenantID, domain, nil
+}
+
+func (r *Resolver) resolveFromJWT(c *gin.Context) (string, string, error) {
+	if r.jwtManager == nil {
+		return "", "", ErrTenantNotResolved
+	}
+
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return "", "", ErrTenantNotResolved
+	}
+
+	claims, err := r.jwtManager.ValidateToken(token)
+	if err != nil || claims.TenantID == "" {
+		return "", "", ErrTenantNotResolved
+	}
+	return claims.TenantID, "", nil
+}
+
+func (r *Resolver) resolveFromPath(c *gin.Context) (string, string, error) {
+	rest, found := strings.CutPrefix(c.Request.URL.Path, r.pathPrefix)
+	if !found {
+		return "", "", ErrTenantNotResolved
+	}
+
+	tenantID, _, _ := strings.Cut(rest, "/")
+	if tenantID == "" {
+		return "", "", ErrTenantNotResolved
+	}
+	return tenantID, "", nil
 }
 
 func (r *Resolver) resolveFromParam(c *gin.Context) (string, string, error) {
@@ -141,12 +253,36 @@ func (r *Resolver) resolveFromParam(c *gin.Context) (string, string, error) {
 func (r *Resolver) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tenantID, domain, err := r.Resolve(c)
+		if errors.Is(err, ErrTenantConflict) {
+			c.JSON(400, gin.H{"error": "Conflicting tenant in request"})
+			c.Abort()
+			return
+		}
 		if err != nil {
 			c.JSON(400, gin.H{"error": "Tenant could not be resolved"})
 			c.Abort()
 			return
 		}
 
+		if r.registry != nil {
+			tenant, err := r.registry.GetActive(c.Request.Context(), tenantID)
+			if err != nil {
+				switch {
+				case errors.Is(err, ErrTenantNotFound), errors.Is(err, ErrTenantDeleted):
+					c.JSON(404, gin.H{"error": "Tenant not found"})
+				case errors.Is(err, ErrTenantSuspended):
+					c.JSON(403, gin.H{"error": "Tenant is suspended"})
+				default:
+					c.JSON(500, gin.H{"error": "Failed to load tenant"})
+				}
+				c.Abort()
+				return
+			}
+
+			c.Set("tenant", tenant)
+			c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), tenant))
+		}
+
 		c.Set("tenant_id", tenantID)
 		c.Set("tenant_domain", domain)
 		c.Next()
@@ -0,0 +1,184 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vhvplatform/go-shared/jwt"
+)
+
+func TestDomainMapper_Wildcards(t *testing.T) {
+	lookups := 0
+	mapper := NewDomainMapper(DomainLookupFunc(func(ctx context.Context, domain string) (string, error) {
+		lookups++
+		return StaticDomains{
+			"portal.acme.io":   "acme",
+			"*.globex.example": "globex",
+		}.LookupDomain(ctx, domain)
+	}), DomainMapperConfig{})
+
+	tests := []struct {
+		domain   string
+		expected string
+		err      error
+	}{
+		{"Portal.Acme.io:8443", "acme", nil},
+		{"eu.app.globex.example", "globex", nil},
+		{"globex.example", "", ErrTenantNotFound},
+		{"unknown.test", "", ErrTenantNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			tenantID, err := mapper.Resolve(context.Background(), tt.domain)
+			if tenantID != tt.expected || !errors.Is(err, tt.err) {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.expected, tt.err, tenantID, err)
+			}
+		})
+	}
+
+	before := lookups
+	mapper.Resolve(context.Background(), "eu.app.globex.example")
+	if lookups != before {
+		t.Errorf("expected cached mapping, got %d new lookups", lookups-before)
+	}
+}
+
+func TestDomainMapper_BoundedCache(t *testing.T) {
+	lookups := 0
+	mapper := NewDomainMapper(DomainLookupFunc(func(ctx context.Context, domain string) (string, error) {
+		lookups++
+		return StaticDomains{"a.acme.io": "acme", "b.acme.io": "acme"}.LookupDomain(ctx, domain)
+	}), DomainMapperConfig{MaxEntries: 1, MaxNegativeEntries: 2})
+
+	// Unmapped hosts only ever fill the negative cache
+	for i := 0; i < 10; i++ {
+		mapper.Resolve(context.Background(), fmt.Sprintf("random-%d.test", i))
+	}
+	if mapper.unmapped.order.Len() != 2 || mapper.mapped.order.Len() != 0 {
+		t.Errorf("expected 2 unmapped and no mapped entries, got %d and %d", mapper.unmapped.order.Len(), mapper.mapped.order.Len())
+	}
+
+	mapper.Resolve(context.Background(), "a.acme.io")
+	mapper.Resolve(context.Background(), "b.acme.io")
+	before := lookups
+	if tenantID, _ := mapper.Resolve(context.Background(), "b.acme.io"); tenantID != "acme" || lookups != before {
+		t.Errorf("expected the most recent mapping to be cached, got %q with %d lookups", tenantID, lookups-before)
+	}
+	mapper.Resolve(context.Background(), "a.acme.io")
+	if lookups == before {
+		t.Error("expected the least recently used mapping to be evicted")
+	}
+}
+
+func TestResolver_Strategies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := jwt.NewManager("secret", 3600, 86400)
+	acmeToken, _ := manager.GenerateToken("user-1", "acme", "", nil, nil)
+
+	tests := []struct {
+		name     string
+		config   ResolverConfig
+		host     string
+		path     string
+		header   string
+		token    string
+		expected string
+		err      error
+	}{
+		{
+			name:     "jwt claim",
+			config:   ResolverConfig{Strategies: []ResolutionStrategy{StrategyJWT}, JWTManager: manager},
+			token:    acmeToken,
+			expected: "acme",
+		},
+		{
+			name:   "invalid jwt",
+			config: ResolverConfig{Strategies: []ResolutionStrategy{StrategyJWT}, JWTManager: manager},
+			token:  "garbage",
+			err:    ErrTenantNotResolved,
+		},
+		{
+			name:     "path prefix",
+			config:   ResolverConfig{Strategies: []ResolutionStrategy{StrategyPath}},
+			path:     "/t/acme/orders",
+			expected: "acme",
+		},
+		{
+			name:     "custom path prefix",
+			config:   ResolverConfig{Strategies: []ResolutionStrategy{StrategyPath}, PathPrefix: "tenants"},
+			path:     "/tenants/globex",
+			expected: "globex",
+		},
+		{
+			name:   "reserved subdomain",
+			config: ResolverConfig{Strategies: []ResolutionStrategy{StrategySubdomain}, ReservedSubdomains: []string{"app"}},
+			host:   "app.example.com",
+			err:    ErrTenantNotResolved,
+		},
+		{
+			name:     "default reserved subdomains overridden",
+			config:   ResolverConfig{Strategies: []ResolutionStrategy{StrategySubdomain}, ReservedSubdomains: []string{"app"}},
+			host:     "www.example.com",
+			expected: "www",
+		},
+		{
+			name:     "custom domain lookup",
+			config:   ResolverConfig{Strategies: []ResolutionStrategy{StrategyDomain}, DomainLookup: StaticDomains{"*.acme.io": "acme"}},
+			host:     "shop.acme.io",
+			expected: "acme",
+		},
+		{
+			name:     "first strategy wins",
+			config:   ResolverConfig{Strategies: []ResolutionStrategy{StrategyHeader, StrategyPath}},
+			header:   "acme",
+			path:     "/t/globex",
+			expected: "acme",
+		},
+		{
+			name:     "agreeing strategies",
+			config:   ResolverConfig{Strategies: []ResolutionStrategy{StrategyHeader, StrategyJWT}, JWTManager: manager, DetectConflicts: true},
+			header:   "acme",
+			token:    acmeToken,
+			expected: "acme",
+		},
+		{
+			name:   "conflicting strategies",
+			config: ResolverConfig{Strategies: []ResolutionStrategy{StrategyHeader, StrategyJWT}, JWTManager: manager, DetectConflicts: true},
+			header: "globex",
+			token:  acmeToken,
+			err:    ErrTenantConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = "/"
+			}
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = req
+
+			tenantID, _, err := NewResolver(tt.config).Resolve(c)
+			if tenantID != tt.expected || !errors.Is(err, tt.err) {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.expected, tt.err, tenantID, err)
+			}
+		})
+	}
+}
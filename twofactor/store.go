@@ -0,0 +1,227 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Enrollment holds a user's second-factor enrollment
+type Enrollment struct {
+	UserID        string     `bson:"user_id" json:"user_id"`
+	TenantID      string     `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	Secret        string     `bson:"secret" json:"-"`
+	Confirmed     bool       `bson:"confirmed" json:"confirmed"`
+	RecoveryCodes []string   `bson:"recovery_codes" json:"-"` // SHA-256 hashes
+	LastUsedStep  int64      `bson:"last_used_step" json:"-"`
+	CreatedAt     time.Time  `bson:"created_at" json:"created_at"`
+	ConfirmedAt   *time.Time `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+}
+
+// Store persists second-factor enrollments
+type Store interface {
+	// Get returns the enrollment for a user or ErrNotEnrolled
+	Get(ctx context.Context, userID string) (*Enrollment, error)
+	// Save creates or replaces an enrollment
+	Save(ctx context.Context, enrollment *Enrollment) error
+	// Delete removes an enrollment
+	Delete(ctx context.Context, userID string) error
+	// MarkStepUsed atomically records a TOTP step; it returns false if the step
+	// (or a later one) was already used
+	MarkStepUsed(ctx context.Context, userID string, step int64) (bool, error)
+	// ConsumeRecoveryCode atomically removes a recovery code hash; it returns false
+	// if the hash was not present
+	ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	// ReplaceRecoveryCodes replaces all recovery code hashes
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+}
+
+// MemoryStore is an in-memory Store, intended for tests and single-instance deployments
+type MemoryStore struct {
+	mu          sync.Mutex
+	enrollments map[string]*Enrollment
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		enrollments: make(map[string]*Enrollment),
+	}
+}
+
+// Get returns the enrollment for a user
+func (s *MemoryStore) Get(ctx context.Context, userID string) (*Enrollment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enrollment, ok := s.enrollments[userID]
+	if !ok {
+		return nil, ErrNotEnrolled
+	}
+	clone := *enrollment
+	clone.RecoveryCodes = append([]string(nil), enrollment.RecoveryCodes...)
+	return &clone, nil
+}
+
+// Save creates or replaces an enrollment
+func (s *MemoryStore) Save(ctx context.Context, enrollment *Enrollment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clone := *enrollment
+	clone.RecoveryCodes = append([]string(nil), enrollment.RecoveryCodes...)
+	s.enrollments[enrollment.UserID] = &clone
+	return nil
+}
+
+// Delete removes an enrollment
+func (s *MemoryStore) Delete(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.enrollments, userID)
+	return nil
+}
+
+// MarkStepUsed records a TOTP step if it is newer than the last used one
+func (s *MemoryStore) MarkStepUsed(ctx context.Context, userID string, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enrollment, ok := s.enrollments[userID]
+	if !ok {
+		return false, ErrNotEnrolled
+	}
+	if step <= enrollment.LastUsedStep {
+		return false, nil
+	}
+	enrollment.LastUsedStep = step
+	return true, nil
+}
+
+// ConsumeRecoveryCode removes a recovery code hash if present
+func (s *MemoryStore) ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enrollment, ok := s.enrollments[userID]
+	if !ok {
+		return false, ErrNotEnrolled
+	}
+	for i, hash := range enrollment.RecoveryCodes {
+		if hash == codeHash {
+			enrollment.RecoveryCodes = append(enrollment.RecoveryCodes[:i], enrollment.RecoveryCodes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery code hashes
+func (s *MemoryStore) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enrollment, ok := s.enrollments[userID]
+	if !ok {
+		return ErrNotEnrolled
+	}
+	enrollment.RecoveryCodes = append([]string(nil), codeHashes...)
+	return nil
+}
+
+// MongoStore is a MongoDB-backed Store keyed by user_id
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a new MongoDB store
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+// EnsureIndexes creates the unique user_id index
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user_id index: %w", err)
+	}
+	return nil
+}
+
+// Get returns the enrollment for a user
+func (s *MongoStore) Get(ctx context.Context, userID string) (*Enrollment, error) {
+	var enrollment Enrollment
+	err := s.collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&enrollment)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotEnrolled
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// Save creates or replaces an enrollment
+func (s *MongoStore) Save(ctx context.Context, enrollment *Enrollment) error {
+	_, err := s.collection.ReplaceOne(ctx,
+		bson.M{"user_id": enrollment.UserID},
+		enrollment,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// Delete removes an enrollment
+func (s *MongoStore) Delete(ctx context.Context, userID string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// MarkStepUsed records a TOTP step if it is newer than the last used one
+func (s *MongoStore) MarkStepUsed(ctx context.Context, userID string, step int64) (bool, error) {
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "last_used_step": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"last_used_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ConsumeRecoveryCode removes a recovery code hash if present
+func (s *MongoStore) ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "recovery_codes": codeHash},
+		bson.M{"$pull": bson.M{"recovery_codes": codeHash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery code hashes
+func (s *MongoStore) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": bson.M{"recovery_codes": codeHashes}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotEnrolled
+	}
+	return nil
+}
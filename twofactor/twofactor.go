@@ -0,0 +1,219 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vhvplatform/go-shared/auth"
+)
+
+var (
+	// ErrNotEnrolled is returned when the user has no second factor enrolled
+	ErrNotEnrolled = errors.New("two-factor authentication not enrolled")
+	// ErrAlreadyEnrolled is returned when enrolling a user that already has a confirmed second factor
+	ErrAlreadyEnrolled = errors.New("two-factor authentication already enrolled")
+	// ErrNotConfirmed is returned when verifying against an unconfirmed enrollment
+	ErrNotConfirmed = errors.New("two-factor enrollment not confirmed")
+	// ErrInvalidCode is returned when a code does not match
+	ErrInvalidCode = errors.New("invalid two-factor code")
+	// ErrCodeReused is returned when a valid TOTP code has already been used
+	ErrCodeReused = errors.New("two-factor code already used")
+	// ErrInvalidSecret is returned when a stored secret cannot be decoded
+	ErrInvalidSecret = errors.New("invalid two-factor secret")
+	// ErrInvalidDigits is returned when the TOTP code length is outside 6 to 8 digits
+	ErrInvalidDigits = errors.New("TOTP digits must be between 6 and 8")
+)
+
+// Config configures the two-factor service
+type Config struct {
+	TOTP              TOTPConfig
+	RecoveryCodeCount int // default: 10
+	QRCodeSize        int // default: 256
+}
+
+// EnrollmentResult contains the data shown to the user when enrolling
+type EnrollmentResult struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"uri"`
+	QRCodePNG     []byte   `json:"qr_code_png"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// Service manages TOTP enrollment and verification
+type Service struct {
+	store  Store
+	totp   *TOTP
+	config Config
+}
+
+// NewService creates a new two-factor service
+func NewService(store Store, config Config) (*Service, error) {
+	if config.RecoveryCodeCount == 0 {
+		config.RecoveryCodeCount = 10
+	}
+	if config.QRCodeSize == 0 {
+		config.QRCodeSize = 256
+	}
+
+	totp, err := NewTOTP(config.TOTP)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		store:  store,
+		totp:   totp,
+		config: config,
+	}, nil
+}
+
+// TOTP returns the underlying TOTP generator
+func (s *Service) TOTP() *TOTP {
+	return s.totp
+}
+
+// Enroll starts enrollment for a user. The enrollment stays unconfirmed until
+// ConfirmEnrollment is called with a valid code. Re-enrolling replaces a pending
+// enrollment but fails if a confirmed one exists.
+func (s *Service) Enroll(ctx context.Context, userID, tenantID, accountName string) (*EnrollmentResult, error) {
+	existing, err := s.store.Get(ctx, userID)
+	if err != nil && !errors.Is(err, ErrNotEnrolled) {
+		return nil, err
+	}
+	if existing != nil && existing.Confirmed {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	secret, err := s.totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	qr, err := s.totp.QRCode(secret, accountName, s.config.QRCodeSize)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := GenerateRecoveryCodes(s.config.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment := &Enrollment{
+		UserID:        userID,
+		TenantID:      tenantID,
+		Secret:        secret,
+		RecoveryCodes: HashRecoveryCodes(codes),
+		CreatedAt:     time.Now(),
+	}
+	if err := s.store.Save(ctx, enrollment); err != nil {
+		return nil, err
+	}
+
+	return &EnrollmentResult{
+		Secret:        secret,
+		URI:           s.totp.URI(secret, accountName),
+		QRCodePNG:     qr,
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// ConfirmEnrollment activates a pending enrollment once the user proves possession of the secret
+func (s *Service) ConfirmEnrollment(ctx context.Context, userID, code string) error {
+	enrollment, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if enrollment.Confirmed {
+		return ErrAlreadyEnrolled
+	}
+
+	step, ok := s.totp.Validate(enrollment.Secret, code)
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	now := time.Now()
+	enrollment.Confirmed = true
+	enrollment.ConfirmedAt = &now
+	enrollment.LastUsedStep = step
+	return s.store.Save(ctx, enrollment)
+}
+
+// Verify checks a TOTP code for a confirmed enrollment, rejecting codes that were already used
+func (s *Service) Verify(ctx context.Context, userID, code string) error {
+	enrollment, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enrollment.Confirmed {
+		return ErrNotConfirmed
+	}
+
+	step, ok := s.totp.Validate(enrollment.Secret, code)
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	fresh, err := s.store.MarkStepUsed(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrCodeReused
+	}
+	return nil
+}
+
+// VerifyRecoveryCode checks and consumes a single-use recovery code
+func (s *Service) VerifyRecoveryCode(ctx context.Context, userID, code string) error {
+	enrollment, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enrollment.Confirmed {
+		return ErrNotConfirmed
+	}
+
+	consumed, err := s.store.ConsumeRecoveryCode(ctx, userID, HashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes and returns the new plaintext codes
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes, err := GenerateRecoveryCodes(s.config.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceRecoveryCodes(ctx, userID, HashRecoveryCodes(codes)); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// IsEnrolled reports whether the user has a confirmed second factor
+func (s *Service) IsEnrolled(ctx context.Context, userID string) (bool, error) {
+	enrollment, err := s.store.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotEnrolled) {
+			return false, nil
+		}
+		return false, err
+	}
+	return enrollment.Confirmed, nil
+}
+
+// Disable removes the user's second factor
+func (s *Service) Disable(ctx context.Context, userID string) error {
+	return s.store.Delete(ctx, userID)
+}
+
+// IsRequired reports whether the tenant login config requires a second factor
+func IsRequired(config *auth.TenantLoginConfig) bool {
+	return config != nil && config.Require2FA
+}
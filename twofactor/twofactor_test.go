@@ -0,0 +1,173 @@
+package twofactor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B test secret ("12345678901234567890" in base32)
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTP_RFC6238Vectors(t *testing.T) {
+	totp, err := NewTOTP(TOTPConfig{Digits: 8})
+	if err != nil {
+		t.Fatalf("failed to create TOTP: %v", err)
+	}
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := totp.GenerateCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+		if code != tt.code {
+			t.Errorf("at %d: expected %s, got %s", tt.unix, tt.code, code)
+		}
+	}
+}
+
+func TestNewTOTP_Digits(t *testing.T) {
+	tests := []struct {
+		digits int
+		err    error
+	}{
+		{0, nil},
+		{6, nil},
+		{8, nil},
+		{5, ErrInvalidDigits},
+		{9, ErrInvalidDigits},
+		{10, ErrInvalidDigits},
+		{-1, ErrInvalidDigits},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewTOTP(TOTPConfig{Digits: tt.digits}); !errors.Is(err, tt.err) {
+			t.Errorf("digits %d: expected %v, got %v", tt.digits, tt.err, err)
+		}
+	}
+	if _, err := NewService(NewMemoryStore(), Config{TOTP: TOTPConfig{Digits: 10}}); !errors.Is(err, ErrInvalidDigits) {
+		t.Errorf("expected NewService to reject 10 digits, got %v", err)
+	}
+}
+
+func TestTOTP_ValidateDriftWindow(t *testing.T) {
+	totp, err := NewTOTP(TOTPConfig{})
+	if err != nil {
+		t.Fatalf("failed to create TOTP: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	totp.now = func() time.Time { return now }
+
+	previous, _ := totp.GenerateCode(rfcSecret, now.Add(-30*time.Second))
+	if _, ok := totp.Validate(rfcSecret, previous); !ok {
+		t.Error("expected previous-step code to be accepted within skew")
+	}
+
+	stale, _ := totp.GenerateCode(rfcSecret, now.Add(-90*time.Second))
+	if _, ok := totp.Validate(rfcSecret, stale); ok {
+		t.Error("expected code outside skew to be rejected")
+	}
+}
+
+func TestTOTP_URI(t *testing.T) {
+	totp, err := NewTOTP(TOTPConfig{Issuer: "Acme"})
+	if err != nil {
+		t.Fatalf("failed to create TOTP: %v", err)
+	}
+	uri := totp.URI("SECRET", "alice@example.com")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Acme:alice@example.com?") {
+		t.Errorf("unexpected URI: %s", uri)
+	}
+	for _, param := range []string{"secret=SECRET", "issuer=Acme", "digits=6", "period=30"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("expected URI to contain %s: %s", param, uri)
+		}
+	}
+
+	png, err := totp.QRCode("SECRET", "alice@example.com", 128)
+	if err != nil {
+		t.Fatalf("failed to generate QR code: %v", err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Error("expected PNG output")
+	}
+}
+
+func TestService_EnrollVerifyAndReplay(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewService(NewMemoryStore(), Config{TOTP: TOTPConfig{Issuer: "Acme"}, RecoveryCodeCount: 3})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	result, err := service.Enroll(ctx, "user-1", "tenant-1", "alice@example.com")
+	if err != nil {
+		t.Fatalf("failed to enroll: %v", err)
+	}
+	if len(result.RecoveryCodes) != 3 {
+		t.Errorf("expected 3 recovery codes, got %d", len(result.RecoveryCodes))
+	}
+
+	code, _ := service.TOTP().GenerateCode(result.Secret, time.Now())
+	if err := service.Verify(ctx, "user-1", code); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("expected ErrNotConfirmed before confirmation, got %v", err)
+	}
+
+	if err := service.ConfirmEnrollment(ctx, "user-1", code); err != nil {
+		t.Fatalf("failed to confirm enrollment: %v", err)
+	}
+
+	// The confirmation code must not be replayable
+	if err := service.Verify(ctx, "user-1", code); !errors.Is(err, ErrCodeReused) {
+		t.Errorf("expected ErrCodeReused, got %v", err)
+	}
+
+	next, _ := service.TOTP().GenerateCode(result.Secret, time.Now().Add(30*time.Second))
+	if err := service.Verify(ctx, "user-1", next); err != nil {
+		t.Errorf("expected next-step code to verify, got %v", err)
+	}
+
+	if _, err := service.Enroll(ctx, "user-1", "tenant-1", "alice@example.com"); !errors.Is(err, ErrAlreadyEnrolled) {
+		t.Errorf("expected ErrAlreadyEnrolled, got %v", err)
+	}
+}
+
+func TestService_RecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewService(NewMemoryStore(), Config{RecoveryCodeCount: 2})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	result, err := service.Enroll(ctx, "user-1", "", "alice")
+	if err != nil {
+		t.Fatalf("failed to enroll: %v", err)
+	}
+	code, _ := service.TOTP().GenerateCode(result.Secret, time.Now())
+	if err := service.ConfirmEnrollment(ctx, "user-1", code); err != nil {
+		t.Fatalf("failed to confirm enrollment: %v", err)
+	}
+
+	recovery := strings.ToLower(result.RecoveryCodes[0])
+	if err := service.VerifyRecoveryCode(ctx, "user-1", recovery); err != nil {
+		t.Errorf("expected recovery code to verify, got %v", err)
+	}
+	if err := service.VerifyRecoveryCode(ctx, "user-1", recovery); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected used recovery code to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,515 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	// ErrUnsupportedHash is returned when an encoded hash uses an unknown algorithm
+	ErrUnsupportedHash = errors.New("unsupported password hash format")
+	// ErrInvalidHash is returned when an encoded hash cannot be parsed
+	ErrInvalidHash = errors.New("invalid password hash")
+)
+
+// Bounds on the parameters accepted from encoded hashes, so that a tampered
+// or corrupted hash cannot make verification panic or exhaust memory and CPU
+const (
+	maxArgon2idMemory     = 1 << 20 // KiB (1 GiB)
+	maxArgon2idIterations = 64
+	maxScryptLogN         = 20 // N = 2^20 (1 GiB with r=8)
+	maxScryptR            = 32
+	maxScryptP            = 16
+	maxScryptMemory       = 1 << 30 // bytes (128 * r * N)
+	maxHashKeyLength      = 1024
+)
+
+// Password hash algorithm identifiers (as used in PHC strings)
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmScrypt   = "scrypt"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// PasswordHasher hashes and verifies passwords for a single algorithm
+type PasswordHasher interface {
+	// Algorithm returns the algorithm identifier
+	Algorithm() string
+	// Hash returns the encoded hash of a password
+	Hash(password string) (string, error)
+	// Verify checks a password against an encoded hash
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether the encoded hash was created with weaker parameters
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idParams holds argon2id tuning parameters
+type Argon2idParams struct {
+	Memory      uint32 // Memory in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams returns the recommended argon2id parameters (OWASP)
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Argon2idHasher hashes passwords with argon2id in PHC format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher creates a new argon2id hasher
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	defaults := DefaultArgon2idParams()
+	if params.Memory == 0 {
+		params.Memory = defaults.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = defaults.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = defaults.Parallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = defaults.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = defaults.KeyLength
+	}
+	return &Argon2idHasher{params: params}
+}
+
+// Algorithm returns the algorithm identifier
+func (h *Argon2idHasher) Algorithm() string {
+	return AlgorithmArgon2id
+}
+
+// Params returns the hasher parameters
+func (h *Argon2idHasher) Params() Argon2idParams {
+	return h.params
+}
+
+// Hash hashes a password with argon2id
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt, err := randomSalt(h.params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id, argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		b64Encode(salt), b64Encode(key),
+	), nil
+}
+
+// Verify checks a password against an argon2id hash
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, computed) == 1, nil
+}
+
+// NeedsRehash reports whether the hash uses weaker parameters than the hasher
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.params.Memory ||
+		params.Iterations < h.params.Iterations ||
+		params.Parallelism < h.params.Parallelism ||
+		uint32(len(salt)) < h.params.SaltLength ||
+		uint32(len(key)) < h.params.KeyLength
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported argon2 version %d", ErrInvalidHash, version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+
+	salt, err := b64Decode(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := b64Decode(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	if params.Iterations < 1 || params.Iterations > maxArgon2idIterations ||
+		params.Parallelism < 1 ||
+		params.Memory < 8*uint32(params.Parallelism) || params.Memory > maxArgon2idMemory ||
+		len(salt) == 0 || len(key) == 0 || len(key) > maxHashKeyLength {
+		return params, nil, nil, fmt.Errorf("%w: argon2id parameters out of range", ErrInvalidHash)
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// TuneArgon2id finds the iteration count for which hashing takes at least the target
+// duration on the current machine, with the given memory (KiB) and parallelism
+func TuneArgon2id(target time.Duration, memory uint32, parallelism uint8) Argon2idParams {
+	params := DefaultArgon2idParams()
+	params.Memory = memory
+	params.Parallelism = parallelism
+	params.Iterations = 1
+
+	salt := make([]byte, params.SaltLength)
+	for params.Iterations < maxArgon2idIterations {
+		start := time.Now()
+		argon2.IDKey([]byte("password-tuning"), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		if time.Since(start) >= target {
+			break
+		}
+		params.Iterations++
+	}
+
+	return params
+}
+
+// ScryptParams holds scrypt tuning parameters
+type ScryptParams struct {
+	LogN       uint8 // CPU/memory cost as log2(N)
+	R          int   // Block size
+	P          int   // Parallelization
+	SaltLength int
+	KeyLength  int
+}
+
+// DefaultScryptParams returns the recommended scrypt parameters (N=2^15, r=8, p=1)
+func DefaultScryptParams() ScryptParams {
+	return ScryptParams{
+		LogN:       15,
+		R:          8,
+		P:          1,
+		SaltLength: 16,
+		KeyLength:  32,
+	}
+}
+
+// ScryptHasher hashes passwords with scrypt in PHC format:
+// $scrypt$ln=15,r=8,p=1$<salt>$<hash>
+type ScryptHasher struct {
+	params ScryptParams
+}
+
+// NewScryptHasher creates a new scrypt hasher
+func NewScryptHasher(params ScryptParams) *ScryptHasher {
+	defaults := DefaultScryptParams()
+	if params.LogN == 0 {
+		params.LogN = defaults.LogN
+	}
+	if params.R == 0 {
+		params.R = defaults.R
+	}
+	if params.P == 0 {
+		params.P = defaults.P
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = defaults.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = defaults.KeyLength
+	}
+	return &ScryptHasher{params: params}
+}
+
+// Algorithm returns the algorithm identifier
+func (h *ScryptHasher) Algorithm() string {
+	return AlgorithmScrypt
+}
+
+// Hash hashes a password with scrypt
+func (h *ScryptHasher) Hash(password string) (string, error) {
+	salt, err := randomSalt(uint32(h.params.SaltLength))
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, 1<<h.params.LogN, h.params.R, h.params.P, h.params.KeyLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return fmt.Sprintf("$%s$ln=%d,r=%d,p=%d$%s$%s",
+		AlgorithmScrypt, h.params.LogN, h.params.R, h.params.P,
+		b64Encode(salt), b64Encode(key),
+	), nil
+}
+
+// Verify checks a password against a scrypt hash
+func (h *ScryptHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	computed, err := scrypt.Key([]byte(password), salt, 1<<params.LogN, params.R, params.P, len(key))
+	if err != nil {
+		return false, fmt.Errorf("failed to hash password: %w", err)
+	}
+	return subtle.ConstantTimeCompare(key, computed) == 1, nil
+}
+
+// NeedsRehash reports whether the hash uses weaker parameters than the hasher
+func (h *ScryptHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeScrypt(encoded)
+	if err != nil {
+		return true
+	}
+	return params.LogN < h.params.LogN ||
+		params.R < h.params.R ||
+		params.P < h.params.P ||
+		len(salt) < h.params.SaltLength ||
+		len(key) < h.params.KeyLength
+}
+
+func decodeScrypt(encoded string) (ScryptParams, []byte, []byte, error) {
+	var params ScryptParams
+
+	// "", "scrypt", "ln=...,r=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[1] != AlgorithmScrypt {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &params.LogN, &params.R, &params.P); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+
+	salt, err := b64Decode(parts[3])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := b64Decode(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	// N = 2^ln is a power of two by construction; ln bounds it
+	if params.LogN < 1 || params.LogN > maxScryptLogN ||
+		params.R < 1 || params.R > maxScryptR ||
+		params.P < 1 || params.P > maxScryptP ||
+		len(salt) == 0 || len(key) == 0 || len(key) > maxHashKeyLength {
+		return params, nil, nil, fmt.Errorf("%w: scrypt parameters out of range", ErrInvalidHash)
+	}
+	// ln and r are only safe together: ln=20 with r=32 would need 4 GiB
+	if int64(128*params.R)<<params.LogN > maxScryptMemory {
+		return params, nil, nil, fmt.Errorf("%w: scrypt parameters exceed memory limit", ErrInvalidHash)
+	}
+
+	params.SaltLength = len(salt)
+	params.KeyLength = len(key)
+	return params, salt, key, nil
+}
+
+// BcryptHasher hashes passwords with bcrypt ($2a$/$2b$/$2y$ modular crypt format)
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a new bcrypt hasher (cost 0 = bcrypt.DefaultCost)
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+// Algorithm returns the algorithm identifier
+func (h *BcryptHasher) Algorithm() string {
+	return AlgorithmBcrypt
+}
+
+// Hash hashes a password with bcrypt
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(bytes), nil
+}
+
+// Verify checks a password against a bcrypt hash
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+}
+
+// NeedsRehash reports whether the hash uses a lower cost than the hasher
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+	return cost < h.cost
+}
+
+// PasswordManager hashes new passwords with a preferred hasher and verifies
+// hashes created by any registered hasher, so legacy hashes can be upgraded
+type PasswordManager struct {
+	preferred PasswordHasher
+	hashers   map[string]PasswordHasher
+}
+
+// NewPasswordManager creates a password manager. The preferred hasher is used for
+// new hashes; the others are only used to verify existing hashes.
+func NewPasswordManager(preferred PasswordHasher, legacy ...PasswordHasher) *PasswordManager {
+	pm := &PasswordManager{
+		preferred: preferred,
+		hashers:   make(map[string]PasswordHasher, len(legacy)+1),
+	}
+	for _, h := range legacy {
+		pm.hashers[h.Algorithm()] = h
+	}
+	pm.hashers[preferred.Algorithm()] = preferred
+	return pm
+}
+
+// DefaultPasswordManager returns a manager using argon2id for new hashes
+// and accepting scrypt and bcrypt hashes
+func DefaultPasswordManager() *PasswordManager {
+	return NewPasswordManager(
+		NewArgon2idHasher(DefaultArgon2idParams()),
+		NewScryptHasher(DefaultScryptParams()),
+		NewBcryptHasher(bcrypt.DefaultCost),
+	)
+}
+
+// Hash hashes a password with the preferred hasher
+func (pm *PasswordManager) Hash(password string) (string, error) {
+	return pm.preferred.Hash(password)
+}
+
+// Verify checks a password against a hash created by any registered hasher
+func (pm *PasswordManager) Verify(password, encoded string) (bool, error) {
+	hasher, err := pm.hasherFor(encoded)
+	if err != nil {
+		return false, err
+	}
+	return hasher.Verify(password, encoded)
+}
+
+// NeedsRehash reports whether the hash should be replaced, either because it was
+// created by a different algorithm or with weaker parameters than the preferred hasher
+func (pm *PasswordManager) NeedsRehash(encoded string) bool {
+	if IdentifyHash(encoded) != pm.preferred.Algorithm() {
+		return true
+	}
+	return pm.preferred.NeedsRehash(encoded)
+}
+
+// VerifyAndRehash verifies a password and, on success, returns a new hash when the
+// stored one needs upgrading. newHash is empty when no rehash is required.
+func (pm *PasswordManager) VerifyAndRehash(password, encoded string) (ok bool, newHash string, err error) {
+	ok, err = pm.Verify(password, encoded)
+	if err != nil || !ok {
+		return false, "", err
+	}
+
+	if !pm.NeedsRehash(encoded) {
+		return true, "", nil
+	}
+
+	newHash, err = pm.Hash(password)
+	if err != nil {
+		return true, "", err
+	}
+	return true, newHash, nil
+}
+
+func (pm *PasswordManager) hasherFor(encoded string) (PasswordHasher, error) {
+	hasher, ok := pm.hashers[IdentifyHash(encoded)]
+	if !ok {
+		return nil, ErrUnsupportedHash
+	}
+	return hasher, nil
+}
+
+// IdentifyHash returns the algorithm identifier of an encoded hash, or "" if unknown
+func IdentifyHash(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$"+AlgorithmArgon2id+"$"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(encoded, "$"+AlgorithmScrypt+"$"):
+		return AlgorithmScrypt
+	case strings.HasPrefix(encoded, "$2a$"),
+		strings.HasPrefix(encoded, "$2b$"),
+		strings.HasPrefix(encoded, "$2y$"):
+		return AlgorithmBcrypt
+	default:
+		return ""
+	}
+}
+
+func randomSalt(length uint32) ([]byte, error) {
+	salt := make([]byte, length)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// b64Encode encodes using unpadded standard base64, as required by the PHC format
+func b64Encode(b []byte) string {
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+func b64Decode(s string) ([]byte, error) {
+	b, err := base64.RawStdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	return b, nil
+}
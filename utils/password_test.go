@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testArgon2idHasher() *Argon2idHasher {
+	return NewArgon2idHasher(Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1})
+}
+
+func testScryptHasher() *ScryptHasher {
+	return NewScryptHasher(ScryptParams{LogN: 4})
+}
+
+func TestPasswordHashers_HashAndVerify(t *testing.T) {
+	hashers := []PasswordHasher{
+		testArgon2idHasher(),
+		testScryptHasher(),
+		NewBcryptHasher(4),
+	}
+
+	for _, hasher := range hashers {
+		t.Run(hasher.Algorithm(), func(t *testing.T) {
+			encoded, err := hasher.Hash("s3cret-password")
+			if err != nil {
+				t.Fatalf("failed to hash: %v", err)
+			}
+			if IdentifyHash(encoded) != hasher.Algorithm() {
+				t.Errorf("expected algorithm %s, got %q for %s", hasher.Algorithm(), IdentifyHash(encoded), encoded)
+			}
+
+			ok, err := hasher.Verify("s3cret-password", encoded)
+			if err != nil || !ok {
+				t.Errorf("expected password to verify, got ok=%v err=%v", ok, err)
+			}
+
+			ok, err = hasher.Verify("wrong-password", encoded)
+			if err != nil || ok {
+				t.Errorf("expected wrong password to fail, got ok=%v err=%v", ok, err)
+			}
+
+			if hasher.NeedsRehash(encoded) {
+				t.Error("expected fresh hash not to need rehash")
+			}
+		})
+	}
+}
+
+func TestArgon2idHasher_PHCFormat(t *testing.T) {
+	encoded, err := testArgon2idHasher().Hash("password")
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected PHC string: %s", encoded)
+	}
+	if parts := strings.Split(encoded, "$"); len(parts) != 6 {
+		t.Errorf("expected 6 PHC segments, got %d", len(parts))
+	}
+}
+
+func TestArgon2idHasher_NeedsRehash(t *testing.T) {
+	weak, err := testArgon2idHasher().Hash("password")
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+
+	stronger := NewArgon2idHasher(Argon2idParams{Memory: 2048, Iterations: 2, Parallelism: 1})
+	if !stronger.NeedsRehash(weak) {
+		t.Error("expected weaker hash to need rehash")
+	}
+	if !stronger.NeedsRehash("not-a-hash") {
+		t.Error("expected invalid hash to need rehash")
+	}
+}
+
+func TestPasswordManager_VerifyAndRehash(t *testing.T) {
+	legacy := NewBcryptHasher(4)
+	manager := NewPasswordManager(testArgon2idHasher(), legacy, testScryptHasher())
+
+	legacyHash, err := legacy.Hash("password")
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+
+	ok, newHash, err := manager.VerifyAndRehash("wrong", legacyHash)
+	if err != nil || ok || newHash != "" {
+		t.Fatalf("expected failed verification without rehash, got ok=%v newHash=%q err=%v", ok, newHash, err)
+	}
+
+	ok, newHash, err = manager.VerifyAndRehash("password", legacyHash)
+	if err != nil || !ok {
+		t.Fatalf("expected legacy hash to verify, got ok=%v err=%v", ok, err)
+	}
+	if IdentifyHash(newHash) != AlgorithmArgon2id {
+		t.Fatalf("expected argon2id rehash, got %q", newHash)
+	}
+
+	ok, rehash, err := manager.VerifyAndRehash("password", newHash)
+	if err != nil || !ok || rehash != "" {
+		t.Errorf("expected upgraded hash to verify without rehash, got ok=%v rehash=%q err=%v", ok, rehash, err)
+	}
+
+	if _, err := manager.Verify("password", "$md5$abc"); err != ErrUnsupportedHash {
+		t.Errorf("expected ErrUnsupportedHash, got %v", err)
+	}
+}
+
+func TestCheckPassword_PHCHashes(t *testing.T) {
+	encoded, err := testScryptHasher().Hash("password")
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	if !CheckPassword("password", encoded) {
+		t.Error("expected CheckPassword to accept scrypt hash")
+	}
+	if CheckPassword("wrong", encoded) {
+		t.Error("expected CheckPassword to reject wrong password")
+	}
+}
+
+func TestDecodeHash_RejectsInvalidParameters(t *testing.T) {
+	// base64 of 16 and 32 bytes
+	salt := "AAAAAAAAAAAAAAAAAAAAAA"
+	key := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+	tests := []struct {
+		name    string
+		encoded string
+		valid   bool
+	}{
+		{"argon2id valid", "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$" + key, true},
+		{"argon2id zero iterations", "$argon2id$v=19$m=1024,t=0,p=1$" + salt + "$" + key, false},
+		{"argon2id too many iterations", "$argon2id$v=19$m=1024,t=100000,p=1$" + salt + "$" + key, false},
+		{"argon2id zero parallelism", "$argon2id$v=19$m=1024,t=1,p=0$" + salt + "$" + key, false},
+		{"argon2id parallelism overflow", "$argon2id$v=19$m=1024,t=1,p=256$" + salt + "$" + key, false},
+		{"argon2id memory below 8p", "$argon2id$v=19$m=7,t=1,p=1$" + salt + "$" + key, false},
+		{"argon2id huge memory", "$argon2id$v=19$m=4294967295,t=1,p=1$" + salt + "$" + key, false},
+		{"argon2id empty key", "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$", false},
+		{"argon2id empty salt", "$argon2id$v=19$m=1024,t=1,p=1$$" + key, false},
+		{"scrypt valid", "$scrypt$ln=4,r=8,p=1$" + salt + "$" + key, true},
+		{"scrypt zero ln", "$scrypt$ln=0,r=8,p=1$" + salt + "$" + key, false},
+		{"scrypt huge ln", "$scrypt$ln=40,r=8,p=1$" + salt + "$" + key, false},
+		{"scrypt ln overflow", "$scrypt$ln=300,r=8,p=1$" + salt + "$" + key, false},
+		{"scrypt zero r", "$scrypt$ln=4,r=0,p=1$" + salt + "$" + key, false},
+		{"scrypt negative p", "$scrypt$ln=4,r=8,p=-1$" + salt + "$" + key, false},
+		{"scrypt huge r", "$scrypt$ln=4,r=1048576,p=1$" + salt + "$" + key, false},
+		{"scrypt max ln with r=8", "$scrypt$ln=20,r=8,p=1$" + salt + "$" + key, true},
+		{"scrypt max ln with max r", "$scrypt$ln=20,r=32,p=1$" + salt + "$" + key, false},
+		{"scrypt memory above limit", "$scrypt$ln=20,r=9,p=1$" + salt + "$" + key, false},
+		{"scrypt empty key", "$scrypt$ln=4,r=8,p=1$" + salt + "$", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if strings.HasPrefix(tt.encoded, "$argon2id$") {
+				_, _, _, err = decodeArgon2id(tt.encoded)
+			} else {
+				_, _, _, err = decodeScrypt(tt.encoded)
+			}
+			if tt.valid && err != nil {
+				t.Errorf("expected valid hash, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("expected ErrInvalidHash, got %v", err)
+			}
+		})
+	}
+}
@@ -21,7 +21,13 @@ func HashPassword(password string) (string, error) {
 }
 
 // CheckPassword compares a password with a hash
+// bcrypt hashes are checked directly; argon2id and scrypt PHC hashes are
+// verified through DefaultPasswordManager
 func CheckPassword(password, hash string) bool {
+	if algorithm := IdentifyHash(hash); algorithm != "" && algorithm != AlgorithmBcrypt {
+		ok, err := DefaultPasswordManager().Verify(password, hash)
+		return err == nil && ok
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }